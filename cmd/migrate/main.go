@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/infra/persistence"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func main() {
+	cfg := config.Load()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoURI))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Disconnect(context.Background())
+
+	applied, err := persistence.NewMigrator(client.Database(cfg.DBName), persistence.Migrations).Run(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(applied) == 0 {
+		log.Println("database is up to date")
+		return
+	}
+	for _, m := range applied {
+		log.Printf("applied migration %d: %s\n", m.Version, m.Description)
+	}
+}
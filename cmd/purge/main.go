@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"time"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/infra/persistence"
+	"url-shortener/internal/services"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func main() {
+	cfg := config.Load()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoURI))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Disconnect(context.Background())
+
+	db := client.Database(cfg.DBName)
+	policy := services.RetentionPolicy{Default: cfg.ClickRetention, Accounts: cfg.ClickRetentionAccounts}
+	var opts []services.RetentionOption
+	if cfg.RollupEnabled {
+		policy.MinAge = services.MinRetention(cfg.RollupLag)
+		opts = append(opts, services.WithRollupCheckpoint(persistence.NewMongoRollupRepository(db)))
+	}
+	svc := services.NewRetentionService(
+		persistence.NewMongoURLRepository(db),
+		persistence.NewMongoURLStatsRepository(db),
+		policy,
+		opts...,
+	)
+
+	report, err := svc.Purge(ctx, time.Now())
+	if report != nil {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/infra/persistence"
+	"url-shortener/internal/services"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func main() {
+	fix := flag.Bool("fix", false, "adjust click counters to match the click log")
+	flag.Parse()
+
+	cfg := config.Load()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoURI))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Disconnect(context.Background())
+
+	db := client.Database(cfg.DBName)
+	var opts []services.ReconcileOption
+	retention := services.RetentionPolicy{Default: cfg.ClickRetention, Accounts: cfg.ClickRetentionAccounts}
+	if retention.Enabled() {
+		opts = append(opts, services.WithPurgedClicks())
+	}
+	svc := services.NewReconcileService(
+		persistence.NewMongoURLRepository(db),
+		persistence.NewMongoURLStatsRepository(db),
+		opts...,
+	)
+
+	report, err := svc.Reconcile(ctx, *fix)
+	if report != nil {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -2,19 +2,28 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"url-shortener/internal/config"
+	"url-shortener/internal/infra/persistence"
 	"url-shortener/internal/interface/bootstrap"
 
+	"github.com/redis/go-redis/v9"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 func main() {
 	cfg := config.Load()
+	if err := cfg.CheckSigningKeys(); err != nil {
+		log.Fatal(err)
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -25,10 +34,73 @@ func main() {
 	}
 	db := client.Database(cfg.DBName)
 
-	r := bootstrap.NewRouter(db, cfg)
+	if cfg.MigrateOnStart {
+		migrate(db)
+	}
+
+	var rdb *redis.Client
+	if cfg.RedisAddr != "" {
+		rdb = redis.NewClient(&redis.Options{Addr: cfg.RedisAddr, Password: cfg.RedisPassword})
+		defer rdb.Close()
+	}
+
+	app := bootstrap.NewApp(db, rdb, cfg)
+	srv := &http.Server{Addr: cfg.ServerAddr, Handler: app.Handler}
+	srv.RegisterOnShutdown(app.CloseStreams)
+
+	stop, stopCancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopCancel()
+
+	go func() {
+		log.Printf("🚀 Server running at %s\n", cfg.ServerAddr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	var admin *http.Server
+	if cfg.AdminAddr != "" {
+		admin = &http.Server{Addr: cfg.AdminAddr, Handler: app.AdminHandler}
+		go func() {
+			if err := admin.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Fatal(err)
+			}
+		}()
+	}
+
+	<-stop.Done()
+	log.Println("shutting down")
 
-	log.Printf("🚀 Server running at %s\n", cfg.ServerAddr)
-	if err := http.ListenAndServe(cfg.ServerAddr, r); err != nil {
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer shutdownCancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("http shutdown: %v", err)
+	}
+	if admin != nil {
+		if err := admin.Shutdown(shutdownCtx); err != nil {
+			log.Printf("admin shutdown: %v", err)
+		}
+	}
+	if err := app.Shutdown(shutdownCtx); err != nil {
+		log.Printf("app shutdown: %v", err)
+	}
+	if err := client.Disconnect(shutdownCtx); err != nil {
+		log.Printf("mongo disconnect: %v", err)
+	}
+}
+
+// migrate applies pending migrations with the same time budget as
+// cmd/migrate: index builds on large collections outlast the connect timeout.
+func migrate(db *mongo.Database) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	applied, err := persistence.NewMigrator(db, persistence.Migrations).Run(ctx)
+	if err != nil {
 		log.Fatal(err)
 	}
+	for _, m := range applied {
+		log.Printf("applied migration %d: %s\n", m.Version, m.Description)
+	}
 }
@@ -1,21 +1,181 @@
 package config
 
-import "os"
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
 
 type Config struct {
 	MongoURI   string
 	DBName     string
 	ServerAddr string
-	SecretKey string
+	// AdminAddr serves operational endpoints such as /debug/vars, empty to
+	// disable them. It defaults to loopback so they are not exposed publicly.
+	AdminAddr      string
+	SecretKey      string
+	VisitorKey     string
+	ShareKey       string
+	PublicHosts    []string
+	TrustedProxies []string
+	GeoIPDatabase  string
+	MigrateOnStart bool
+	RequestTimeout time.Duration
+
+	RedisAddr        string
+	RedisPassword    string
+	CacheTTL         time.Duration
+	CacheNegativeTTL time.Duration
+
+	// LinkCacheTTL bounds how long a replica keeps redirecting to a link
+	// that was edited on another replica.
+	LinkCacheSize int
+	LinkCacheTTL  time.Duration
+	LinkCacheWarm int
+
+	ClickQueueSize      int
+	ClickWorkers        int
+	ClickBatchSize      int
+	ClickFlushInterval  time.Duration
+	ClickEnqueueTimeout time.Duration
+	ClickTransactions   bool
+	ShutdownTimeout     time.Duration
+
+	PrivacyIP            string
+	PrivacyDropUserAgent bool
+	PrivacyHonorOptOut   bool
+
+	// ClickRetention is how long raw clicks are kept, zero for ever.
+	// ClickRetentionAccounts overrides it per owner ID.
+	ClickRetention         time.Duration
+	ClickRetentionAccounts map[string]time.Duration
+
+	LiveMaxSubscribers int
+	LiveHistory        int
+	LiveHeartbeat      time.Duration
+
+	// ConversionWindow is the default time from click to conversion within
+	// which conversions are attributed, zero for no limit.
+	ConversionWindow time.Duration
+
+	RollupEnabled  bool
+	RollupInterval time.Duration
+	RollupLag      time.Duration
+	RollupMaxHours int
+
+	// Metadata* control the background fetch of destination titles and
+	// OpenGraph tags. MetadataTimeout bounds each fetch and MetadataMaxBytes
+	// how much of a page is read.
+	MetadataEnabled  bool
+	MetadataWorkers  int
+	MetadataQueue    int
+	MetadataTimeout  time.Duration
+	MetadataMaxBytes int
+
+	// Alert* tune traffic anomaly detection, which needs the rollup job.
+	// Alerts are posted to each owner's own webhook, within
+	// WebhookTimeout, and mailed to owners through SMTPAddr when set.
+	AlertsEnabled      bool
+	AlertInterval      time.Duration
+	AlertBaselineHours int
+	AlertMinClicks     int
+	AlertFlatHours     int
+	AlertCooldown      time.Duration
+	WebhookTimeout     time.Duration
+	AlertEmailFrom     string
+	SMTPAddr           string
+	SMTPUsername       string
+	SMTPPassword       string
 }
 
 func Load() *Config {
 	return &Config{
-		MongoURI:   getEnv("MONGO_URI", "mongodb://localhost:27017"),
-		DBName:     getEnv("MONGO_DB", "url_shortener"),
-		ServerAddr: getEnv("SERVER_ADDR", ":8080"),
-		SecretKey: getEnv("SECRET", "123"),
+		MongoURI:       getEnv("MONGO_URI", "mongodb://localhost:27017"),
+		DBName:         getEnv("MONGO_DB", "url_shortener"),
+		ServerAddr:     getEnv("SERVER_ADDR", ":8080"),
+		AdminAddr:      getEnv("ADMIN_ADDR", "127.0.0.1:6060"),
+		SecretKey:      getEnv("SECRET", "123"),
+		VisitorKey:     getEnv("VISITOR_KEY", ""),
+		ShareKey:       getEnv("SHARE_KEY", ""),
+		PublicHosts:    getEnvList("PUBLIC_HOSTS", nil),
+		TrustedProxies: getEnvList("TRUSTED_PROXIES", nil),
+		GeoIPDatabase:  getEnv("GEOIP_DB", ""),
+		MigrateOnStart: getEnvBool("MIGRATE_ON_START", true),
+		RequestTimeout: getEnvDuration("REQUEST_TIMEOUT", 10*time.Second),
+
+		RedisAddr:        getEnv("REDIS_ADDR", ""),
+		RedisPassword:    getEnv("REDIS_PASSWORD", ""),
+		CacheTTL:         getEnvDuration("CACHE_TTL", 5*time.Minute),
+		CacheNegativeTTL: getEnvDuration("CACHE_NEGATIVE_TTL", 30*time.Second),
+
+		LinkCacheSize: getEnvInt("LINK_CACHE_SIZE", 10000),
+		LinkCacheTTL:  getEnvDuration("LINK_CACHE_TTL", 30*time.Second),
+		LinkCacheWarm: getEnvInt("LINK_CACHE_WARM", 100),
+
+		ClickQueueSize:      getEnvInt("CLICK_QUEUE_SIZE", 10000),
+		ClickWorkers:        getEnvInt("CLICK_WORKERS", 2),
+		ClickBatchSize:      getEnvInt("CLICK_BATCH_SIZE", 500),
+		ClickFlushInterval:  getEnvDuration("CLICK_FLUSH_INTERVAL", time.Second),
+		ClickEnqueueTimeout: getEnvDuration("CLICK_ENQUEUE_TIMEOUT", 5*time.Millisecond),
+		ClickTransactions:   getEnvBool("CLICK_TRANSACTIONS", false),
+		ShutdownTimeout:     getEnvDuration("SHUTDOWN_TIMEOUT", 15*time.Second),
+
+		PrivacyIP:            getEnv("PRIVACY_IP", "truncate"),
+		PrivacyDropUserAgent: getEnvBool("PRIVACY_DROP_USER_AGENT", false),
+		PrivacyHonorOptOut:   getEnvBool("PRIVACY_HONOR_OPT_OUT", true),
+
+		ClickRetention:         getEnvDuration("CLICK_RETENTION", 0),
+		ClickRetentionAccounts: getEnvDurationMap("CLICK_RETENTION_ACCOUNTS"),
+
+		LiveMaxSubscribers: getEnvInt("LIVE_MAX_SUBSCRIBERS", 100),
+		LiveHistory:        getEnvInt("LIVE_HISTORY", 100),
+		LiveHeartbeat:      getEnvDuration("LIVE_HEARTBEAT", 15*time.Second),
+
+		ConversionWindow: getEnvDuration("CONVERSION_WINDOW", 30*24*time.Hour),
+
+		RollupEnabled:  getEnvBool("ROLLUP_ENABLED", true),
+		RollupInterval: getEnvDuration("ROLLUP_INTERVAL", time.Minute),
+		RollupLag:      getEnvDuration("ROLLUP_LAG", 2*time.Minute),
+		RollupMaxHours: getEnvInt("ROLLUP_MAX_HOURS", 24),
+
+		MetadataEnabled:  getEnvBool("METADATA_ENABLED", true),
+		MetadataWorkers:  getEnvInt("METADATA_WORKERS", 2),
+		MetadataQueue:    getEnvInt("METADATA_QUEUE", 1000),
+		MetadataTimeout:  getEnvDuration("METADATA_TIMEOUT", 5*time.Second),
+		MetadataMaxBytes: getEnvInt("METADATA_MAX_BYTES", 512<<10),
+
+		AlertsEnabled:      getEnvBool("ALERTS_ENABLED", true),
+		AlertInterval:      getEnvDuration("ALERT_INTERVAL", 5*time.Minute),
+		AlertBaselineHours: getEnvInt("ALERT_BASELINE_HOURS", 7*24),
+		AlertMinClicks:     getEnvInt("ALERT_MIN_CLICKS", 20),
+		AlertFlatHours:     getEnvInt("ALERT_FLAT_HOURS", 6),
+		AlertCooldown:      getEnvDuration("ALERT_COOLDOWN", 24*time.Hour),
+		WebhookTimeout:     getEnvDuration("ALERT_WEBHOOK_TIMEOUT", 10*time.Second),
+		AlertEmailFrom:     getEnv("ALERT_EMAIL_FROM", "alerts@localhost"),
+		SMTPAddr:           getEnv("SMTP_ADDR", ""),
+		SMTPUsername:       getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:       getEnv("SMTP_PASSWORD", ""),
+	}
+}
+
+// CheckSigningKeys reports whether the keys the server signs shares and
+// visitor hashes with are set and differ from the JWT secret. Only the server
+// uses them, so the other commands do not call it.
+func (c *Config) CheckSigningKeys() error {
+	for _, k := range []struct{ name, value string }{
+		{"VISITOR_KEY", c.VisitorKey},
+		{"SHARE_KEY", c.ShareKey},
+	} {
+		if k.value == "" {
+			return fmt.Errorf("%s is required", k.name)
+		}
+		if k.value == c.SecretKey {
+			return fmt.Errorf("%s must differ from SECRET", k.name)
+		}
 	}
+	return nil
 }
 
 func getEnv(key, fallback string) string {
@@ -24,3 +184,62 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// getEnvList splits a comma separated variable, dropping empty items.
+func getEnvList(key string, fallback []string) []string {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getEnvDurationMap parses "key=duration" pairs separated by commas, skipping
+// malformed pairs.
+func getEnvDurationMap(key string) map[string]time.Duration {
+	values := make(map[string]time.Duration)
+	for _, item := range getEnvList(key, nil) {
+		k, v, ok := strings.Cut(item, "=")
+		if !ok {
+			continue
+		}
+		if d, err := time.ParseDuration(strings.TrimSpace(v)); err == nil {
+			values[strings.TrimSpace(k)] = d
+		}
+	}
+	return values
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	switch os.Getenv(key) {
+	case "1", "true", "TRUE", "True":
+		return true
+	case "0", "false", "FALSE", "False":
+		return false
+	}
+	return fallback
+}
+
+func getEnvInt(key string, fallback int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,25 @@
+package entity
+
+import "time"
+
+const (
+	AlertSpike    = "spike"
+	AlertFlat     = "flat"
+	AlertBotBurst = "bot_burst"
+)
+
+// Alert flags unusual traffic on a link in the hour starting at Hour.
+// Observed is the count that triggered it (human clicks, or bot clicks for a
+// bot burst) and Baseline the clicks per hour expected from the preceding
+// days. OriginalURL is the destination when the alert was raised.
+type Alert struct {
+	ID          string
+	URLID       string
+	OwnerID     string
+	OriginalURL string
+	Kind        string
+	Hour        time.Time
+	Observed    int
+	Baseline    float64
+	CreatedAt   time.Time
+}
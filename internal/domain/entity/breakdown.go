@@ -0,0 +1,7 @@
+package entity
+
+// BreakdownRow is the click count of one value of a breakdown dimension.
+type BreakdownRow struct {
+	Key    string
+	Clicks int
+}
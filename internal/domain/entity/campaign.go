@@ -0,0 +1,27 @@
+package entity
+
+import "time"
+
+// UTM holds the utm_* query parameters a campaign adds to the destinations
+// of its links. Empty values are left out.
+type UTM struct {
+	Source   string
+	Medium   string
+	Campaign string
+	Term     string
+	Content  string
+}
+
+// Campaign groups links of one owner. StartsAt and EndsAt bound the period
+// its reports cover by default; either may be zero. An archived campaign
+// keeps its links and reports but takes no new links.
+type Campaign struct {
+	ID         string
+	OwnerID    string
+	Name       string
+	StartsAt   time.Time
+	EndsAt     time.Time
+	UTM        UTM
+	CreatedAt  time.Time
+	ArchivedAt time.Time
+}
@@ -0,0 +1,8 @@
+package entity
+
+import "time"
+
+type ClickBucket struct {
+	Start  time.Time
+	Clicks int
+}
@@ -0,0 +1,27 @@
+package entity
+
+import "time"
+
+// Conversion is an advertiser reported outcome attributed to a click.
+type Conversion struct {
+	ID          string
+	URLID       string
+	ClickID     string
+	Value       float64
+	ClickedAt   time.Time
+	ConvertedAt time.Time
+}
+
+type ConversionTotals struct {
+	Conversions int
+	Value       float64
+}
+
+// IssuedClick is a click ID handed out on a redirect. It is kept apart from
+// the click itself so attribution outlives click retention.
+type IssuedClick struct {
+	ClickID   string
+	URLID     string
+	ClickedAt time.Time
+	ExpireAt  time.Time
+}
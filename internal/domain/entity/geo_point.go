@@ -0,0 +1,8 @@
+package entity
+
+// GeoPoint is the click count at one coarse map coordinate.
+type GeoPoint struct {
+	Latitude  float64
+	Longitude float64
+	Clicks    int
+}
@@ -0,0 +1,26 @@
+package entity
+
+import "time"
+
+const (
+	RollupHour = "hour"
+	RollupDay  = "day"
+)
+
+// Rollup is the pre-aggregated clicks of one link over one hour or one UTC
+// day. Human, Bots and Previews split the clicks by class; the breakdowns
+// count human clicks only, keyed by country code, device and referrer host,
+// with "" for clicks without a value.
+type Rollup struct {
+	URLID       string
+	Granularity string
+	Start       time.Time
+
+	Human    int
+	Bots     int
+	Previews int
+
+	Countries map[string]int
+	Devices   map[string]int
+	Referrers map[string]int
+}
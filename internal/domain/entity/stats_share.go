@@ -0,0 +1,22 @@
+package entity
+
+import "time"
+
+// StatsShare grants read-only access to the stats of a link to anyone holding
+// its token. Zero ExpiresAt never expires; a non-zero RevokedAt ends it.
+type StatsShare struct {
+	ID        string
+	URLID     string
+	OwnerID   string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	RevokedAt time.Time
+}
+
+// Active reports whether the share grants access at now.
+func (s *StatsShare) Active(now time.Time) bool {
+	if !s.RevokedAt.IsZero() {
+		return false
+	}
+	return s.ExpiresAt.IsZero() || now.Before(s.ExpiresAt)
+}
@@ -9,4 +9,68 @@ type URL struct {
 	CreatedAt   time.Time
 	ClickCount  int
 	LastClick   time.Time
+
+	// BotClickCount counts clicks from bots and link preview fetchers, which
+	// are kept out of ClickCount.
+	BotClickCount int
+
+	// ClickIDParam is the query parameter that carries a click ID to the
+	// destination, empty when the link does not track conversions.
+	// AttributionWindow bounds the time from click to conversion; zero uses
+	// the service default.
+	ClickIDParam      string
+	AttributionWindow time.Duration
+	// TrackedClickCount counts the human clicks that were given a click ID.
+	TrackedClickCount int
+	// PostbackSecret authenticates the advertiser's conversion postbacks.
+	PostbackSecret string
+
+	// CampaignID is the campaign the link belongs to, if any.
+	CampaignID string
+
+	// Tags are free-form labels; Folder is the one folder the link is filed
+	// in, empty for none.
+	Tags   []string
+	Folder string
+
+	// Metadata is fetched from the destination page in the background;
+	// MetadataOverride holds the values the owner set, which win over it.
+	Metadata         LinkMetadata
+	MetadataOverride LinkMetadata
+}
+
+// LinkMetadata describes the page a link points to. OGTitle and Description
+// come from its OpenGraph tags.
+type LinkMetadata struct {
+	Title       string
+	OGTitle     string
+	Description string
+	Image       string
+	Favicon     string
+	FetchedAt   time.Time
+}
+
+// Preview merges the owner's overrides over the fetched metadata. The
+// OpenGraph title is preferred to the page title.
+func (u *URL) Preview() LinkMetadata {
+	preview := u.Metadata
+	if preview.OGTitle != "" {
+		preview.Title = preview.OGTitle
+	}
+	preview.OGTitle = ""
+
+	override := u.MetadataOverride
+	if override.Title != "" {
+		preview.Title = override.Title
+	}
+	if override.Description != "" {
+		preview.Description = override.Description
+	}
+	if override.Image != "" {
+		preview.Image = override.Image
+	}
+	if override.Favicon != "" {
+		preview.Favicon = override.Favicon
+	}
+	return preview
 }
@@ -11,4 +11,43 @@ type URLStat struct {
 	IP        string
 	UserAgent string
 	Referer   string
+
+	// RefererHost and RefererSource are the normalized referrer, see
+	// pkg/referrer. Direct traffic has an empty host.
+	RefererHost   string
+	RefererSource string
+
+	Browser        string
+	BrowserVersion string
+	OS             string
+	OSVersion      string
+	Device         string
+
+	// Country, Region and City come from the offline GeoIP database when one
+	// is configured. Latitude and Longitude are coarse, see geoip.Location.
+	Country   string
+	Region    string
+	City      string
+	Latitude  float64
+	Longitude float64
+
+	// Class is botdetect.ClassHuman, ClassBot or ClassPreview. Only human
+	// clicks count towards headline numbers.
+	Class string
+
+	// ExpireAt is when the click is deleted; zero keeps it forever. Counters
+	// and unique visitor sketches are kept regardless.
+	ExpireAt time.Time
+
+	// OptOut marks a click recorded without any personal data because the
+	// visitor sent DNT or Sec-GPC.
+	OptOut bool
+
+	// ClickID is the identifier appended to the destination of links that
+	// track conversions, empty otherwise.
+	ClickID string
+
+	// VisitorHash is a keyed fingerprint of the visitor used to feed the
+	// unique visitor sketches. It is never persisted with the click.
+	VisitorHash uint64
 }
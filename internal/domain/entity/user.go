@@ -8,4 +8,9 @@ type User struct {
 	Email          string
 	HashedPassword string
 	CreatedAt      time.Time
+
+	// WebhookURL receives the user's traffic alerts, signed with
+	// WebhookSecret. Empty when the user has none.
+	WebhookURL    string
+	WebhookSecret string
 }
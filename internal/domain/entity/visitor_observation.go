@@ -0,0 +1,12 @@
+package entity
+
+import "time"
+
+// VisitorObservation is one HyperLogLog register update for a link's daily
+// unique visitor sketch.
+type VisitorObservation struct {
+	URLID    string
+	Day      time.Time
+	Register uint16
+	Rank     uint8
+}
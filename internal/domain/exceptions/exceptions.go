@@ -9,4 +9,19 @@ var (
 	ErrInvalidCredentials        = errors.New("invalid credentials")
 	ErrInvalidURL                = errors.New("invalid url")
 	ErrUnauthorizedURLStatistics = errors.New("unauthorized to access url statistics")
+	ErrInvalidStatsQuery         = errors.New("invalid stats query")
+	ErrInvalidConversion         = errors.New("invalid conversion")
+	ErrClickNotFound             = errors.New("click not found")
+	ErrConversionExpired         = errors.New("click outside the attribution window")
+	ErrConversionExists          = errors.New("conversion already recorded")
+	ErrInvalidPostbackSecret     = errors.New("invalid postback secret")
+	ErrShareNotFound             = errors.New("stats share not found")
+	ErrInvalidShare              = errors.New("invalid stats share")
+	ErrCampaignNotFound          = errors.New("campaign not found")
+	ErrCampaignArchived          = errors.New("campaign archived")
+	ErrInvalidCampaign           = errors.New("invalid campaign")
+	ErrInvalidLabels             = errors.New("invalid tags or folder")
+	ErrInvalidMetadata           = errors.New("invalid link metadata")
+	ErrAlertExists               = errors.New("alert already raised")
+	ErrInvalidWebhook            = errors.New("invalid alert webhook")
 )
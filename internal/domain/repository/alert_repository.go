@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"url-shortener/internal/domain/entity"
+)
+
+type AlertRepository interface {
+	// Save fails with exceptions.ErrAlertExists when the link already has an
+	// alert of the same kind for the same hour, so concurrent detectors
+	// notify once.
+	Save(ctx context.Context, alert *entity.Alert) error
+	// LastHour returns the hour of the latest alert of kind for urlID, zero
+	// when there is none.
+	LastHour(ctx context.Context, urlID, kind string) (time.Time, error)
+	// FindByOwner returns the latest alerts of ownerID, newest first.
+	FindByOwner(ctx context.Context, ownerID string, limit int) ([]entity.Alert, error)
+	// Checkpoint is the end of the last hour the detector evaluated, zero
+	// before the first run. SetCheckpoint never moves it backwards.
+	Checkpoint(ctx context.Context) (time.Time, error)
+	SetCheckpoint(ctx context.Context, through time.Time) error
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"url-shortener/internal/domain/entity"
+)
+
+type CampaignRepository interface {
+	// Save stores a new campaign and sets its ID.
+	Save(ctx context.Context, campaign *entity.Campaign) error
+	// FindByID fails with exceptions.ErrCampaignNotFound for unknown
+	// campaigns.
+	FindByID(ctx context.Context, id string) (*entity.Campaign, error)
+	// FindByOwner lists the campaigns of ownerID, newest first, leaving out
+	// archived ones unless archived is set.
+	FindByOwner(ctx context.Context, ownerID string, archived bool) ([]entity.Campaign, error)
+	// SetArchived archives the campaign at the given time, or restores it
+	// when at is zero.
+	SetArchived(ctx context.Context, id string, at time.Time) error
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+	"url-shortener/internal/domain/entity"
+)
+
+type ConversionRepository interface {
+	// Save records a conversion, failing with exceptions.ErrConversionExists
+	// when its click already converted.
+	Save(ctx context.Context, conversion *entity.Conversion) error
+	Totals(ctx context.Context, urlID string) (entity.ConversionTotals, error)
+
+	IssueClick(ctx context.Context, click *entity.IssuedClick) error
+	// FindClick fails with exceptions.ErrClickNotFound when clickID was never
+	// issued or has expired.
+	FindClick(ctx context.Context, clickID string) (*entity.IssuedClick, error)
+}
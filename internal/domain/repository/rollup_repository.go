@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"url-shortener/internal/domain/entity"
+)
+
+type RollupRepository interface {
+	// Upsert replaces the given rollups, so writing the same rollup twice is
+	// harmless.
+	Upsert(ctx context.Context, rollups []entity.Rollup) error
+	FindHourly(ctx context.Context, urlIDs []string, from, to time.Time) ([]entity.Rollup, error)
+	// ActiveLinks returns the IDs of the links with an hourly rollup in
+	// [from, to).
+	ActiveLinks(ctx context.Context, from, to time.Time) ([]string, error)
+
+	// Checkpoint is the end of the last hour folded into the rollups, zero
+	// before the first run. SetCheckpoint never moves it backwards.
+	Checkpoint(ctx context.Context) (time.Time, error)
+	SetCheckpoint(ctx context.Context, through time.Time) error
+
+	// AcquireLease takes or renews the exclusive right to run the rollup job
+	// for ttl. It reports false while another owner holds an unexpired lease.
+	AcquireLease(ctx context.Context, owner string, ttl time.Duration) (bool, error)
+	ReleaseLease(ctx context.Context, owner string) error
+
+	// TimeSeries and Breakdown read like their URLStatsRepository
+	// counterparts, over whole hours in [From, To). Breakdown supports the
+	// country, device, referrer host and link dimensions of human clicks, and
+	// returns every row when Limit is zero.
+	TimeSeries(ctx context.Context, query TimeSeriesQuery) ([]entity.ClickBucket, error)
+	Breakdown(ctx context.Context, query BreakdownQuery) ([]entity.BreakdownRow, int, error)
+}
@@ -0,0 +1,96 @@
+package repository
+
+import "time"
+
+type Interval string
+
+const (
+	IntervalHour Interval = "hour"
+	IntervalDay  Interval = "day"
+	IntervalWeek Interval = "week"
+)
+
+// TimeSeriesQuery selects clicks of the given links in [From, To) and groups
+// them into Interval buckets aligned to Location. Clicks from bots and preview
+// fetchers are left out unless IncludeBots is set.
+type TimeSeriesQuery struct {
+	URLIDs      []string
+	Interval    Interval
+	From        time.Time
+	To          time.Time
+	Location    *time.Location
+	IncludeBots bool
+}
+
+// ClickQuery selects a page of raw clicks of one link, newest first. Cursor is
+// the opaque value returned with the previous page; empty starts from the
+// newest click.
+type ClickQuery struct {
+	URLID       string
+	From        time.Time
+	To          time.Time
+	RefererHost string
+	UserAgent   string
+	Class       string
+	Cursor      string
+	Limit       int
+}
+
+type Dimension string
+
+const (
+	DimensionBrowser Dimension = "browser"
+	DimensionOS      Dimension = "os"
+	DimensionDevice  Dimension = "device"
+	// DimensionPlatform combines OS and browser, as in "iOS Safari".
+	DimensionPlatform Dimension = "platform"
+
+	DimensionRefererHost   Dimension = "referer_host"
+	DimensionRefererSource Dimension = "referer_source"
+
+	DimensionCountry Dimension = "country"
+	DimensionRegion  Dimension = "region"
+	// DimensionCity qualifies the city with its country, as in "Paris, FR".
+	DimensionCity Dimension = "city"
+
+	// DimensionLink groups by link, for breakdowns across several links.
+	DimensionLink Dimension = "url_id"
+)
+
+// BreakdownQuery groups the clicks of the given links in [From, To) by
+// Dimension, returning at most Limit rows ordered by clicks. Clicks from bots
+// and preview fetchers are left out unless IncludeBots is set.
+type BreakdownQuery struct {
+	URLIDs      []string
+	From        time.Time
+	To          time.Time
+	Dimension   Dimension
+	Limit       int
+	IncludeBots bool
+}
+
+// GeoQuery selects the located clicks of the given links in [From, To),
+// returning at most Limit points.
+type GeoQuery struct {
+	URLIDs      []string
+	From        time.Time
+	To          time.Time
+	Limit       int
+	IncludeBots bool
+}
+
+// ExportQuery selects every click of a link in [From, To), bots included. An
+// empty URLID selects the clicks of every link, in no particular order.
+type ExportQuery struct {
+	URLID string
+	From  time.Time
+	To    time.Time
+}
+
+// PurgeQuery selects clicks older than Before, of URLIDs when set, or of every
+// link except ExcludeURLIDs otherwise.
+type PurgeQuery struct {
+	URLIDs        []string
+	ExcludeURLIDs []string
+	Before        time.Time
+}
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"url-shortener/internal/domain/entity"
+)
+
+type StatsShareRepository interface {
+	Save(ctx context.Context, share *entity.StatsShare) error
+	// FindByID fails with exceptions.ErrShareNotFound for unknown shares.
+	FindByID(ctx context.Context, id string) (*entity.StatsShare, error)
+	FindByURL(ctx context.Context, urlID string) ([]entity.StatsShare, error)
+	// Revoke marks a share of urlID revoked at the given time, failing with
+	// exceptions.ErrShareNotFound when there is no such share.
+	Revoke(ctx context.Context, urlID, id string, at time.Time) error
+}
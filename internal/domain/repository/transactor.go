@@ -0,0 +1,9 @@
+package repository
+
+import "context"
+
+// Transactor runs fn atomically. Repositories called with the context passed
+// to fn take part in the transaction.
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/pkg/hll"
+)
+
+type UniqueVisitorRepository interface {
+	Observe(ctx context.Context, observations []entity.VisitorObservation) error
+	// Sketch merges the daily sketches of the given links whose day falls in
+	// [from, to). Zero bounds are open.
+	Sketch(ctx context.Context, urlIDs []string, from, to time.Time) (*hll.Sketch, error)
+}
@@ -1,9 +1,48 @@
 package repository
 
-import "url-shortener/internal/domain/entity"
+import (
+	"context"
+	"time"
+	"url-shortener/internal/domain/entity"
+)
+
+// ClickIncrement is a coalesced counter update for a single link. Count holds
+// human clicks and Bots the clicks from bots and preview fetchers. Tracked is
+// the part of Count that was given a click ID.
+type ClickIncrement struct {
+	URLID     string
+	Count     int
+	Bots      int
+	Tracked   int
+	LastClick time.Time
+}
+
+// URLFilter selects the links of OwnerID; Tag and Folder narrow the result
+// when set.
+type URLFilter struct {
+	OwnerID string
+	Tag     string
+	Folder  string
+}
 
 type URLRepository interface {
-	Save(url *entity.URL) error
-	FindByID(id string) (*entity.URL, error)
-	IncrementClick(id string) error
+	Save(ctx context.Context, url *entity.URL) error
+	FindByID(ctx context.Context, id string) (*entity.URL, error)
+	FindTop(ctx context.Context, limit int) ([]entity.URL, error)
+	FindByOwner(ctx context.Context, ownerID string) ([]entity.URL, error)
+	FindByCampaign(ctx context.Context, campaignID string) ([]entity.URL, error)
+	List(ctx context.Context, filter URLFilter) ([]entity.URL, error)
+	// SetMetadata stores fetched metadata without touching the rest of the
+	// link, so it cannot undo a concurrent edit.
+	SetMetadata(ctx context.Context, id string, metadata entity.LinkMetadata) error
+	// SetCampaign moves a link into campaignID, empty for none, along with
+	// the destination carrying that campaign's UTM values.
+	SetCampaign(ctx context.Context, id, campaignID, originalURL string) error
+	SetLabels(ctx context.Context, id string, tags []string, folder string) error
+	SetMetadataOverride(ctx context.Context, id string, override entity.LinkMetadata) error
+	SetConversions(ctx context.Context, id, clickIDParam string, window time.Duration, postbackSecret string) error
+	IncrementClick(ctx context.Context, id string) error
+	IncrementClicks(ctx context.Context, increments []ClickIncrement) error
+	ClickCounts(ctx context.Context) (map[string]int, error)
+	AdjustClickCount(ctx context.Context, id string, delta int) error
 }
@@ -1,10 +1,32 @@
 package repository
 
 import (
+	"context"
+	"time"
 	"url-shortener/internal/domain/entity"
 )
 
 type URLStatsRepository interface {
-	Save(stat *entity.URLStat) error
-	FindByURLID(id string) ([]entity.URLStat, error)
+	Save(ctx context.Context, stat *entity.URLStat) error
+	SaveMany(ctx context.Context, stats []*entity.URLStat) error
+	FindPage(ctx context.Context, query ClickQuery) ([]entity.URLStat, string, error)
+	// FindByClickID fails with exceptions.ErrClickNotFound when no stored
+	// click carries clickID.
+	FindByClickID(ctx context.Context, clickID string) (*entity.URLStat, error)
+	// Stream calls fn for every click selected by query, oldest first for a
+	// single link, stopping at the first error fn returns.
+	Stream(ctx context.Context, query ExportQuery, fn func(*entity.URLStat) error) error
+	CountByURL(ctx context.Context) (map[string]int, error)
+	// OldestClick returns the time of the first click still stored, zero when
+	// there is none.
+	OldestClick(ctx context.Context) (time.Time, error)
+	// DeleteBefore removes raw clicks older than query.Before and returns how
+	// many were deleted.
+	DeleteBefore(ctx context.Context, query PurgeQuery) (int, error)
+	TimeSeries(ctx context.Context, query TimeSeriesQuery) ([]entity.ClickBucket, error)
+	// Breakdown returns the top rows and the total click count across all
+	// values of the dimension.
+	Breakdown(ctx context.Context, query BreakdownQuery) ([]entity.BreakdownRow, int, error)
+	// Locations counts located clicks per map coordinate, most clicked first.
+	Locations(ctx context.Context, query GeoQuery) ([]entity.GeoPoint, error)
 }
@@ -1,9 +1,15 @@
 package repository
 
-import "url-shortener/internal/domain/entity"
+import (
+	"context"
+	"url-shortener/internal/domain/entity"
+)
 
 type UserRepository interface {
-	Save(user *entity.User) (*entity.User, error)
-	FindByID(id string) (*entity.User, error)
-	FindByEmail(email string) (*entity.User, error)
+	Save(ctx context.Context, user *entity.User) (*entity.User, error)
+	FindByID(ctx context.Context, id string) (*entity.User, error)
+	FindByEmail(ctx context.Context, email string) (*entity.User, error)
+	// SetWebhook stores the alert webhook of the user, removing it when url is
+	// empty.
+	SetWebhook(ctx context.Context, id, url, secret string) error
 }
@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/exceptions"
+	"url-shortener/internal/domain/repository"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// notFoundMarker is stored in place of a link that does not exist, so repeated
+// lookups of an unknown code do not fall through to the database.
+const notFoundMarker = "-"
+
+// RedisURLRepository is a read-through cache in front of another
+// URLRepository. Only FindByID is served from Redis; writes go to the wrapped
+// repository and drop the cached entry. Click counters on a cached link are as
+// stale as the TTL, so it is meant for the redirect path.
+//
+// Redis failures never fail a request: the cache is bypassed and the error is
+// logged.
+type RedisURLRepository struct {
+	next        repository.URLRepository
+	client      redis.UniversalClient
+	ttl         time.Duration
+	negativeTTL time.Duration
+}
+
+func NewRedisURLRepository(next repository.URLRepository, client redis.UniversalClient, ttl, negativeTTL time.Duration) *RedisURLRepository {
+	return &RedisURLRepository{
+		next:        next,
+		client:      client,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+	}
+}
+
+func (r *RedisURLRepository) Save(ctx context.Context, url *entity.URL) error {
+	if err := r.next.Save(ctx, url); err != nil {
+		return err
+	}
+	r.invalidate(ctx, url.ID)
+	return nil
+}
+
+func (r *RedisURLRepository) FindByID(ctx context.Context, id string) (*entity.URL, error) {
+	raw, err := r.client.Get(ctx, key(id)).Result()
+	switch {
+	case err == nil:
+		if raw == notFoundMarker {
+			return nil, exceptions.ErrURLNotFound
+		}
+		var url entity.URL
+		if err := json.Unmarshal([]byte(raw), &url); err == nil {
+			return &url, nil
+		}
+	case !errors.Is(err, redis.Nil):
+		log.Printf("cache: get %s: %v", id, err)
+	}
+
+	url, err := r.next.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, exceptions.ErrURLNotFound) && r.negativeTTL > 0 {
+			r.set(ctx, id, notFoundMarker, r.negativeTTL)
+		}
+		return nil, err
+	}
+
+	if data, err := json.Marshal(url); err == nil {
+		r.set(ctx, id, string(data), r.ttl)
+	}
+	return url, nil
+}
+
+func (r *RedisURLRepository) FindTop(ctx context.Context, limit int) ([]entity.URL, error) {
+	return r.next.FindTop(ctx, limit)
+}
+
+func (r *RedisURLRepository) FindByOwner(ctx context.Context, ownerID string) ([]entity.URL, error) {
+	return r.next.FindByOwner(ctx, ownerID)
+}
+
+func (r *RedisURLRepository) FindByCampaign(ctx context.Context, campaignID string) ([]entity.URL, error) {
+	return r.next.FindByCampaign(ctx, campaignID)
+}
+
+func (r *RedisURLRepository) List(ctx context.Context, filter repository.URLFilter) ([]entity.URL, error) {
+	return r.next.List(ctx, filter)
+}
+
+func (r *RedisURLRepository) SetMetadata(ctx context.Context, id string, metadata entity.LinkMetadata) error {
+	if err := r.next.SetMetadata(ctx, id, metadata); err != nil {
+		return err
+	}
+	r.invalidate(ctx, id)
+	return nil
+}
+
+func (r *RedisURLRepository) SetCampaign(ctx context.Context, id, campaignID, originalURL string) error {
+	if err := r.next.SetCampaign(ctx, id, campaignID, originalURL); err != nil {
+		return err
+	}
+	r.invalidate(ctx, id)
+	return nil
+}
+
+func (r *RedisURLRepository) SetLabels(ctx context.Context, id string, tags []string, folder string) error {
+	if err := r.next.SetLabels(ctx, id, tags, folder); err != nil {
+		return err
+	}
+	r.invalidate(ctx, id)
+	return nil
+}
+
+func (r *RedisURLRepository) SetMetadataOverride(ctx context.Context, id string, override entity.LinkMetadata) error {
+	if err := r.next.SetMetadataOverride(ctx, id, override); err != nil {
+		return err
+	}
+	r.invalidate(ctx, id)
+	return nil
+}
+
+func (r *RedisURLRepository) SetConversions(ctx context.Context, id, clickIDParam string, window time.Duration, postbackSecret string) error {
+	if err := r.next.SetConversions(ctx, id, clickIDParam, window, postbackSecret); err != nil {
+		return err
+	}
+	r.invalidate(ctx, id)
+	return nil
+}
+
+func (r *RedisURLRepository) IncrementClick(ctx context.Context, id string) error {
+	return r.next.IncrementClick(ctx, id)
+}
+
+func (r *RedisURLRepository) IncrementClicks(ctx context.Context, increments []repository.ClickIncrement) error {
+	return r.next.IncrementClicks(ctx, increments)
+}
+
+func (r *RedisURLRepository) ClickCounts(ctx context.Context) (map[string]int, error) {
+	return r.next.ClickCounts(ctx)
+}
+
+func (r *RedisURLRepository) AdjustClickCount(ctx context.Context, id string, delta int) error {
+	return r.next.AdjustClickCount(ctx, id, delta)
+}
+
+func (r *RedisURLRepository) set(ctx context.Context, id, value string, ttl time.Duration) {
+	if err := r.client.Set(ctx, key(id), value, ttl).Err(); err != nil {
+		log.Printf("cache: set %s: %v", id, err)
+	}
+}
+
+func (r *RedisURLRepository) invalidate(ctx context.Context, id string) {
+	if err := r.client.Del(ctx, key(id)).Err(); err != nil {
+		log.Printf("cache: invalidate %s: %v", id, err)
+	}
+}
+
+func key(id string) string {
+	return "url:" + id
+}
@@ -0,0 +1,235 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/exceptions"
+	"url-shortener/internal/domain/repository"
+	"url-shortener/internal/infra/cache"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// --- Mocks ---
+
+type MockURLRepo struct {
+	mock.Mock
+}
+
+func (m *MockURLRepo) Save(ctx context.Context, u *entity.URL) error {
+	args := m.Called(ctx, u)
+	return args.Error(0)
+}
+
+func (m *MockURLRepo) FindByID(ctx context.Context, id string) (*entity.URL, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) != nil {
+		return args.Get(0).(*entity.URL), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockURLRepo) FindTop(ctx context.Context, limit int) ([]entity.URL, error) {
+	args := m.Called(ctx, limit)
+	if args.Get(0) != nil {
+		return args.Get(0).([]entity.URL), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockURLRepo) FindByOwner(ctx context.Context, ownerID string) ([]entity.URL, error) {
+	args := m.Called(ctx, ownerID)
+	if args.Get(0) != nil {
+		return args.Get(0).([]entity.URL), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockURLRepo) FindByCampaign(ctx context.Context, campaignID string) ([]entity.URL, error) {
+	args := m.Called(ctx, campaignID)
+	if args.Get(0) != nil {
+		return args.Get(0).([]entity.URL), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockURLRepo) List(ctx context.Context, filter repository.URLFilter) ([]entity.URL, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) != nil {
+		return args.Get(0).([]entity.URL), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockURLRepo) SetMetadata(ctx context.Context, id string, metadata entity.LinkMetadata) error {
+	args := m.Called(ctx, id, metadata)
+	return args.Error(0)
+}
+
+func (m *MockURLRepo) SetCampaign(ctx context.Context, id, campaignID, originalURL string) error {
+	args := m.Called(ctx, id, campaignID, originalURL)
+	return args.Error(0)
+}
+
+func (m *MockURLRepo) SetLabels(ctx context.Context, id string, tags []string, folder string) error {
+	args := m.Called(ctx, id, tags, folder)
+	return args.Error(0)
+}
+
+func (m *MockURLRepo) SetMetadataOverride(ctx context.Context, id string, override entity.LinkMetadata) error {
+	args := m.Called(ctx, id, override)
+	return args.Error(0)
+}
+
+func (m *MockURLRepo) SetConversions(ctx context.Context, id, clickIDParam string, window time.Duration, postbackSecret string) error {
+	args := m.Called(ctx, id, clickIDParam, window, postbackSecret)
+	return args.Error(0)
+}
+
+func (m *MockURLRepo) IncrementClick(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockURLRepo) IncrementClicks(ctx context.Context, increments []repository.ClickIncrement) error {
+	args := m.Called(ctx, increments)
+	return args.Error(0)
+}
+
+func (m *MockURLRepo) ClickCounts(ctx context.Context) (map[string]int, error) {
+	args := m.Called(ctx)
+	if args.Get(0) != nil {
+		return args.Get(0).(map[string]int), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockURLRepo) AdjustClickCount(ctx context.Context, id string, delta int) error {
+	args := m.Called(ctx, id, delta)
+	return args.Error(0)
+}
+
+func newCachedRepo(t *testing.T) (*cache.RedisURLRepository, *MockURLRepo, *miniredis.Miniredis) {
+	srv := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+
+	inner := new(MockURLRepo)
+	return cache.NewRedisURLRepository(inner, client, time.Minute, 10*time.Second), inner, srv
+}
+
+// --- Tests ---
+
+func TestRedisURLRepository_FindByID_ReadThrough(t *testing.T) {
+	repo, inner, _ := newCachedRepo(t)
+	ctx := context.Background()
+
+	urlEntity := &entity.URL{ID: "abc123", OriginalURL: "https://example.com"}
+	inner.On("FindByID", mock.Anything, "abc123").Return(urlEntity, nil).Once()
+
+	first, err := repo.FindByID(ctx, "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", first.OriginalURL)
+
+	second, err := repo.FindByID(ctx, "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", second.OriginalURL)
+
+	inner.AssertNumberOfCalls(t, "FindByID", 1)
+}
+
+func TestRedisURLRepository_FindByID_NegativeCache(t *testing.T) {
+	repo, inner, srv := newCachedRepo(t)
+	ctx := context.Background()
+
+	inner.On("FindByID", mock.Anything, "missing").Return(nil, exceptions.ErrURLNotFound)
+
+	_, err := repo.FindByID(ctx, "missing")
+	assert.ErrorIs(t, err, exceptions.ErrURLNotFound)
+	_, err = repo.FindByID(ctx, "missing")
+	assert.ErrorIs(t, err, exceptions.ErrURLNotFound)
+	inner.AssertNumberOfCalls(t, "FindByID", 1)
+
+	srv.FastForward(11 * time.Second)
+
+	_, err = repo.FindByID(ctx, "missing")
+	assert.ErrorIs(t, err, exceptions.ErrURLNotFound)
+	inner.AssertNumberOfCalls(t, "FindByID", 2)
+}
+
+func TestRedisURLRepository_FindByID_DoesNotCacheErrors(t *testing.T) {
+	repo, inner, srv := newCachedRepo(t)
+	ctx := context.Background()
+
+	inner.On("FindByID", mock.Anything, "abc123").Return(nil, errors.New("db down"))
+
+	_, err := repo.FindByID(ctx, "abc123")
+	assert.EqualError(t, err, "db down")
+	assert.False(t, srv.Exists("url:abc123"))
+}
+
+func TestRedisURLRepository_InvalidatesOnWrites(t *testing.T) {
+	repo, inner, srv := newCachedRepo(t)
+	ctx := context.Background()
+
+	urlEntity := &entity.URL{ID: "abc123", OriginalURL: "https://example.com"}
+	inner.On("FindByID", mock.Anything, "abc123").Return(urlEntity, nil)
+	inner.On("SetMetadata", mock.Anything, "abc123", mock.Anything).Return(nil)
+	inner.On("SetCampaign", mock.Anything, "abc123", "spring", mock.Anything).Return(nil)
+	inner.On("SetLabels", mock.Anything, "abc123", []string{"promo"}, "").Return(nil)
+	inner.On("SetMetadataOverride", mock.Anything, "abc123", mock.Anything).Return(nil)
+
+	_, _ = repo.FindByID(ctx, "abc123")
+	assert.True(t, srv.Exists("url:abc123"))
+	assert.NoError(t, repo.SetMetadata(ctx, "abc123", entity.LinkMetadata{Title: "Example"}))
+	assert.False(t, srv.Exists("url:abc123"))
+
+	_, _ = repo.FindByID(ctx, "abc123")
+	assert.True(t, srv.Exists("url:abc123"))
+	assert.NoError(t, repo.SetCampaign(ctx, "abc123", "spring", "https://example.com?utm_campaign=spring"))
+	assert.False(t, srv.Exists("url:abc123"))
+
+	_, _ = repo.FindByID(ctx, "abc123")
+	assert.True(t, srv.Exists("url:abc123"))
+	assert.NoError(t, repo.SetLabels(ctx, "abc123", []string{"promo"}, ""))
+	assert.False(t, srv.Exists("url:abc123"))
+
+	_, _ = repo.FindByID(ctx, "abc123")
+	assert.True(t, srv.Exists("url:abc123"))
+	assert.NoError(t, repo.SetMetadataOverride(ctx, "abc123", entity.LinkMetadata{Title: "Sale"}))
+	assert.False(t, srv.Exists("url:abc123"))
+}
+
+func TestRedisURLRepository_SaveClearsNegativeEntry(t *testing.T) {
+	repo, inner, srv := newCachedRepo(t)
+	ctx := context.Background()
+
+	urlEntity := &entity.URL{ID: "abc123", OriginalURL: "https://example.com"}
+	inner.On("FindByID", mock.Anything, "abc123").Return(nil, exceptions.ErrURLNotFound).Once()
+	inner.On("Save", mock.Anything, urlEntity).Return(nil)
+
+	_, _ = repo.FindByID(ctx, "abc123")
+	assert.True(t, srv.Exists("url:abc123"))
+
+	assert.NoError(t, repo.Save(ctx, urlEntity))
+	assert.False(t, srv.Exists("url:abc123"))
+}
+
+func TestRedisURLRepository_FallsBackWhenRedisIsDown(t *testing.T) {
+	repo, inner, srv := newCachedRepo(t)
+	ctx := context.Background()
+	srv.Close()
+
+	urlEntity := &entity.URL{ID: "abc123", OriginalURL: "https://example.com"}
+	inner.On("FindByID", mock.Anything, "abc123").Return(urlEntity, nil)
+
+	res, err := repo.FindByID(ctx, "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", res.OriginalURL)
+}
@@ -0,0 +1,90 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/smtp"
+	"strings"
+	"time"
+	"url-shortener/internal/domain/entity"
+)
+
+// SendMailFunc has the signature of smtp.SendMail.
+type SendMailFunc func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error
+
+// EmailNotifier mails every alert to the email of the link's owner over
+// SMTP. Alerts whose owner could not be loaded are skipped.
+type EmailNotifier struct {
+	addr     string
+	from     string
+	auth     smtp.Auth
+	sendMail SendMailFunc
+}
+
+type EmailOption func(*EmailNotifier)
+
+// WithSendMail replaces smtp.SendMail, for tests.
+func WithSendMail(fn SendMailFunc) EmailOption {
+	return func(n *EmailNotifier) {
+		n.sendMail = fn
+	}
+}
+
+// NewEmailNotifier sends through the SMTP server at addr (host:port),
+// authenticating with PLAIN auth when username is set.
+func NewEmailNotifier(addr, from, username, password string, opts ...EmailOption) *EmailNotifier {
+	n := &EmailNotifier{addr: addr, from: from, sendMail: smtp.SendMail}
+	if username != "" {
+		host, _, _ := strings.Cut(addr, ":")
+		n.auth = smtp.PlainAuth("", username, password, host)
+	}
+	for _, opt := range opts {
+		opt(n)
+	}
+	return n
+}
+
+func (n *EmailNotifier) Notify(_ context.Context, alert entity.Alert, owner *entity.User) error {
+	if owner == nil || owner.Email == "" {
+		return nil
+	}
+
+	subject, body := emailContent(alert)
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", n.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", owner.Email)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	return n.sendMail(n.addr, n.auth, n.from, []string{owner.Email}, []byte(msg.String()))
+}
+
+func emailContent(alert entity.Alert) (string, string) {
+	hour := alert.Hour.UTC().Format("02/01/2006 15:04") + " UTC"
+	var subject, summary string
+	switch alert.Kind {
+	case entity.AlertSpike:
+		subject = "Pico de cliques no link " + alert.URLID
+		summary = fmt.Sprintf("O link recebeu %d cliques na hora iniciada em %s, contra uma média de %.1f por hora.",
+			alert.Observed, hour, alert.Baseline)
+	case entity.AlertFlat:
+		subject = "O link " + alert.URLID + " parou de receber cliques"
+		summary = fmt.Sprintf("O link não recebe cliques desde %s. A média anterior era de %.1f cliques por hora.",
+			hour, alert.Baseline)
+	case entity.AlertBotBurst:
+		subject = "Tráfego de bots no link " + alert.URLID
+		summary = fmt.Sprintf("O link recebeu %d cliques de bots na hora iniciada em %s, contra uma média de %.1f por hora.",
+			alert.Observed, hour, alert.Baseline)
+	default:
+		subject = "Alerta de tráfego no link " + alert.URLID
+		summary = fmt.Sprintf("Tráfego incomum na hora iniciada em %s.", hour)
+	}
+
+	body := summary + "\n\nDestino: " + alert.OriginalURL +
+		"\nAlerta gerado em " + alert.CreatedAt.UTC().Format(time.RFC3339) + ".\n"
+	return subject, body
+}
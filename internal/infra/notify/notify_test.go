@@ -0,0 +1,124 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"testing"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/infra/notify"
+	"url-shortener/pkg/linkmeta"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var alert = entity.Alert{
+	ID:          "alert1",
+	URLID:       "abc123",
+	OwnerID:     "owner1",
+	OriginalURL: "https://partner.example/landing",
+	Kind:        entity.AlertFlat,
+	Hour:        time.Date(2025, 3, 1, 14, 0, 0, 0, time.UTC),
+	Baseline:    12.5,
+	CreatedAt:   time.Date(2025, 3, 1, 20, 5, 0, 0, time.UTC),
+}
+
+func TestWebhookNotifier_PostsSignedAlert(t *testing.T) {
+	var body []byte
+	var path, signature string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get("X-Signature")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	n := notify.NewWebhookNotifier(time.Second, notify.WithHTTPClient(srv.Client()))
+	owner := &entity.User{ID: "owner1", WebhookURL: srv.URL + "/hooks", WebhookSecret: "s3cret"}
+	assert.NoError(t, n.Notify(context.Background(), alert, owner))
+
+	var payload map[string]any
+	assert.NoError(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, "flat", payload["kind"])
+	assert.Equal(t, "abc123", payload["url_id"])
+	assert.Equal(t, "owner1", payload["owner_id"])
+	assert.Equal(t, "/hooks", path)
+	assert.Equal(t, "sha256="+notify.Sign([]byte("s3cret"), body), signature)
+}
+
+func TestWebhookNotifier_FailsOnErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	n := notify.NewWebhookNotifier(time.Second, notify.WithHTTPClient(srv.Client()))
+	assert.Error(t, n.Notify(context.Background(), alert, &entity.User{ID: "owner1", WebhookURL: srv.URL}))
+}
+
+func TestWebhookNotifier_SkipsOwnersWithoutWebhook(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer srv.Close()
+
+	n := notify.NewWebhookNotifier(time.Second, notify.WithHTTPClient(srv.Client()))
+
+	assert.NoError(t, n.Notify(context.Background(), alert, nil))
+	assert.NoError(t, n.Notify(context.Background(), alert, &entity.User{ID: "owner1"}))
+	assert.Zero(t, calls)
+}
+
+func TestWebhookNotifier_RefusesPrivateAddresses(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer srv.Close()
+
+	n := notify.NewWebhookNotifier(time.Second)
+	err := n.Notify(context.Background(), alert, &entity.User{ID: "owner1", WebhookURL: srv.URL})
+
+	assert.ErrorIs(t, err, linkmeta.ErrBlockedAddress)
+	assert.Zero(t, calls)
+}
+
+func TestEmailNotifier_MailsOwner(t *testing.T) {
+	var sentTo []string
+	var msg string
+	send := func(addr string, _ smtp.Auth, from string, to []string, raw []byte) error {
+		assert.Equal(t, "smtp.example:587", addr)
+		assert.Equal(t, "alerts@sho.rt", from)
+		sentTo, msg = to, string(raw)
+		return nil
+	}
+	n := notify.NewEmailNotifier("smtp.example:587", "alerts@sho.rt", "", "", notify.WithSendMail(send))
+
+	err := n.Notify(context.Background(), alert, &entity.User{ID: "owner1", Email: "ana@example.com"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ana@example.com"}, sentTo)
+	assert.Contains(t, msg, "To: ana@example.com\r\n")
+	assert.Contains(t, msg, "Subject: O link abc123 parou de receber cliques\r\n")
+	assert.Contains(t, msg, "não recebe cliques desde 01/03/2025 14:00 UTC")
+	assert.Contains(t, msg, "https://partner.example/landing")
+}
+
+func TestEmailNotifier_SkipsUnknownOwner(t *testing.T) {
+	calls := 0
+	n := notify.NewEmailNotifier("smtp.example:25", "alerts@sho.rt", "", "", notify.WithSendMail(
+		func(string, smtp.Auth, string, []string, []byte) error {
+			calls++
+			return nil
+		}))
+
+	assert.NoError(t, n.Notify(context.Background(), alert, nil))
+	assert.NoError(t, n.Notify(context.Background(), alert, &entity.User{ID: "owner1"}))
+	assert.Zero(t, calls)
+}
@@ -0,0 +1,117 @@
+// Package notify delivers traffic alerts to link owners.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/pkg/linkmeta"
+)
+
+// WebhookNotifier posts each alert as JSON to the webhook of the link owner,
+// if they set one. The body is signed with the owner's secret using
+// HMAC-SHA256, in the X-Signature header as "sha256=" followed by the hex
+// digest. Endpoints are user-supplied, so requests never reach non-public
+// addresses and redirects are not followed.
+type WebhookNotifier struct {
+	client *http.Client
+}
+
+type WebhookOption func(*WebhookNotifier)
+
+// WithHTTPClient replaces the SSRF-safe client, for tests against local
+// servers.
+func WithHTTPClient(client *http.Client) WebhookOption {
+	return func(n *WebhookNotifier) {
+		n.client = client
+	}
+}
+
+func NewWebhookNotifier(timeout time.Duration, opts ...WebhookOption) *WebhookNotifier {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	n := &WebhookNotifier{
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: linkmeta.SafeTransport(timeout),
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+	for _, opt := range opts {
+		opt(n)
+	}
+	return n
+}
+
+type webhookPayload struct {
+	ID          string    `json:"id"`
+	Kind        string    `json:"kind"`
+	URLID       string    `json:"url_id"`
+	OriginalURL string    `json:"original_url"`
+	OwnerID     string    `json:"owner_id"`
+	Hour        time.Time `json:"hour"`
+	Observed    int       `json:"observed"`
+	Baseline    float64   `json:"baseline"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, alert entity.Alert, owner *entity.User) error {
+	if owner == nil || owner.WebhookURL == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(webhookPayload{
+		ID:          alert.ID,
+		Kind:        alert.Kind,
+		URLID:       alert.URLID,
+		OriginalURL: alert.OriginalURL,
+		OwnerID:     alert.OwnerID,
+		Hour:        alert.Hour,
+		Observed:    alert.Observed,
+		Baseline:    alert.Baseline,
+		CreatedAt:   alert.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, owner.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if owner.WebhookSecret != "" {
+		req.Header.Set("X-Signature", "sha256="+Sign([]byte(owner.WebhookSecret), body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the hex HMAC-SHA256 of body, as receivers should compute it
+// to verify X-Signature.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,198 @@
+package persistence
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Migrations is the ordered list of schema changes applied by the Migrator.
+// Append new entries with the next version number; never reorder or edit
+// entries that have already shipped.
+var Migrations = []Migration{
+	{
+		Version:     1,
+		Description: "unique index on users.email",
+		Up: createIndex("users", mongo.IndexModel{
+			Keys:    bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().SetName("email_unique").SetUnique(true),
+		}),
+	},
+	{
+		Version:     2,
+		Description: "index on url_stats (url_id, clicked_at)",
+		Up: createIndex("url_stats", mongo.IndexModel{
+			Keys:    bson.D{{Key: "url_id", Value: 1}, {Key: "clicked_at", Value: 1}},
+			Options: options.Index().SetName("url_id_clicked_at"),
+		}),
+	},
+	{
+		Version:     3,
+		Description: "index on urls.owner_id",
+		Up: createIndex("urls", mongo.IndexModel{
+			Keys:    bson.D{{Key: "owner_id", Value: 1}},
+			Options: options.Index().SetName("owner_id"),
+		}),
+	},
+	{
+		Version:     4,
+		Description: "index on urls.click_count",
+		Up: createIndex("urls", mongo.IndexModel{
+			Keys:    bson.D{{Key: "click_count", Value: -1}},
+			Options: options.Index().SetName("click_count_desc"),
+		}),
+	},
+	{
+		Version:     5,
+		Description: "index on url_stats (url_id, _id) for click log pagination",
+		Up: createIndex("url_stats", mongo.IndexModel{
+			Keys:    bson.D{{Key: "url_id", Value: 1}, {Key: "_id", Value: -1}},
+			Options: options.Index().SetName("url_id_id_desc"),
+		}),
+	},
+	{
+		Version:     6,
+		Description: "index on url_uniques (url_id, day)",
+		Up: createIndex("url_uniques", mongo.IndexModel{
+			Keys:    bson.D{{Key: "url_id", Value: 1}, {Key: "day", Value: 1}},
+			Options: options.Index().SetName("url_id_day"),
+		}),
+	},
+	{
+		Version:     7,
+		Description: "index on url_stats (url_id, referer_host) for referrer filters",
+		Up: createIndex("url_stats", mongo.IndexModel{
+			Keys:    bson.D{{Key: "url_id", Value: 1}, {Key: "referer_host", Value: 1}},
+			Options: options.Index().SetName("url_id_referer_host"),
+		}),
+	},
+	{
+		Version:     8,
+		Description: "TTL index on url_stats.expire_at",
+		Up: createIndex("url_stats", mongo.IndexModel{
+			Keys:    bson.D{{Key: "expire_at", Value: 1}},
+			Options: options.Index().SetName("expire_at_ttl").SetExpireAfterSeconds(0),
+		}),
+	},
+	{
+		Version:     9,
+		Description: "index on url_stats.clicked_at for retention purges",
+		Up: createIndex("url_stats", mongo.IndexModel{
+			Keys:    bson.D{{Key: "clicked_at", Value: 1}},
+			Options: options.Index().SetName("clicked_at"),
+		}),
+	},
+	{
+		Version:     10,
+		Description: "index on url_rollups (url_id, granularity, start) for rollup reads",
+		Up: createIndex("url_rollups", mongo.IndexModel{
+			Keys:    bson.D{{Key: "url_id", Value: 1}, {Key: "granularity", Value: 1}, {Key: "start", Value: 1}},
+			Options: options.Index().SetName("url_id_granularity_start"),
+		}),
+	},
+	{
+		Version:     11,
+		Description: "sparse index on url_stats.click_id for conversion attribution",
+		Up: createIndex("url_stats", mongo.IndexModel{
+			Keys:    bson.D{{Key: "click_id", Value: 1}},
+			Options: options.Index().SetName("click_id").SetSparse(true),
+		}),
+	},
+	{
+		Version:     12,
+		Description: "unique index on url_conversions.click_id",
+		Up: createIndex("url_conversions", mongo.IndexModel{
+			Keys:    bson.D{{Key: "click_id", Value: 1}},
+			Options: options.Index().SetName("click_id_unique").SetUnique(true),
+		}),
+	},
+	{
+		Version:     13,
+		Description: "index on url_conversions.url_id for conversion totals",
+		Up: createIndex("url_conversions", mongo.IndexModel{
+			Keys:    bson.D{{Key: "url_id", Value: 1}},
+			Options: options.Index().SetName("url_id"),
+		}),
+	},
+	{
+		Version:     14,
+		Description: "index on stats_shares.url_id for listing a link's shares",
+		Up: createIndex("stats_shares", mongo.IndexModel{
+			Keys:    bson.D{{Key: "url_id", Value: 1}},
+			Options: options.Index().SetName("url_id"),
+		}),
+	},
+	{
+		Version:     15,
+		Description: "index on urls.campaign_id for campaign reports",
+		Up: createIndex("urls", mongo.IndexModel{
+			Keys:    bson.D{{Key: "campaign_id", Value: 1}},
+			Options: options.Index().SetName("campaign_id").SetSparse(true),
+		}),
+	},
+	{
+		Version:     16,
+		Description: "index on campaigns (owner_id, created_at) for campaign listing",
+		Up: createIndex("campaigns", mongo.IndexModel{
+			Keys:    bson.D{{Key: "owner_id", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("owner_id_created_at"),
+		}),
+	},
+	{
+		Version:     17,
+		Description: "index on urls (owner_id, tags) for tag filters",
+		Up: createIndex("urls", mongo.IndexModel{
+			Keys:    bson.D{{Key: "owner_id", Value: 1}, {Key: "tags", Value: 1}},
+			Options: options.Index().SetName("owner_id_tags"),
+		}),
+	},
+	{
+		Version:     18,
+		Description: "index on urls (owner_id, folder) for folder filters",
+		Up: createIndex("urls", mongo.IndexModel{
+			Keys:    bson.D{{Key: "owner_id", Value: 1}, {Key: "folder", Value: 1}},
+			Options: options.Index().SetName("owner_id_folder"),
+		}),
+	},
+	{
+		Version:     19,
+		Description: "index on url_rollups (granularity, start) for active link scans",
+		Up: createIndex("url_rollups", mongo.IndexModel{
+			Keys:    bson.D{{Key: "granularity", Value: 1}, {Key: "start", Value: 1}},
+			Options: options.Index().SetName("granularity_start"),
+		}),
+	},
+	{
+		Version:     20,
+		Description: "unique index on url_alerts (url_id, kind, hour) so each anomaly alerts once",
+		Up: createIndex("url_alerts", mongo.IndexModel{
+			Keys:    bson.D{{Key: "url_id", Value: 1}, {Key: "kind", Value: 1}, {Key: "hour", Value: -1}},
+			Options: options.Index().SetName("url_id_kind_hour").SetUnique(true),
+		}),
+	},
+	{
+		Version:     21,
+		Description: "index on url_alerts (owner_id, created_at) for alert listing",
+		Up: createIndex("url_alerts", mongo.IndexModel{
+			Keys:    bson.D{{Key: "owner_id", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("owner_id_created_at"),
+		}),
+	},
+	{
+		Version:     22,
+		Description: "TTL index on url_click_ids.expire_at",
+		Up: createIndex("url_click_ids", mongo.IndexModel{
+			Keys:    bson.D{{Key: "expire_at", Value: 1}},
+			Options: options.Index().SetName("expire_at_ttl").SetExpireAfterSeconds(0),
+		}),
+	},
+}
+
+func createIndex(collection string, index mongo.IndexModel) func(ctx context.Context, db *mongo.Database) error {
+	return func(ctx context.Context, db *mongo.Database) error {
+		_, err := db.Collection(collection).Indexes().CreateOne(ctx, index)
+		return err
+	}
+}
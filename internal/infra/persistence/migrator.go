@@ -0,0 +1,91 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+	"url-shortener/internal/infra/persistence/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Migration is a single, versioned schema change. Versions must be unique and
+// are applied in ascending order; once a version is recorded as applied it is
+// never run again, so a released migration must not be edited.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+}
+
+type Migrator struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+	migrations []Migration
+}
+
+func NewMigrator(db *mongo.Database, migrations []Migration) *Migrator {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	return &Migrator{
+		db:         db,
+		collection: db.Collection("schema_migrations"),
+		migrations: sorted,
+	}
+}
+
+// Run applies every migration that has not been recorded yet and returns the
+// ones it applied. Migrations must be idempotent: two replicas starting at the
+// same time may both run the same version, and only the first record wins.
+func (m *Migrator) Run(ctx context.Context) ([]Migration, error) {
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for _, mig := range m.migrations {
+		if applied[mig.Version] {
+			continue
+		}
+
+		if err := mig.Up(ctx, m.db); err != nil {
+			return ran, fmt.Errorf("migration %d (%s): %w", mig.Version, mig.Description, err)
+		}
+
+		record := model.Migration{
+			Version:     mig.Version,
+			Description: mig.Description,
+			AppliedAt:   time.Now(),
+		}
+		if _, err := m.collection.InsertOne(ctx, record); err != nil && !mongo.IsDuplicateKeyError(err) {
+			return ran, fmt.Errorf("record migration %d: %w", mig.Version, err)
+		}
+		ran = append(ran, mig)
+	}
+
+	return ran, nil
+}
+
+func (m *Migrator) appliedVersions(ctx context.Context) (map[int]bool, error) {
+	cursor, err := m.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	applied := make(map[int]bool)
+	for cursor.Next(ctx) {
+		var rec model.Migration
+		if err := cursor.Decode(&rec); err != nil {
+			return nil, err
+		}
+		applied[rec.Version] = true
+	}
+
+	return applied, cursor.Err()
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+type Alert struct {
+	ID          string    `bson:"_id" json:"id"`
+	URLID       string    `bson:"url_id" json:"url_id"`
+	OwnerID     string    `bson:"owner_id" json:"owner_id"`
+	OriginalURL string    `bson:"original_url" json:"original_url"`
+	Kind        string    `bson:"kind" json:"kind"`
+	Hour        time.Time `bson:"hour" json:"hour"`
+	Observed    int       `bson:"observed" json:"observed"`
+	Baseline    float64   `bson:"baseline" json:"baseline"`
+	CreatedAt   time.Time `bson:"created_at" json:"created_at"`
+}
+
+// AlertState holds the detector checkpoint.
+type AlertState struct {
+	ID      string    `bson:"_id" json:"id"`
+	Through time.Time `bson:"through" json:"through"`
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+type UTM struct {
+	Source   string `bson:"source,omitempty" json:"source,omitempty"`
+	Medium   string `bson:"medium,omitempty" json:"medium,omitempty"`
+	Campaign string `bson:"campaign,omitempty" json:"campaign,omitempty"`
+	Term     string `bson:"term,omitempty" json:"term,omitempty"`
+	Content  string `bson:"content,omitempty" json:"content,omitempty"`
+}
+
+type Campaign struct {
+	ID         string     `bson:"_id" json:"id"`
+	OwnerID    string     `bson:"owner_id" json:"owner_id"`
+	Name       string     `bson:"name" json:"name"`
+	StartsAt   *time.Time `bson:"starts_at,omitempty" json:"starts_at,omitempty"`
+	EndsAt     *time.Time `bson:"ends_at,omitempty" json:"ends_at,omitempty"`
+	UTM        UTM        `bson:"utm" json:"utm"`
+	CreatedAt  time.Time  `bson:"created_at" json:"created_at"`
+	ArchivedAt *time.Time `bson:"archived_at,omitempty" json:"archived_at,omitempty"`
+}
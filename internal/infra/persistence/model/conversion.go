@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Conversion struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	URLID       string             `bson:"url_id" json:"url_id"`
+	ClickID     string             `bson:"click_id" json:"click_id"`
+	Value       float64            `bson:"value,omitempty" json:"value,omitempty"`
+	ClickedAt   time.Time          `bson:"clicked_at" json:"clicked_at"`
+	ConvertedAt time.Time          `bson:"converted_at" json:"converted_at"`
+}
+
+type IssuedClick struct {
+	ClickID   string    `bson:"_id" json:"click_id"`
+	URLID     string    `bson:"url_id" json:"url_id"`
+	ClickedAt time.Time `bson:"clicked_at" json:"clicked_at"`
+
+	// ExpireAt is a pointer so that click IDs without an attribution limit
+	// have no value for the TTL index to act on.
+	ExpireAt *time.Time `bson:"expire_at,omitempty" json:"expire_at,omitempty"`
+}
@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// Rollup is stored with its breakdowns as arrays rather than maps because
+// keys such as referrer hosts contain dots.
+type Rollup struct {
+	ID          string    `bson:"_id" json:"id"`
+	URLID       string    `bson:"url_id" json:"url_id"`
+	Granularity string    `bson:"granularity" json:"granularity"`
+	Start       time.Time `bson:"start" json:"start"`
+
+	Human    int `bson:"human" json:"human"`
+	Bots     int `bson:"bots" json:"bots"`
+	Previews int `bson:"previews" json:"previews"`
+
+	Countries []RollupCount `bson:"countries" json:"countries"`
+	Devices   []RollupCount `bson:"devices" json:"devices"`
+	Referrers []RollupCount `bson:"referrers" json:"referrers"`
+}
+
+type RollupCount struct {
+	Key   string `bson:"k" json:"k"`
+	Count int    `bson:"n" json:"n"`
+}
+
+// RollupState holds the job checkpoint and its lease, one document each.
+type RollupState struct {
+	ID        string    `bson:"_id" json:"id"`
+	Through   time.Time `bson:"through,omitempty" json:"through,omitempty"`
+	Owner     string    `bson:"owner,omitempty" json:"owner,omitempty"`
+	ExpiresAt time.Time `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+}
@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+type StatsShare struct {
+	ID        string     `bson:"_id" json:"id"`
+	URLID     string     `bson:"url_id" json:"url_id"`
+	OwnerID   string     `bson:"owner_id" json:"owner_id"`
+	CreatedAt time.Time  `bson:"created_at" json:"created_at"`
+	ExpiresAt *time.Time `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	RevokedAt *time.Time `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+}
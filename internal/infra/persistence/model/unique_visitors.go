@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// UniqueVisitors is a per-link, per-day HyperLogLog sketch. Registers are keyed
+// by their index so updates can use $max on individual registers.
+type UniqueVisitors struct {
+	ID        string           `bson:"_id" json:"id"`
+	URLID     string           `bson:"url_id" json:"url_id"`
+	Day       time.Time        `bson:"day" json:"day"`
+	Registers map[string]uint8 `bson:"registers" json:"registers"`
+}
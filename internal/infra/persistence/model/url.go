@@ -10,4 +10,28 @@ type URL struct {
 
 	ClickCount int       `bson:"click_count" json:"click_count"`
 	LastClick  time.Time `bson:"last_click,omitempty" json:"last_click,omitempty"`
+
+	BotClickCount int `bson:"bot_click_count" json:"bot_click_count"`
+
+	ClickIDParam      string        `bson:"click_id_param,omitempty" json:"click_id_param,omitempty"`
+	AttributionWindow time.Duration `bson:"attribution_window,omitempty" json:"attribution_window,omitempty"`
+	TrackedClickCount int           `bson:"tracked_click_count,omitempty" json:"tracked_click_count,omitempty"`
+	PostbackSecret    string        `bson:"postback_secret,omitempty" json:"-"`
+
+	CampaignID string `bson:"campaign_id,omitempty" json:"campaign_id,omitempty"`
+
+	Tags   []string `bson:"tags,omitempty" json:"tags,omitempty"`
+	Folder string   `bson:"folder,omitempty" json:"folder,omitempty"`
+
+	Metadata         *LinkMetadata `bson:"metadata,omitempty" json:"metadata,omitempty"`
+	MetadataOverride *LinkMetadata `bson:"metadata_override,omitempty" json:"metadata_override,omitempty"`
+}
+
+type LinkMetadata struct {
+	Title       string    `bson:"title,omitempty" json:"title,omitempty"`
+	OGTitle     string    `bson:"og_title,omitempty" json:"og_title,omitempty"`
+	Description string    `bson:"description,omitempty" json:"description,omitempty"`
+	Image       string    `bson:"image,omitempty" json:"image,omitempty"`
+	Favicon     string    `bson:"favicon,omitempty" json:"favicon,omitempty"`
+	FetchedAt   time.Time `bson:"fetched_at,omitempty" json:"fetched_at,omitempty"`
 }
@@ -13,4 +13,28 @@ type URLStat struct {
 	IP        string             `bson:"ip" json:"ip"`
 	UserAgent string             `bson:"user_agent" json:"user_agent"`
 	Referer   string             `bson:"referer,omitempty" json:"referer,omitempty"`
+
+	RefererHost   string `bson:"referer_host,omitempty" json:"referer_host,omitempty"`
+	RefererSource string `bson:"referer_source,omitempty" json:"referer_source,omitempty"`
+
+	Browser        string `bson:"browser,omitempty" json:"browser,omitempty"`
+	BrowserVersion string `bson:"browser_version,omitempty" json:"browser_version,omitempty"`
+	OS             string `bson:"os,omitempty" json:"os,omitempty"`
+	OSVersion      string `bson:"os_version,omitempty" json:"os_version,omitempty"`
+	Device         string `bson:"device,omitempty" json:"device,omitempty"`
+
+	Country   string  `bson:"country,omitempty" json:"country,omitempty"`
+	Region    string  `bson:"region,omitempty" json:"region,omitempty"`
+	City      string  `bson:"city,omitempty" json:"city,omitempty"`
+	Latitude  float64 `bson:"lat,omitempty" json:"lat,omitempty"`
+	Longitude float64 `bson:"lon,omitempty" json:"lon,omitempty"`
+
+	Class  string `bson:"class,omitempty" json:"class,omitempty"`
+	OptOut bool   `bson:"opt_out,omitempty" json:"opt_out,omitempty"`
+
+	ClickID string `bson:"click_id,omitempty" json:"click_id,omitempty"`
+
+	// ExpireAt is a pointer so that clicks kept forever have no value for the
+	// TTL index to act on.
+	ExpireAt *time.Time `bson:"expire_at,omitempty" json:"expire_at,omitempty"`
 }
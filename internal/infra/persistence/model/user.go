@@ -12,4 +12,6 @@ type User struct {
 	Email          string             `bson:"email" json:"email"`
 	HashedPassword string             `bson:"password" json:"password"`
 	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	WebhookURL     string             `bson:"webhook_url,omitempty" json:"webhook_url,omitempty"`
+	WebhookSecret  string             `bson:"webhook_secret,omitempty" json:"-"`
 }
@@ -0,0 +1,109 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/exceptions"
+	"url-shortener/internal/infra/persistence/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoAlertRepository struct {
+	collection *mongo.Collection
+	state      *mongo.Collection
+}
+
+func NewMongoAlertRepository(db *mongo.Database) *MongoAlertRepository {
+	return &MongoAlertRepository{
+		collection: db.Collection("url_alerts"),
+		state:      db.Collection("alert_state"),
+	}
+}
+
+func (r *MongoAlertRepository) Save(ctx context.Context, alert *entity.Alert) error {
+	m := model.Alert{
+		ID:          primitive.NewObjectID().Hex(),
+		URLID:       alert.URLID,
+		OwnerID:     alert.OwnerID,
+		OriginalURL: alert.OriginalURL,
+		Kind:        alert.Kind,
+		Hour:        alert.Hour,
+		Observed:    alert.Observed,
+		Baseline:    alert.Baseline,
+		CreatedAt:   alert.CreatedAt,
+	}
+	if _, err := r.collection.InsertOne(ctx, m); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return exceptions.ErrAlertExists
+		}
+		return err
+	}
+	alert.ID = m.ID
+	return nil
+}
+
+func (r *MongoAlertRepository) LastHour(ctx context.Context, urlID, kind string) (time.Time, error) {
+	var m model.Alert
+	opts := options.FindOne().SetSort(bson.D{{Key: "hour", Value: -1}})
+	err := r.collection.FindOne(ctx, bson.M{"url_id": urlID, "kind": kind}, opts).Decode(&m)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return m.Hour, nil
+}
+
+func (r *MongoAlertRepository) FindByOwner(ctx context.Context, ownerID string, limit int) ([]entity.Alert, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := r.collection.Find(ctx, bson.M{"owner_id": ownerID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var alerts []entity.Alert
+	for cursor.Next(ctx) {
+		var m model.Alert
+		if err := cursor.Decode(&m); err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, entity.Alert{
+			ID:          m.ID,
+			URLID:       m.URLID,
+			OwnerID:     m.OwnerID,
+			OriginalURL: m.OriginalURL,
+			Kind:        m.Kind,
+			Hour:        m.Hour,
+			Observed:    m.Observed,
+			Baseline:    m.Baseline,
+			CreatedAt:   m.CreatedAt,
+		})
+	}
+	return alerts, cursor.Err()
+}
+
+func (r *MongoAlertRepository) Checkpoint(ctx context.Context) (time.Time, error) {
+	var m model.AlertState
+	err := r.state.FindOne(ctx, bson.M{"_id": checkpointID}).Decode(&m)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return time.Time{}, nil
+	}
+	return m.Through, err
+}
+
+func (r *MongoAlertRepository) SetCheckpoint(ctx context.Context, through time.Time) error {
+	_, err := r.state.UpdateOne(ctx,
+		bson.M{"_id": checkpointID},
+		bson.M{"$max": bson.M{"through": through}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
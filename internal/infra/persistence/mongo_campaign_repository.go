@@ -0,0 +1,139 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/exceptions"
+	"url-shortener/internal/infra/persistence/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoCampaignRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoCampaignRepository(db *mongo.Database) *MongoCampaignRepository {
+	return &MongoCampaignRepository{
+		collection: db.Collection("campaigns"),
+	}
+}
+
+func (r *MongoCampaignRepository) Save(ctx context.Context, campaign *entity.Campaign) error {
+	m := fromModelCampaign(campaign)
+	m.ID = primitive.NewObjectID().Hex()
+	if _, err := r.collection.InsertOne(ctx, m); err != nil {
+		return err
+	}
+	campaign.ID = m.ID
+	return nil
+}
+
+func (r *MongoCampaignRepository) FindByID(ctx context.Context, id string) (*entity.Campaign, error) {
+	var m model.Campaign
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&m)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, exceptions.ErrCampaignNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toEntityCampaign(&m), nil
+}
+
+func (r *MongoCampaignRepository) FindByOwner(ctx context.Context, ownerID string, archived bool) ([]entity.Campaign, error) {
+	filter := bson.M{"owner_id": ownerID}
+	if !archived {
+		filter["archived_at"] = bson.M{"$exists": false}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var campaigns []entity.Campaign
+	for cursor.Next(ctx) {
+		var m model.Campaign
+		if err := cursor.Decode(&m); err != nil {
+			return nil, err
+		}
+		campaigns = append(campaigns, *toEntityCampaign(&m))
+	}
+	return campaigns, cursor.Err()
+}
+
+func (r *MongoCampaignRepository) SetArchived(ctx context.Context, id string, at time.Time) error {
+	update := bson.M{"$unset": bson.M{"archived_at": ""}}
+	if !at.IsZero() {
+		update = bson.M{"$set": bson.M{"archived_at": at}}
+	}
+	res, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return exceptions.ErrCampaignNotFound
+	}
+	return nil
+}
+
+func fromModelCampaign(campaign *entity.Campaign) *model.Campaign {
+	return &model.Campaign{
+		ID:      campaign.ID,
+		OwnerID: campaign.OwnerID,
+		Name:    campaign.Name,
+		UTM: model.UTM{
+			Source:   campaign.UTM.Source,
+			Medium:   campaign.UTM.Medium,
+			Campaign: campaign.UTM.Campaign,
+			Term:     campaign.UTM.Term,
+			Content:  campaign.UTM.Content,
+		},
+		StartsAt:   optionalTime(campaign.StartsAt),
+		EndsAt:     optionalTime(campaign.EndsAt),
+		CreatedAt:  campaign.CreatedAt,
+		ArchivedAt: optionalTime(campaign.ArchivedAt),
+	}
+}
+
+func toEntityCampaign(m *model.Campaign) *entity.Campaign {
+	return &entity.Campaign{
+		ID:      m.ID,
+		OwnerID: m.OwnerID,
+		Name:    m.Name,
+		UTM: entity.UTM{
+			Source:   m.UTM.Source,
+			Medium:   m.UTM.Medium,
+			Campaign: m.UTM.Campaign,
+			Term:     m.UTM.Term,
+			Content:  m.UTM.Content,
+		},
+		StartsAt:   derefTime(m.StartsAt),
+		EndsAt:     derefTime(m.EndsAt),
+		CreatedAt:  m.CreatedAt,
+		ArchivedAt: derefTime(m.ArchivedAt),
+	}
+}
+
+// optionalTime maps a zero time to nil so that it is left out of documents.
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func derefTime(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
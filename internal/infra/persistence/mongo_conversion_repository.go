@@ -0,0 +1,108 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/exceptions"
+	"url-shortener/internal/infra/persistence/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type MongoConversionRepository struct {
+	collection *mongo.Collection
+	clicks     *mongo.Collection
+}
+
+func NewMongoConversionRepository(db *mongo.Database) *MongoConversionRepository {
+	return &MongoConversionRepository{
+		collection: db.Collection("url_conversions"),
+		clicks:     db.Collection("url_click_ids"),
+	}
+}
+
+func (r *MongoConversionRepository) Save(ctx context.Context, conversion *entity.Conversion) error {
+	m := model.Conversion{
+		ID:          primitive.NewObjectID(),
+		URLID:       conversion.URLID,
+		ClickID:     conversion.ClickID,
+		Value:       conversion.Value,
+		ClickedAt:   conversion.ClickedAt,
+		ConvertedAt: conversion.ConvertedAt,
+	}
+	if _, err := r.collection.InsertOne(ctx, m); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return exceptions.ErrConversionExists
+		}
+		return err
+	}
+	conversion.ID = m.ID.Hex()
+	return nil
+}
+
+func (r *MongoConversionRepository) Totals(ctx context.Context, urlID string) (entity.ConversionTotals, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"url_id": urlID}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":         nil,
+			"conversions": bson.M{"$sum": 1},
+			"value":       bson.M{"$sum": "$value"},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return entity.ConversionTotals{}, err
+	}
+	defer cursor.Close(ctx)
+
+	var totals entity.ConversionTotals
+	if cursor.Next(ctx) {
+		var row struct {
+			Conversions int     `bson:"conversions"`
+			Value       float64 `bson:"value"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return totals, err
+		}
+		totals = entity.ConversionTotals{Conversions: row.Conversions, Value: row.Value}
+	}
+	return totals, cursor.Err()
+}
+
+func (r *MongoConversionRepository) IssueClick(ctx context.Context, click *entity.IssuedClick) error {
+	m := model.IssuedClick{
+		ClickID:   click.ClickID,
+		URLID:     click.URLID,
+		ClickedAt: click.ClickedAt,
+	}
+	if !click.ExpireAt.IsZero() {
+		expireAt := click.ExpireAt
+		m.ExpireAt = &expireAt
+	}
+	_, err := r.clicks.InsertOne(ctx, m)
+	return err
+}
+
+func (r *MongoConversionRepository) FindClick(ctx context.Context, clickID string) (*entity.IssuedClick, error) {
+	var m model.IssuedClick
+	if err := r.clicks.FindOne(ctx, bson.M{"_id": clickID}).Decode(&m); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, exceptions.ErrClickNotFound
+		}
+		return nil, err
+	}
+
+	click := &entity.IssuedClick{
+		ClickID:   m.ClickID,
+		URLID:     m.URLID,
+		ClickedAt: m.ClickedAt,
+	}
+	if m.ExpireAt != nil {
+		click.ExpireAt = *m.ExpireAt
+	}
+	return click, nil
+}
@@ -0,0 +1,336 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/exceptions"
+	"url-shortener/internal/domain/repository"
+	"url-shortener/internal/infra/persistence/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	checkpointID = "checkpoint"
+	leaseID      = "lease"
+)
+
+type MongoRollupRepository struct {
+	rollups *mongo.Collection
+	state   *mongo.Collection
+}
+
+func NewMongoRollupRepository(db *mongo.Database) *MongoRollupRepository {
+	return &MongoRollupRepository{
+		rollups: db.Collection("url_rollups"),
+		state:   db.Collection("rollup_state"),
+	}
+}
+
+func (r *MongoRollupRepository) Upsert(ctx context.Context, rollups []entity.Rollup) error {
+	if len(rollups) == 0 {
+		return nil
+	}
+
+	models := make([]mongo.WriteModel, 0, len(rollups))
+	for i := range rollups {
+		m := fromEntityRollup(&rollups[i])
+		models = append(models, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"_id": m.ID}).
+			SetReplacement(m).
+			SetUpsert(true))
+	}
+
+	_, err := r.rollups.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+func (r *MongoRollupRepository) FindHourly(ctx context.Context, urlIDs []string, from, to time.Time) ([]entity.Rollup, error) {
+	filter := bson.M{
+		"url_id":      bson.M{"$in": urlIDs},
+		"granularity": entity.RollupHour,
+		"start":       bson.M{"$gte": from, "$lt": to},
+	}
+
+	cursor, err := r.rollups.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rollups []entity.Rollup
+	for cursor.Next(ctx) {
+		var m model.Rollup
+		if err := cursor.Decode(&m); err != nil {
+			return nil, err
+		}
+		rollups = append(rollups, *toEntityRollup(&m))
+	}
+
+	return rollups, cursor.Err()
+}
+
+func (r *MongoRollupRepository) ActiveLinks(ctx context.Context, from, to time.Time) ([]string, error) {
+	values, err := r.rollups.Distinct(ctx, "url_id", bson.M{
+		"granularity": entity.RollupHour,
+		"start":       bson.M{"$gte": from, "$lt": to},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(values))
+	for _, v := range values {
+		if id, ok := v.(string); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (r *MongoRollupRepository) Checkpoint(ctx context.Context) (time.Time, error) {
+	var m model.RollupState
+	err := r.state.FindOne(ctx, bson.M{"_id": checkpointID}).Decode(&m)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return time.Time{}, nil
+	}
+	return m.Through, err
+}
+
+func (r *MongoRollupRepository) SetCheckpoint(ctx context.Context, through time.Time) error {
+	_, err := r.state.UpdateOne(ctx,
+		bson.M{"_id": checkpointID},
+		bson.M{"$max": bson.M{"through": through}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// AcquireLease matches the lease only when it is free or already ours. When it
+// is held by someone else the upsert tries to insert a second lease document
+// and fails on the _id, which is how a lost race shows up.
+func (r *MongoRollupRepository) AcquireLease(ctx context.Context, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	_, err := r.state.UpdateOne(ctx,
+		bson.M{
+			"_id": leaseID,
+			"$or": bson.A{
+				bson.M{"owner": owner},
+				bson.M{"expires_at": bson.M{"$lt": now}},
+			},
+		},
+		bson.M{"$set": bson.M{"owner": owner, "expires_at": now.Add(ttl)}},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *MongoRollupRepository) ReleaseLease(ctx context.Context, owner string) error {
+	_, err := r.state.UpdateOne(ctx,
+		bson.M{"_id": leaseID, "owner": owner},
+		bson.M{"$set": bson.M{"expires_at": time.Now()}},
+	)
+	return err
+}
+
+func (r *MongoRollupRepository) TimeSeries(ctx context.Context, query repository.TimeSeriesQuery) ([]entity.ClickBucket, error) {
+	trunc := bson.M{
+		"date":     "$start",
+		"unit":     string(query.Interval),
+		"timezone": query.Location.String(),
+	}
+	if query.Interval == repository.IntervalWeek {
+		trunc["startOfWeek"] = "monday"
+	}
+
+	var clicks any = "$human"
+	if query.IncludeBots {
+		clicks = bson.M{"$add": bson.A{"$human", "$bots", "$previews"}}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"url_id":      bson.M{"$in": query.URLIDs},
+			"granularity": entity.RollupHour,
+			"start":       bson.M{"$gte": query.From, "$lt": query.To},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    bson.M{"$dateTrunc": trunc},
+			"clicks": bson.M{"$sum": clicks},
+		}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+
+	cursor, err := r.rollups.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var buckets []entity.ClickBucket
+	for cursor.Next(ctx) {
+		var row struct {
+			Start  time.Time `bson:"_id"`
+			Clicks int       `bson:"clicks"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, entity.ClickBucket{Start: row.Start, Clicks: row.Clicks})
+	}
+
+	return buckets, cursor.Err()
+}
+
+var rollupArrays = map[repository.Dimension]string{
+	repository.DimensionCountry:     "$countries",
+	repository.DimensionDevice:      "$devices",
+	repository.DimensionRefererHost: "$referrers",
+}
+
+func (r *MongoRollupRepository) Breakdown(ctx context.Context, query repository.BreakdownQuery) ([]entity.BreakdownRow, int, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: rollupMatch(query.URLIDs, query.From, query.To)}},
+	}
+	if array, ok := rollupArrays[query.Dimension]; ok {
+		pipeline = append(pipeline,
+			bson.D{{Key: "$unwind", Value: array}},
+			bson.D{{Key: "$group", Value: bson.M{"_id": array + ".k", "clicks": bson.M{"$sum": array + ".n"}}}},
+		)
+	} else if query.Dimension == repository.DimensionLink {
+		pipeline = append(pipeline,
+			bson.D{{Key: "$group", Value: bson.M{"_id": "$url_id", "clicks": bson.M{"$sum": "$human"}}}},
+		)
+	} else {
+		return nil, 0, exceptions.ErrInvalidStatsQuery
+	}
+
+	cursor, err := r.rollups.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var (
+		rows  []entity.BreakdownRow
+		total int
+	)
+	for cursor.Next(ctx) {
+		var row struct {
+			Key    string `bson:"_id"`
+			Clicks int    `bson:"clicks"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return nil, 0, err
+		}
+		if row.Clicks == 0 {
+			continue
+		}
+		rows = append(rows, entity.BreakdownRow{Key: row.Key, Clicks: row.Clicks})
+		total += row.Clicks
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Clicks != rows[j].Clicks {
+			return rows[i].Clicks > rows[j].Clicks
+		}
+		return rows[i].Key < rows[j].Key
+	})
+	if query.Limit > 0 && len(rows) > query.Limit {
+		rows = rows[:query.Limit]
+	}
+
+	return rows, total, nil
+}
+
+// rollupMatch covers [from, to), which must be hour aligned, reading daily
+// rollups for the whole UTC days inside it and hourly rollups for the rest.
+func rollupMatch(urlIDs []string, from, to time.Time) bson.M {
+	hourly := func(from, to time.Time) bson.M {
+		return bson.M{"granularity": entity.RollupHour, "start": bson.M{"$gte": from, "$lt": to}}
+	}
+
+	match := bson.M{"url_id": bson.M{"$in": urlIDs}}
+
+	firstDay := from.UTC().Truncate(24 * time.Hour)
+	if firstDay.Before(from) {
+		firstDay = firstDay.Add(24 * time.Hour)
+	}
+	lastDay := to.UTC().Truncate(24 * time.Hour)
+	if !firstDay.Before(lastDay) {
+		for k, v := range hourly(from, to) {
+			match[k] = v
+		}
+		return match
+	}
+
+	match["$or"] = bson.A{
+		hourly(from, firstDay),
+		bson.M{"granularity": entity.RollupDay, "start": bson.M{"$gte": firstDay, "$lt": lastDay}},
+		hourly(lastDay, to),
+	}
+	return match
+}
+
+func rollupID(urlID, granularity string, start time.Time) string {
+	return urlID + ":" + granularity + ":" + start.UTC().Format(time.RFC3339)
+}
+
+func toEntityRollup(m *model.Rollup) *entity.Rollup {
+	return &entity.Rollup{
+		URLID:       m.URLID,
+		Granularity: m.Granularity,
+		Start:       m.Start,
+		Human:       m.Human,
+		Bots:        m.Bots,
+		Previews:    m.Previews,
+		Countries:   toRollupMap(m.Countries),
+		Devices:     toRollupMap(m.Devices),
+		Referrers:   toRollupMap(m.Referrers),
+	}
+}
+
+func fromEntityRollup(e *entity.Rollup) *model.Rollup {
+	return &model.Rollup{
+		ID:          rollupID(e.URLID, e.Granularity, e.Start),
+		URLID:       e.URLID,
+		Granularity: e.Granularity,
+		Start:       e.Start.UTC(),
+		Human:       e.Human,
+		Bots:        e.Bots,
+		Previews:    e.Previews,
+		Countries:   fromRollupMap(e.Countries),
+		Devices:     fromRollupMap(e.Devices),
+		Referrers:   fromRollupMap(e.Referrers),
+	}
+}
+
+func toRollupMap(counts []model.RollupCount) map[string]int {
+	m := make(map[string]int, len(counts))
+	for _, c := range counts {
+		m[c.Key] += c.Count
+	}
+	return m
+}
+
+// fromRollupMap sorts by key so that rewriting an unchanged rollup stores an
+// identical document.
+func fromRollupMap(m map[string]int) []model.RollupCount {
+	counts := make([]model.RollupCount, 0, len(m))
+	for k, n := range m {
+		counts = append(counts, model.RollupCount{Key: k, Count: n})
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i].Key < counts[j].Key })
+	return counts
+}
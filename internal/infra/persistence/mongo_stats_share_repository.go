@@ -0,0 +1,108 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/exceptions"
+	"url-shortener/internal/infra/persistence/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoStatsShareRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoStatsShareRepository(db *mongo.Database) *MongoStatsShareRepository {
+	return &MongoStatsShareRepository{
+		collection: db.Collection("stats_shares"),
+	}
+}
+
+func (r *MongoStatsShareRepository) Save(ctx context.Context, share *entity.StatsShare) error {
+	_, err := r.collection.InsertOne(ctx, fromModelStatsShare(share))
+	return err
+}
+
+func (r *MongoStatsShareRepository) FindByID(ctx context.Context, id string) (*entity.StatsShare, error) {
+	var m model.StatsShare
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&m)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, exceptions.ErrShareNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toEntityStatsShare(&m), nil
+}
+
+func (r *MongoStatsShareRepository) FindByURL(ctx context.Context, urlID string) ([]entity.StatsShare, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"url_id": urlID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var shares []entity.StatsShare
+	for cursor.Next(ctx) {
+		var m model.StatsShare
+		if err := cursor.Decode(&m); err != nil {
+			return nil, err
+		}
+		shares = append(shares, *toEntityStatsShare(&m))
+	}
+	return shares, cursor.Err()
+}
+
+func (r *MongoStatsShareRepository) Revoke(ctx context.Context, urlID, id string, at time.Time) error {
+	res, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "url_id": urlID},
+		bson.M{"$min": bson.M{"revoked_at": at}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return exceptions.ErrShareNotFound
+	}
+	return nil
+}
+
+func fromModelStatsShare(share *entity.StatsShare) *model.StatsShare {
+	m := &model.StatsShare{
+		ID:        share.ID,
+		URLID:     share.URLID,
+		OwnerID:   share.OwnerID,
+		CreatedAt: share.CreatedAt,
+	}
+	if !share.ExpiresAt.IsZero() {
+		expiresAt := share.ExpiresAt
+		m.ExpiresAt = &expiresAt
+	}
+	if !share.RevokedAt.IsZero() {
+		revokedAt := share.RevokedAt
+		m.RevokedAt = &revokedAt
+	}
+	return m
+}
+
+func toEntityStatsShare(m *model.StatsShare) *entity.StatsShare {
+	share := &entity.StatsShare{
+		ID:        m.ID,
+		URLID:     m.URLID,
+		OwnerID:   m.OwnerID,
+		CreatedAt: m.CreatedAt,
+	}
+	if m.ExpiresAt != nil {
+		share.ExpiresAt = *m.ExpiresAt
+	}
+	if m.RevokedAt != nil {
+		share.RevokedAt = *m.RevokedAt
+	}
+	return share
+}
@@ -0,0 +1,97 @@
+package persistence
+
+import (
+	"context"
+	"strconv"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/infra/persistence/model"
+	"url-shortener/pkg/hll"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoUniqueVisitorRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoUniqueVisitorRepository(db *mongo.Database) *MongoUniqueVisitorRepository {
+	return &MongoUniqueVisitorRepository{
+		collection: db.Collection("url_uniques"),
+	}
+}
+
+// Observe folds register updates into the daily sketches with one upsert per
+// link and day. Updates only ever raise registers, so replaying them is
+// harmless.
+func (r *MongoUniqueVisitorRepository) Observe(ctx context.Context, observations []entity.VisitorObservation) error {
+	type key struct {
+		urlID string
+		day   time.Time
+	}
+	grouped := make(map[key]bson.M)
+	for _, o := range observations {
+		k := key{urlID: o.URLID, day: o.Day.UTC().Truncate(24 * time.Hour)}
+		if grouped[k] == nil {
+			grouped[k] = bson.M{}
+		}
+		field := "registers." + strconv.Itoa(int(o.Register))
+		if cur, ok := grouped[k][field].(uint8); !ok || o.Rank > cur {
+			grouped[k][field] = o.Rank
+		}
+	}
+	if len(grouped) == 0 {
+		return nil
+	}
+
+	models := make([]mongo.WriteModel, 0, len(grouped))
+	for k, registers := range grouped {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": k.urlID + ":" + k.day.Format("2006-01-02")}).
+			SetUpdate(bson.M{
+				"$setOnInsert": bson.M{"url_id": k.urlID, "day": k.day},
+				"$max":         registers,
+			}).
+			SetUpsert(true))
+	}
+
+	_, err := r.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+func (r *MongoUniqueVisitorRepository) Sketch(ctx context.Context, urlIDs []string, from, to time.Time) (*hll.Sketch, error) {
+	filter := bson.M{"url_id": bson.M{"$in": urlIDs}}
+	day := bson.M{}
+	if !from.IsZero() {
+		day["$gte"] = from.UTC().Truncate(24 * time.Hour)
+	}
+	if !to.IsZero() {
+		day["$lt"] = to
+	}
+	if len(day) > 0 {
+		filter["day"] = day
+	}
+
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	sketch := hll.New()
+	for cursor.Next(ctx) {
+		var m model.UniqueVisitors
+		if err := cursor.Decode(&m); err != nil {
+			return nil, err
+		}
+		for idx, rank := range m.Registers {
+			if n, err := strconv.Atoi(idx); err == nil {
+				sketch.Set(uint16(n), rank)
+			}
+		}
+	}
+
+	return sketch, cursor.Err()
+}
@@ -2,12 +2,16 @@ package persistence
 
 import (
 	"context"
+	"errors"
 	"time"
 	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/exceptions"
+	"url-shortener/internal/domain/repository"
 	"url-shortener/internal/infra/persistence/model"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type MongoURLRepository struct {
@@ -20,27 +24,191 @@ func NewMongoURLRepository(db *mongo.Database) *MongoURLRepository {
 	}
 }
 
-func (r *MongoURLRepository) Save(url *entity.URL) error {
-	_, err := r.collection.InsertOne(context.TODO(), fromModelUrl(url))
+func (r *MongoURLRepository) Save(ctx context.Context, url *entity.URL) error {
+	_, err := r.collection.InsertOne(ctx, fromModelUrl(url))
 	return err
 }
 
-func (r *MongoURLRepository) FindByID(id string) (*entity.URL, error) {
+func (r *MongoURLRepository) FindByID(ctx context.Context, id string) (*entity.URL, error) {
 	var url model.URL
-	err := r.collection.FindOne(context.TODO(), map[string]string{"_id": id}).Decode(&url)
+	err := r.collection.FindOne(ctx, map[string]string{"_id": id}).Decode(&url)
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, exceptions.ErrURLNotFound
+		}
 		return nil, err
 	}
 	return toEntityUrl(&url), nil
 }
 
-func (r *MongoURLRepository) IncrementClick(id string) error {
+func (r *MongoURLRepository) FindTop(ctx context.Context, limit int) ([]entity.URL, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "click_count", Value: -1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var urls []entity.URL
+	for cursor.Next(ctx) {
+		var m model.URL
+		if err := cursor.Decode(&m); err != nil {
+			return nil, err
+		}
+		urls = append(urls, *toEntityUrl(&m))
+	}
+
+	return urls, cursor.Err()
+}
+
+func (r *MongoURLRepository) FindByOwner(ctx context.Context, ownerID string) ([]entity.URL, error) {
+	return r.findAll(ctx, bson.M{"owner_id": ownerID})
+}
+
+func (r *MongoURLRepository) FindByCampaign(ctx context.Context, campaignID string) ([]entity.URL, error) {
+	return r.findAll(ctx, bson.M{"campaign_id": campaignID})
+}
+
+func (r *MongoURLRepository) List(ctx context.Context, filter repository.URLFilter) ([]entity.URL, error) {
+	query := bson.M{"owner_id": filter.OwnerID}
+	if filter.Tag != "" {
+		query["tags"] = filter.Tag
+	}
+	if filter.Folder != "" {
+		query["folder"] = filter.Folder
+	}
+	return r.findAll(ctx, query)
+}
+
+// findAll returns the links matching filter, oldest first.
+func (r *MongoURLRepository) findAll(ctx context.Context, filter bson.M) ([]entity.URL, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var urls []entity.URL
+	for cursor.Next(ctx) {
+		var m model.URL
+		if err := cursor.Decode(&m); err != nil {
+			return nil, err
+		}
+		urls = append(urls, *toEntityUrl(&m))
+	}
+
+	return urls, cursor.Err()
+}
+
+func (r *MongoURLRepository) SetMetadata(ctx context.Context, id string, metadata entity.LinkMetadata) error {
+	return r.updateOne(ctx, id, bson.M{
+		"$set": bson.M{"metadata": fromEntityMetadata(metadata)},
+	})
+}
+
+func (r *MongoURLRepository) SetCampaign(ctx context.Context, id, campaignID, originalURL string) error {
+	update := bson.M{"$set": bson.M{"original_url": originalURL, "campaign_id": campaignID}}
+	if campaignID == "" {
+		update = bson.M{
+			"$set":   bson.M{"original_url": originalURL},
+			"$unset": bson.M{"campaign_id": ""},
+		}
+	}
+	return r.updateOne(ctx, id, update)
+}
+
+func (r *MongoURLRepository) SetLabels(ctx context.Context, id string, tags []string, folder string) error {
+	return r.updateOne(ctx, id, bson.M{
+		"$set": bson.M{"tags": tags, "folder": folder},
+	})
+}
+
+func (r *MongoURLRepository) SetMetadataOverride(ctx context.Context, id string, override entity.LinkMetadata) error {
+	update := bson.M{"$set": bson.M{"metadata_override": fromEntityMetadata(override)}}
+	if override == (entity.LinkMetadata{}) {
+		update = bson.M{"$unset": bson.M{"metadata_override": ""}}
+	}
+	return r.updateOne(ctx, id, update)
+}
+
+func (r *MongoURLRepository) SetConversions(ctx context.Context, id, clickIDParam string, window time.Duration, postbackSecret string) error {
+	return r.updateOne(ctx, id, bson.M{
+		"$set": bson.M{
+			"click_id_param":     clickIDParam,
+			"attribution_window": window,
+			"postback_secret":    postbackSecret,
+		},
+	})
+}
+
+func (r *MongoURLRepository) updateOne(ctx context.Context, id string, update bson.M) error {
+	res, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return exceptions.ErrURLNotFound
+	}
+	return nil
+}
+
+func (r *MongoURLRepository) IncrementClick(ctx context.Context, id string) error {
 	filter := bson.M{"_id": id}
 	update := bson.M{
 		"$inc": bson.M{"click_count": 1},
 		"$set": bson.M{"last_click": time.Now()},
 	}
-	_, err := r.collection.UpdateOne(context.Background(), filter, update)
+	_, err := r.collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (r *MongoURLRepository) IncrementClicks(ctx context.Context, increments []repository.ClickIncrement) error {
+	if len(increments) == 0 {
+		return nil
+	}
+
+	models := make([]mongo.WriteModel, 0, len(increments))
+	for _, inc := range increments {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": inc.URLID}).
+			SetUpdate(bson.M{
+				"$inc": bson.M{"click_count": inc.Count, "bot_click_count": inc.Bots, "tracked_click_count": inc.Tracked},
+				"$max": bson.M{"last_click": inc.LastClick},
+			}))
+	}
+
+	_, err := r.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+func (r *MongoURLRepository) ClickCounts(ctx context.Context) (map[string]int, error) {
+	opts := options.Find().SetProjection(bson.M{"_id": 1, "click_count": 1})
+
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	counts := make(map[string]int)
+	for cursor.Next(ctx) {
+		var m model.URL
+		if err := cursor.Decode(&m); err != nil {
+			return nil, err
+		}
+		counts[m.ID] = m.ClickCount
+	}
+
+	return counts, cursor.Err()
+}
+
+func (r *MongoURLRepository) AdjustClickCount(ctx context.Context, id string, delta int) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"click_count": delta}})
 	return err
 }
 
@@ -50,6 +218,9 @@ func fromModelUrl(url *entity.URL) *model.URL {
 		OriginalURL: url.OriginalURL,
 		OwnerID:     url.OwnerID,
 		CreatedAt:   time.Now(),
+		CampaignID:  url.CampaignID,
+		Tags:        url.Tags,
+		Folder:      url.Folder,
 	}
 }
 
@@ -61,5 +232,50 @@ func toEntityUrl(url *model.URL) *entity.URL {
 		CreatedAt:   url.CreatedAt,
 		ClickCount:  url.ClickCount,
 		LastClick:   url.LastClick,
+
+		BotClickCount: url.BotClickCount,
+
+		ClickIDParam:      url.ClickIDParam,
+		AttributionWindow: url.AttributionWindow,
+		TrackedClickCount: url.TrackedClickCount,
+		PostbackSecret:    url.PostbackSecret,
+
+		CampaignID: url.CampaignID,
+
+		Tags:   url.Tags,
+		Folder: url.Folder,
+
+		Metadata:         toEntityMetadata(url.Metadata),
+		MetadataOverride: toEntityMetadata(url.MetadataOverride),
+	}
+}
+
+// fromEntityMetadata returns nil for empty metadata, which is left out of
+// the document.
+func fromEntityMetadata(m entity.LinkMetadata) *model.LinkMetadata {
+	if m == (entity.LinkMetadata{}) {
+		return nil
+	}
+	return &model.LinkMetadata{
+		Title:       m.Title,
+		OGTitle:     m.OGTitle,
+		Description: m.Description,
+		Image:       m.Image,
+		Favicon:     m.Favicon,
+		FetchedAt:   m.FetchedAt,
+	}
+}
+
+func toEntityMetadata(m *model.LinkMetadata) entity.LinkMetadata {
+	if m == nil {
+		return entity.LinkMetadata{}
+	}
+	return entity.LinkMetadata{
+		Title:       m.Title,
+		OGTitle:     m.OGTitle,
+		Description: m.Description,
+		Image:       m.Image,
+		Favicon:     m.Favicon,
+		FetchedAt:   m.FetchedAt,
 	}
 }
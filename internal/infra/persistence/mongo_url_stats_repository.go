@@ -2,12 +2,19 @@ package persistence
 
 import (
 	"context"
+	"errors"
+	"regexp"
+	"time"
 	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/exceptions"
 	"url-shortener/internal/domain/repository"
 	"url-shortener/internal/infra/persistence/model"
+	"url-shortener/pkg/botdetect"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type MongoURLStatsRepository struct {
@@ -20,14 +27,44 @@ func NewMongoURLStatsRepository(db *mongo.Database) repository.URLStatsRepositor
 	}
 }
 
-func (r *MongoURLStatsRepository) FindByURLID(urlID string) ([]entity.URLStat, error) {
-	ctx := context.TODO()
+// FindPage returns up to query.Limit clicks ordered by _id descending, which
+// follows insertion order, and the cursor for the next page or "" on the last
+// one.
+func (r *MongoURLStatsRepository) FindPage(ctx context.Context, query repository.ClickQuery) ([]entity.URLStat, string, error) {
+	filter := clickFilter(query.URLID, query.From, query.To)
+	if query.Cursor != "" {
+		after, err := primitive.ObjectIDFromHex(query.Cursor)
+		if err != nil {
+			return nil, "", exceptions.ErrInvalidStatsQuery
+		}
+		filter["_id"] = bson.M{"$lt": after}
+	}
+	if query.RefererHost != "" {
+		// Clicks recorded before referrers were normalized only have the raw
+		// header, so fall back to matching its host.
+		pattern := `^[a-zA-Z][a-zA-Z0-9+.-]*://(www\.)?` + regexp.QuoteMeta(query.RefererHost) + `([:/?#]|$)`
+		filter["$or"] = bson.A{
+			bson.M{"referer_host": query.RefererHost},
+			bson.M{
+				"referer_host": bson.M{"$exists": false},
+				"referer":      primitive.Regex{Pattern: pattern, Options: "i"},
+			},
+		}
+	}
+	if query.UserAgent != "" {
+		filter["user_agent"] = primitive.Regex{Pattern: regexp.QuoteMeta(query.UserAgent), Options: "i"}
+	}
+	if query.Class != "" {
+		filter["class"] = query.Class
+	}
 
-	filter := map[string]interface{}{"url_id": urlID}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetLimit(int64(query.Limit) + 1)
 
-	cursor, err := r.collection.Find(ctx, filter)
+	cursor, err := r.collection.Find(ctx, filter, opts)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer cursor.Close(ctx)
 
@@ -35,40 +72,391 @@ func (r *MongoURLStatsRepository) FindByURLID(urlID string) ([]entity.URLStat, e
 	for cursor.Next(ctx) {
 		var m model.URLStat
 		if err := cursor.Decode(&m); err != nil {
+			return nil, "", err
+		}
+		stats = append(stats, *toEntityUrlStat(&m))
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, "", err
+	}
+
+	if len(stats) <= query.Limit {
+		return stats, "", nil
+	}
+	stats = stats[:query.Limit]
+	return stats, stats[len(stats)-1].ID, nil
+}
+
+func (r *MongoURLStatsRepository) Save(ctx context.Context, stat *entity.URLStat) error {
+	urlStat := fromModelUrlStats(stat)
+
+	_, err := r.collection.InsertOne(ctx, urlStat)
+	return err
+}
+
+func (r *MongoURLStatsRepository) SaveMany(ctx context.Context, stats []*entity.URLStat) error {
+	if len(stats) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, 0, len(stats))
+	for _, stat := range stats {
+		docs = append(docs, fromModelUrlStats(stat))
+	}
+
+	_, err := r.collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	return err
+}
+
+func (r *MongoURLStatsRepository) Stream(ctx context.Context, query repository.ExportQuery, fn func(*entity.URLStat) error) error {
+	opts := options.Find().SetBatchSize(1000)
+
+	filter := clickFilter(query.URLID, query.From, query.To)
+	if query.URLID == "" {
+		// Across links the clicked_at index drives the scan and order is
+		// left to the server.
+		delete(filter, "url_id")
+	} else {
+		opts.SetSort(bson.D{{Key: "_id", Value: 1}})
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var m model.URLStat
+		if err := cursor.Decode(&m); err != nil {
+			return err
+		}
+		if err := fn(toEntityUrlStat(&m)); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+func (r *MongoURLStatsRepository) CountByURL(ctx context.Context) (map[string]int, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"class": notAutomated}}},
+		{{Key: "$group", Value: bson.M{"_id": "$url_id", "count": bson.M{"$sum": 1}}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	counts := make(map[string]int)
+	for cursor.Next(ctx) {
+		var row struct {
+			URLID string `bson:"_id"`
+			Count int    `bson:"count"`
+		}
+		if err := cursor.Decode(&row); err != nil {
 			return nil, err
 		}
+		counts[row.URLID] = row.Count
+	}
+
+	return counts, cursor.Err()
+}
+
+func (r *MongoURLStatsRepository) FindByClickID(ctx context.Context, clickID string) (*entity.URLStat, error) {
+	var m model.URLStat
+	err := r.collection.FindOne(ctx, bson.M{"click_id": clickID}).Decode(&m)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, exceptions.ErrClickNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toEntityUrlStat(&m), nil
+}
+
+func (r *MongoURLStatsRepository) OldestClick(ctx context.Context) (time.Time, error) {
+	opts := options.FindOne().
+		SetSort(bson.D{{Key: "clicked_at", Value: 1}}).
+		SetProjection(bson.M{"clicked_at": 1})
+
+	var m model.URLStat
+	err := r.collection.FindOne(ctx, bson.M{}, opts).Decode(&m)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return time.Time{}, nil
+	}
+	return m.ClickedAt, err
+}
 
-		stats = append(stats, entity.URLStat{
-			ID:        m.ID.Hex(),
-			URLID:     m.URLID,
-			ClickedAt: m.ClickedAt,
-			IP:        m.IP,
-			UserAgent: m.UserAgent,
-			Referer:   m.Referer,
-		})
+func (r *MongoURLStatsRepository) DeleteBefore(ctx context.Context, query repository.PurgeQuery) (int, error) {
+	filter := bson.M{"clicked_at": bson.M{"$lt": query.Before}}
+	switch {
+	case query.URLIDs != nil:
+		filter["url_id"] = bson.M{"$in": query.URLIDs}
+	case len(query.ExcludeURLIDs) > 0:
+		filter["url_id"] = bson.M{"$nin": query.ExcludeURLIDs}
+	}
+
+	res, err := r.collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return int(res.DeletedCount), nil
+}
+
+func (r *MongoURLStatsRepository) TimeSeries(ctx context.Context, query repository.TimeSeriesQuery) ([]entity.ClickBucket, error) {
+	trunc := bson.M{
+		"date":     "$clicked_at",
+		"unit":     string(query.Interval),
+		"timezone": query.Location.String(),
+	}
+	if query.Interval == repository.IntervalWeek {
+		trunc["startOfWeek"] = "monday"
 	}
 
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: statsMatch(query.URLIDs, query.From, query.To, query.IncludeBots)}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    bson.M{"$dateTrunc": trunc},
+			"clicks": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var buckets []entity.ClickBucket
+	for cursor.Next(ctx) {
+		var row struct {
+			Start  time.Time `bson:"_id"`
+			Clicks int       `bson:"clicks"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, entity.ClickBucket{Start: row.Start, Clicks: row.Clicks})
+	}
+
+	return buckets, cursor.Err()
+}
+
+var dimensionFields = map[repository.Dimension]any{
+	repository.DimensionBrowser:       bson.M{"$ifNull": bson.A{"$browser", ""}},
+	repository.DimensionOS:            bson.M{"$ifNull": bson.A{"$os", ""}},
+	repository.DimensionDevice:        bson.M{"$ifNull": bson.A{"$device", ""}},
+	repository.DimensionRefererHost:   bson.M{"$ifNull": bson.A{"$referer_host", ""}},
+	repository.DimensionRefererSource: bson.M{"$ifNull": bson.A{"$referer_source", ""}},
+	repository.DimensionLink:          "$url_id",
+	repository.DimensionCountry:       bson.M{"$ifNull": bson.A{"$country", ""}},
+	repository.DimensionRegion:        bson.M{"$ifNull": bson.A{"$region", ""}},
+	repository.DimensionCity: bson.M{"$cond": bson.A{
+		bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$city", ""}}, ""}},
+		"",
+		bson.M{"$concat": bson.A{"$city", ", ", bson.M{"$ifNull": bson.A{"$country", ""}}}},
+	}},
+	repository.DimensionPlatform: bson.M{"$trim": bson.M{"input": bson.M{"$concat": bson.A{
+		bson.M{"$ifNull": bson.A{"$os", ""}}, " ", bson.M{"$ifNull": bson.A{"$browser", ""}},
+	}}}},
+}
+
+func (r *MongoURLStatsRepository) Breakdown(ctx context.Context, query repository.BreakdownQuery) ([]entity.BreakdownRow, int, error) {
+	field, ok := dimensionFields[query.Dimension]
+	if !ok {
+		return nil, 0, exceptions.ErrInvalidStatsQuery
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: statsMatch(query.URLIDs, query.From, query.To, query.IncludeBots)}},
+		{{Key: "$group", Value: bson.M{"_id": field, "clicks": bson.M{"$sum": 1}}}},
+		{{Key: "$facet", Value: bson.M{
+			"rows": bson.A{
+				bson.M{"$sort": bson.D{{Key: "clicks", Value: -1}, {Key: "_id", Value: 1}}},
+				bson.M{"$limit": query.Limit},
+			},
+			"total": bson.A{
+				bson.M{"$group": bson.M{"_id": nil, "clicks": bson.M{"$sum": "$clicks"}}},
+			},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var result struct {
+		Rows []struct {
+			Key    string `bson:"_id"`
+			Clicks int    `bson:"clicks"`
+		} `bson:"rows"`
+		Total []struct {
+			Clicks int `bson:"clicks"`
+		} `bson:"total"`
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&result); err != nil {
+			return nil, 0, err
+		}
+	}
 	if err := cursor.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	rows := make([]entity.BreakdownRow, 0, len(result.Rows))
+	for _, row := range result.Rows {
+		rows = append(rows, entity.BreakdownRow{Key: row.Key, Clicks: row.Clicks})
+	}
+	total := 0
+	if len(result.Total) > 0 {
+		total = result.Total[0].Clicks
+	}
+
+	return rows, total, nil
+}
+
+func (r *MongoURLStatsRepository) Locations(ctx context.Context, query repository.GeoQuery) ([]entity.GeoPoint, error) {
+	match := statsMatch(query.URLIDs, query.From, query.To, query.IncludeBots)
+	match["lat"] = bson.M{"$exists": true}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    bson.M{"lat": "$lat", "lon": "$lon"},
+			"clicks": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "clicks", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: query.Limit}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
 		return nil, err
 	}
+	defer cursor.Close(ctx)
+
+	var points []entity.GeoPoint
+	for cursor.Next(ctx) {
+		var row struct {
+			ID struct {
+				Lat float64 `bson:"lat"`
+				Lon float64 `bson:"lon"`
+			} `bson:"_id"`
+			Clicks int `bson:"clicks"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return nil, err
+		}
+		points = append(points, entity.GeoPoint{Latitude: row.ID.Lat, Longitude: row.ID.Lon, Clicks: row.Clicks})
+	}
 
-	return stats, nil
+	return points, cursor.Err()
 }
 
-func (r *MongoURLStatsRepository) Save(stat *entity.URLStat) error {
-	urlStat := fromModelUrlStats(stat)
+// notAutomated matches human clicks, including those recorded before clicks
+// were classified.
+var notAutomated = bson.M{"$nin": bson.A{botdetect.ClassBot, botdetect.ClassPreview}}
 
-	_, err := r.collection.InsertOne(context.TODO(), urlStat)
-	return err
+func statsMatch(urlIDs []string, from, to time.Time, includeBots bool) bson.M {
+	match := clickFilter("", from, to)
+	match["url_id"] = bson.M{"$in": urlIDs}
+	if !includeBots {
+		match["class"] = notAutomated
+	}
+	return match
+}
+
+func clickFilter(urlID string, from, to time.Time) bson.M {
+	filter := bson.M{"url_id": urlID}
+
+	clickedAt := bson.M{}
+	if !from.IsZero() {
+		clickedAt["$gte"] = from
+	}
+	if !to.IsZero() {
+		clickedAt["$lt"] = to
+	}
+	if len(clickedAt) > 0 {
+		filter["clicked_at"] = clickedAt
+	}
+
+	return filter
+}
+
+func toEntityUrlStat(m *model.URLStat) *entity.URLStat {
+	stat := &entity.URLStat{
+		ID:        m.ID.Hex(),
+		URLID:     m.URLID,
+		ClickedAt: m.ClickedAt,
+		IP:        m.IP,
+		UserAgent: m.UserAgent,
+		Referer:   m.Referer,
+
+		RefererHost:   m.RefererHost,
+		RefererSource: m.RefererSource,
+
+		Browser:        m.Browser,
+		BrowserVersion: m.BrowserVersion,
+		OS:             m.OS,
+		OSVersion:      m.OSVersion,
+		Device:         m.Device,
+
+		Country:   m.Country,
+		Region:    m.Region,
+		City:      m.City,
+		Latitude:  m.Latitude,
+		Longitude: m.Longitude,
+
+		Class:  m.Class,
+		OptOut: m.OptOut,
+
+		ClickID: m.ClickID,
+	}
+	if m.ExpireAt != nil {
+		stat.ExpireAt = *m.ExpireAt
+	}
+	return stat
 }
 
 func fromModelUrlStats(url *entity.URLStat) *model.URLStat {
-	return &model.URLStat{
+	m := &model.URLStat{
 		ID:        primitive.NewObjectID(),
 		URLID:     url.URLID,
 		ClickedAt: url.ClickedAt,
 		IP:        url.IP,
 		UserAgent: url.UserAgent,
 		Referer:   url.Referer,
+
+		RefererHost:   url.RefererHost,
+		RefererSource: url.RefererSource,
+
+		Browser:        url.Browser,
+		BrowserVersion: url.BrowserVersion,
+		OS:             url.OS,
+		OSVersion:      url.OSVersion,
+		Device:         url.Device,
+
+		Country:   url.Country,
+		Region:    url.Region,
+		City:      url.City,
+		Latitude:  url.Latitude,
+		Longitude: url.Longitude,
+
+		Class:  url.Class,
+		OptOut: url.OptOut,
+
+		ClickID: url.ClickID,
+	}
+	if !url.ExpireAt.IsZero() {
+		expireAt := url.ExpireAt
+		m.ExpireAt = &expireAt
 	}
+	return m
 }
@@ -2,11 +2,12 @@ package persistence
 
 import (
 	"context"
-	"log"
 	"time"
 	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/exceptions"
 	"url-shortener/internal/infra/persistence/model"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
@@ -21,44 +22,65 @@ func NewMongoUserRepository(db *mongo.Database) *MongoUserRepository {
 	}
 }
 
-func (r *MongoUserRepository) Save(user *entity.User) (*entity.User, error) {
-	result, err := r.collection.InsertOne(context.TODO(), fromModelUser(user))
+func (r *MongoUserRepository) Save(ctx context.Context, user *entity.User) (*entity.User, error) {
+	result, err := r.collection.InsertOne(ctx, fromModelUser(user))
 	if err != nil {
-		log.Fatal(err)
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, exceptions.ErrEmailAlreadyExists
+		}
 		return nil, err
 	}
 
-	savedUser, err := r.FindByID(result.InsertedID.(primitive.ObjectID).Hex())
+	savedUser, err := r.FindByID(ctx, result.InsertedID.(primitive.ObjectID).Hex())
 	if err != nil {
-		log.Fatal(err)
 		return nil, err
 	}
 	return savedUser, nil
 }
 
-func (r *MongoUserRepository) FindByID(id string) (*entity.User, error) {
+func (r *MongoUserRepository) FindByID(ctx context.Context, id string) (*entity.User, error) {
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, err
 	}
 
 	var user model.User
-	err = r.collection.FindOne(context.TODO(), map[string]any{"_id": objID}).Decode(&user)
+	err = r.collection.FindOne(ctx, map[string]any{"_id": objID}).Decode(&user)
 	if err != nil {
 		return nil, err
 	}
 	return toEntityUser(&user), nil
 }
 
-func (r *MongoUserRepository) FindByEmail(email string) (*entity.User, error) {
+func (r *MongoUserRepository) FindByEmail(ctx context.Context, email string) (*entity.User, error) {
 	var user model.User
-	err := r.collection.FindOne(context.TODO(), map[string]string{"email": email}).Decode(&user)
+	err := r.collection.FindOne(ctx, map[string]string{"email": email}).Decode(&user)
 	if err != nil {
 		return nil, err
 	}
 	return toEntityUser(&user), nil
 }
 
+func (r *MongoUserRepository) SetWebhook(ctx context.Context, id, url, secret string) error {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return exceptions.ErrUserNotFound
+	}
+
+	update := bson.M{"$set": bson.M{"webhook_url": url, "webhook_secret": secret}}
+	if url == "" {
+		update = bson.M{"$unset": bson.M{"webhook_url": "", "webhook_secret": ""}}
+	}
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": objID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return exceptions.ErrUserNotFound
+	}
+	return nil
+}
+
 func fromModelUser(user *entity.User) *model.User {
 	return &model.User{
 		ID:             primitive.NewObjectID(),
@@ -76,5 +98,7 @@ func toEntityUser(user *model.User) *entity.User {
 		Email:          user.Email,
 		HashedPassword: user.HashedPassword,
 		CreatedAt:      user.CreatedAt,
+		WebhookURL:     user.WebhookURL,
+		WebhookSecret:  user.WebhookSecret,
 	}
 }
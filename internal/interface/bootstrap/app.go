@@ -0,0 +1,44 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// App is the wired HTTP handler together with the background components that
+// have to be drained when the server stops.
+type App struct {
+	Handler http.Handler
+	// AdminHandler serves operational endpoints and must only be bound to a
+	// private address.
+	AdminHandler http.Handler
+	closers      []func(ctx context.Context) error
+	streams      []func()
+}
+
+func (a *App) onShutdown(fn func(ctx context.Context) error) {
+	a.closers = append(a.closers, fn)
+}
+
+func (a *App) onStreamsClose(fn func()) {
+	a.streams = append(a.streams, fn)
+}
+
+// CloseStreams ends long-lived streaming responses. Register it with
+// http.Server.RegisterOnShutdown, since Shutdown otherwise waits for them.
+func (a *App) CloseStreams() {
+	for _, fn := range a.streams {
+		fn()
+	}
+}
+
+// Shutdown stops background components in reverse start order. It should be
+// called after the HTTP server has stopped accepting requests.
+func (a *App) Shutdown(ctx context.Context) error {
+	var errs []error
+	for i := len(a.closers) - 1; i >= 0; i-- {
+		errs = append(errs, a.closers[i](ctx))
+	}
+	return errors.Join(errs...)
+}
@@ -1,51 +1,275 @@
 package bootstrap
 
 import (
+	"context"
+	"expvar"
+	"fmt"
+	"log"
 	"net/http"
+	"os"
 	"time"
 
 	"url-shortener/internal/config"
+	"url-shortener/internal/domain/repository"
+	"url-shortener/internal/infra/cache"
+	"url-shortener/internal/infra/notify"
 	"url-shortener/internal/infra/persistence"
 	"url-shortener/internal/infra/security"
 	"url-shortener/internal/interface/handler"
 	"url-shortener/internal/interface/middleware"
 	"url-shortener/internal/services"
 	"url-shortener/pkg"
+	"url-shortener/pkg/geoip"
+	"url-shortener/pkg/linkmeta"
 
 	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/redis/go-redis/v9"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func NewRouter(db *mongo.Database, cfg *config.Config) http.Handler {
-	urlRepo := persistence.NewMongoURLRepository(db)
+func NewApp(db *mongo.Database, rdb *redis.Client, cfg *config.Config) *App {
+	app := &App{}
+
+	// The Redis cache only serves redirects: counters on a cached link are as
+	// stale as its TTL, so stats, ownership checks and the click pipeline
+	// read the database directly.
+	var urlRepo repository.URLRepository = persistence.NewMongoURLRepository(db)
+	redirectRepo := urlRepo
+	if rdb != nil {
+		redirectRepo = cache.NewRedisURLRepository(urlRepo, rdb, cfg.CacheTTL, cfg.CacheNegativeTTL)
+	}
 	userRepo := persistence.NewMongoUserRepository(db)
 	statsRepo := persistence.NewMongoURLStatsRepository(db)
+	visitorRepo := persistence.NewMongoUniqueVisitorRepository(db)
 
 	idGen := &pkg.ShortIDGenerator{}
 	hasher := &pkg.PassowrdHasher{}
 	tokenGen := security.NewJWTService(cfg.SecretKey)
 
-	urlService := services.NewURLService(urlRepo, idGen, statsRepo)
+	clickCfg := services.ClickPipelineConfig{
+		QueueSize:      cfg.ClickQueueSize,
+		Workers:        cfg.ClickWorkers,
+		BatchSize:      cfg.ClickBatchSize,
+		FlushInterval:  cfg.ClickFlushInterval,
+		EnqueueTimeout: cfg.ClickEnqueueTimeout,
+		Visitors:       visitorRepo,
+	}
+	if cfg.ClickTransactions {
+		clickCfg.Transactor = persistence.NewMongoTransactor(db.Client())
+	}
+	clicks := services.NewClickPipeline(urlRepo, statsRepo, clickCfg)
+	expvar.Publish("click_pipeline", expvar.Func(func() any { return clicks.Stats() }))
+	app.onShutdown(clicks.Close)
+
+	// Raw clicks must outlive the rollup lag, or the TTL index would delete
+	// hours the rollup job has not folded yet.
+	retention := services.RetentionPolicy{
+		Default:  cfg.ClickRetention,
+		Accounts: cfg.ClickRetentionAccounts,
+	}
+	if cfg.RollupEnabled {
+		retention.MinAge = services.MinRetention(cfg.RollupLag)
+	}
+
+	urlOpts := []services.URLServiceOption{
+		services.WithRedirectRepository(redirectRepo),
+		services.WithClickRecorder(clicks),
+		services.WithUniqueVisitors(visitorRepo, []byte(cfg.VisitorKey)),
+		services.WithInternalHosts(cfg.PublicHosts...),
+		services.WithConversions(persistence.NewMongoConversionRepository(db), cfg.ConversionWindow),
+		services.WithCampaigns(persistence.NewMongoCampaignRepository(db)),
+		services.WithRetention(retention),
+		services.WithPrivacy(services.PrivacyPolicy{
+			IP:            services.IPMode(cfg.PrivacyIP),
+			DropUserAgent: cfg.PrivacyDropUserAgent,
+			HonorOptOut:   cfg.PrivacyHonorOptOut,
+		}),
+	}
+	live := services.NewClickBroker(services.ClickBrokerConfig{
+		MaxSubscribers: cfg.LiveMaxSubscribers,
+		History:        cfg.LiveHistory,
+	})
+	expvar.Publish("live_subscribers", expvar.Func(func() any { return live.Subscribers() }))
+	app.onStreamsClose(live.Close)
+	urlOpts = append(urlOpts, services.WithLiveClicks(live))
+
+	if geo := openGeoIP(cfg.GeoIPDatabase); geo != nil {
+		app.onShutdown(func(context.Context) error { return geo.Close() })
+		urlOpts = append(urlOpts, services.WithGeoLocator(geo))
+	}
+	if cfg.MetadataEnabled {
+		fetcher := linkmeta.NewFetcher(cfg.MetadataTimeout, linkmeta.WithMaxBytes(int64(cfg.MetadataMaxBytes)))
+		metadata := services.NewMetadataWorker(redirectRepo, fetcher, services.MetadataWorkerConfig{
+			QueueSize: cfg.MetadataQueue,
+			Workers:   cfg.MetadataWorkers,
+			// Leave room for the write after a fetch that used its whole timeout.
+			Timeout: cfg.MetadataTimeout + 5*time.Second,
+		})
+		expvar.Publish("metadata_worker", expvar.Func(func() any { return metadata.Stats() }))
+		app.onShutdown(metadata.Close)
+		urlOpts = append(urlOpts, services.WithMetadata(metadata))
+	}
+	if cfg.LinkCacheSize > 0 {
+		linkCache := services.NewLinkCache(cfg.LinkCacheSize, cfg.LinkCacheTTL)
+		expvar.Publish("link_cache", expvar.Func(func() any { return linkCache.Stats() }))
+		urlOpts = append(urlOpts, services.WithLinkCache(linkCache))
+	}
+
+	// Stats reads go through the rollups when the job maintains them; the
+	// click pipeline above keeps writing raw clicks.
+	var statsReader repository.URLStatsRepository = statsRepo
+	rollupRepo := persistence.NewMongoRollupRepository(db)
+	if cfg.RollupEnabled {
+		rollup := services.NewRollupService(statsRepo, rollupRepo, services.RollupConfig{
+			Interval: cfg.RollupInterval,
+			Lag:      cfg.RollupLag,
+			MaxHours: cfg.RollupMaxHours,
+			Owner:    rollupOwner(),
+		})
+		rollup.Start()
+		app.onShutdown(rollup.Close)
+		statsReader = services.NewRolledUpStats(statsRepo, rollupRepo)
+	}
+
+	// Anomaly detection reads the hourly rollups, so it only runs alongside
+	// the rollup job; past alerts stay listed either way.
+	notifiers := []services.AlertNotifier{notify.NewWebhookNotifier(cfg.WebhookTimeout)}
+	if cfg.SMTPAddr != "" {
+		notifiers = append(notifiers, notify.NewEmailNotifier(cfg.SMTPAddr, cfg.AlertEmailFrom, cfg.SMTPUsername, cfg.SMTPPassword))
+	}
+	anomaly := services.NewAnomalyService(urlRepo, userRepo, rollupRepo, persistence.NewMongoAlertRepository(db), services.AnomalyConfig{
+		Interval:      cfg.AlertInterval,
+		BaselineHours: cfg.AlertBaselineHours,
+		MinClicks:     cfg.AlertMinClicks,
+		FlatHours:     cfg.AlertFlatHours,
+		Cooldown:      cfg.AlertCooldown,
+	}, notifiers...)
+	if cfg.RollupEnabled && cfg.AlertsEnabled {
+		anomaly.Start()
+	}
+	app.onShutdown(anomaly.Close)
+
+	urlService := services.NewURLService(urlRepo, idGen, statsReader, urlOpts...)
+	warmLinkCache(urlService, cfg.LinkCacheWarm)
 	userService := services.NewUserService(userRepo, hasher, tokenGen)
+	shareService := services.NewShareService(persistence.NewMongoStatsShareRepository(db), urlService, []byte(cfg.ShareKey))
 
 	urlHandler := handler.NewURLHandler(urlService)
+	liveHandler := handler.NewLiveHandler(urlService, cfg.LiveHeartbeat)
 	userHandler := handler.NewUserHandler(userService)
+	shareHandler := handler.NewShareHandler(shareService)
+	alertHandler := handler.NewAlertHandler(anomaly)
 
 	rl := middleware.NewIPRateLimiter(1, 3, 3*time.Minute, 1*time.Minute)
 
 	r := chi.NewRouter()
-	r.Use(rl.Middleware())
+	r.Use(middleware.RealIP(cfg.TrustedProxies))
+
+	// Advertisers post conversions from a few servers and authenticate with
+	// the link's postback secret, so postbacks skip the per-IP limit.
+	r.Group(func(r chi.Router) {
+		r.Use(chimiddleware.Timeout(cfg.RequestTimeout))
+		r.Get("/conversions/postback", urlHandler.Postback)
+		r.Post("/conversions/postback", urlHandler.Postback)
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(rl.Middleware())
+		r.Use(chimiddleware.Timeout(cfg.RequestTimeout))
+
+		r.Post("/users", userHandler.Save)
+		r.Post("/users/signin", userHandler.Login)
 
-	r.Post("/users", userHandler.Save)
-	r.Post("/users/signin", userHandler.Login)
+		r.Get("/urls/{id}", urlHandler.Redirect)
+		r.Head("/urls/{id}", urlHandler.Redirect)
+		r.Get("/urls/{id}/preview", urlHandler.Preview)
 
-	r.Get("/urls/{id}", urlHandler.Redirect)
+		r.Get("/conversions/pixel.gif", urlHandler.Pixel)
 
-	r.Group(func(protected chi.Router) {
-		protected.Use(middleware.AuthMiddleware(tokenGen))
-		protected.Post("/urls/shorten", urlHandler.Shorten)
-		protected.Get("/urls/{id}/stats", urlHandler.Stats)
+		r.Get("/shared/{token}", shareHandler.Page)
+		r.Get("/shared/{token}/stats", shareHandler.Stats)
+
+		r.Group(func(protected chi.Router) {
+			protected.Use(middleware.AuthMiddleware(tokenGen))
+			protected.Post("/urls/shorten", urlHandler.Shorten)
+			protected.Put("/urls/{id}/conversions", urlHandler.ConfigureConversions)
+			protected.Get("/urls/{id}/stats", urlHandler.Stats)
+			protected.Get("/urls/{id}/stats/timeseries", urlHandler.TimeSeries)
+			protected.Get("/urls/{id}/stats/clicks", urlHandler.Clicks)
+			protected.Get("/urls/{id}/stats/breakdown", urlHandler.Breakdown)
+			protected.Get("/urls/{id}/stats/referrers", urlHandler.Referrers)
+			protected.Get("/urls/{id}/stats/geo", urlHandler.Geo)
+			protected.Get("/urls/{id}/stats/map", urlHandler.GeoMap)
+			protected.Get("/me/analytics", urlHandler.AccountAnalytics)
+			protected.Get("/me/alerts", alertHandler.List)
+			protected.Put("/me/alerts/webhook", alertHandler.SetWebhook)
+			protected.Post("/urls/{id}/shares", shareHandler.Create)
+			protected.Get("/urls/{id}/shares", shareHandler.List)
+			protected.Delete("/urls/{id}/shares/{shareID}", shareHandler.Revoke)
+			protected.Put("/urls/{id}/campaign", urlHandler.SetCampaign)
+			protected.Get("/urls", urlHandler.Links)
+			protected.Put("/urls/{id}/labels", urlHandler.SetLabels)
+			protected.Put("/urls/{id}/metadata", urlHandler.SetMetadata)
+			protected.Post("/campaigns", urlHandler.CreateCampaign)
+			protected.Get("/campaigns", urlHandler.Campaigns)
+			protected.Get("/campaigns/{id}/stats", urlHandler.CampaignStats)
+			protected.Post("/campaigns/{id}/archive", urlHandler.ArchiveCampaign)
+			protected.Post("/campaigns/{id}/unarchive", urlHandler.UnarchiveCampaign)
+		})
+	})
+
+	// Exports and live streams stay open for as long as they have data to
+	// send, so they are exempt from the request timeout.
+	r.Group(func(streaming chi.Router) {
+		streaming.Use(rl.Middleware())
+		streaming.Use(middleware.AuthMiddleware(tokenGen))
+		streaming.Get("/urls/{id}/stats/export", urlHandler.Export)
+		streaming.Get("/me/export", urlHandler.ExportAll)
+		streaming.Get("/urls/{id}/stats/live", liveHandler.Stream)
 	})
 
-	return r
+	admin := http.NewServeMux()
+	admin.Handle("/debug/vars", expvar.Handler())
+
+	app.Handler = r
+	app.AdminHandler = admin
+	return app
+}
+
+// openGeoIP returns nil when no database is configured or it cannot be read,
+// in which case clicks are stored without a location.
+func openGeoIP(path string) *geoip.Reader {
+	if path == "" {
+		return nil
+	}
+	geo, err := geoip.Open(path)
+	if err != nil {
+		log.Printf("geoip disabled: %v", err)
+		return nil
+	}
+	return geo
+}
+
+// rollupOwner names this process in the rollup job lease.
+func rollupOwner() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}
+
+func warmLinkCache(urlService *services.URLService, limit int) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	n, err := urlService.WarmCache(ctx, limit)
+	if err != nil {
+		log.Printf("link cache warm-up failed: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("link cache warmed with %d links", n)
+	}
 }
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"url-shortener/internal/domain/exceptions"
+	"url-shortener/internal/interface/middleware"
+	"url-shortener/internal/services"
+	"url-shortener/internal/services/dto"
+)
+
+type AlertHandler struct {
+	service *services.AnomalyService
+}
+
+func NewAlertHandler(s *services.AnomalyService) *AlertHandler {
+	return &AlertHandler{service: s}
+}
+
+// List returns the caller's latest traffic alerts, up to the limit query
+// parameter.
+func (h *AlertHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit := 0
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			http.Error(w, "Parâmetros inválidos", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	alerts, err := h.service.Alerts(r.Context(), userID, limit)
+	if err != nil {
+		writeStatsError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(alerts)
+}
+
+// SetWebhook sets the endpoint the caller's alerts are posted to. The
+// response carries the signing secret, generated when none was sent.
+func (h *AlertHandler) SetWebhook(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.WebhookInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Dados inválidos", http.StatusBadRequest)
+		return
+	}
+
+	webhook, err := h.service.SetWebhook(r.Context(), userID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, exceptions.ErrInvalidWebhook):
+			http.Error(w, "Webhook inválido: use uma URL https pública", http.StatusBadRequest)
+		case errors.Is(err, exceptions.ErrUserNotFound):
+			http.Error(w, "Usuário não encontrado", http.StatusNotFound)
+		default:
+			http.Error(w, "Erro ao salvar webhook", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(webhook)
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"url-shortener/internal/domain/exceptions"
+	"url-shortener/internal/interface/middleware"
+	"url-shortener/internal/services/dto"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (h *URLHandler) CreateCampaign(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.CampaignInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Parâmetros inválidos", http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		http.Error(w, "Parâmetros inválidos", http.StatusBadRequest)
+		return
+	}
+
+	campaign, err := h.service.CreateCampaign(r.Context(), userID, req)
+	if err != nil {
+		writeCampaignError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(campaign)
+}
+
+func (h *URLHandler) Campaigns(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	campaigns, err := h.service.Campaigns(r.Context(), userID, r.URL.Query().Get("archived") == "true")
+	if err != nil {
+		writeCampaignError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(campaigns)
+}
+
+func (h *URLHandler) CampaignStats(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	id := chi.URLParam(r, "id")
+	if userID == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	input, err := accountAnalyticsInput(r)
+	if err != nil {
+		http.Error(w, "Parâmetros inválidos", http.StatusBadRequest)
+		return
+	}
+
+	stats, err := h.service.CampaignStats(r.Context(), id, userID, input)
+	if err != nil {
+		writeCampaignError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(stats)
+}
+
+func (h *URLHandler) ArchiveCampaign(w http.ResponseWriter, r *http.Request) {
+	h.archiveCampaign(w, r, true)
+}
+
+func (h *URLHandler) UnarchiveCampaign(w http.ResponseWriter, r *http.Request) {
+	h.archiveCampaign(w, r, false)
+}
+
+func (h *URLHandler) archiveCampaign(w http.ResponseWriter, r *http.Request, archived bool) {
+	userID := middleware.GetUserID(r.Context())
+	id := chi.URLParam(r, "id")
+	if userID == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	campaign, err := h.service.ArchiveCampaign(r.Context(), id, userID, archived)
+	if err != nil {
+		writeCampaignError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(campaign)
+}
+
+func (h *URLHandler) SetCampaign(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	id := chi.URLParam(r, "id")
+	if userID == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.CampaignLink
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Parâmetros inválidos", http.StatusBadRequest)
+		return
+	}
+
+	url, err := h.service.SetCampaign(r.Context(), id, userID, req.CampaignID)
+	if err != nil {
+		writeCampaignError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(url)
+}
+
+func isCampaignError(err error) bool {
+	return errors.Is(err, exceptions.ErrCampaignNotFound) ||
+		errors.Is(err, exceptions.ErrCampaignArchived) ||
+		errors.Is(err, exceptions.ErrInvalidCampaign)
+}
+
+func writeCampaignError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, exceptions.ErrCampaignNotFound):
+		http.Error(w, "Campanha não encontrada", http.StatusNotFound)
+	case errors.Is(err, exceptions.ErrCampaignArchived):
+		http.Error(w, "Campanha arquivada", http.StatusConflict)
+	case errors.Is(err, exceptions.ErrInvalidCampaign):
+		http.Error(w, "Parâmetros inválidos", http.StatusBadRequest)
+	default:
+		writeStatsError(w, err)
+	}
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"url-shortener/internal/domain/exceptions"
+	"url-shortener/internal/interface/middleware"
+	"url-shortener/internal/services/dto"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// pixel is a transparent 1x1 GIF.
+var pixel = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+func (h *URLHandler) ConfigureConversions(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	id := chi.URLParam(r, "id")
+	if userID == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.ConversionSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Parâmetros inválidos", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.service.ConfigureConversions(r.Context(), id, userID, req)
+	if err != nil {
+		writeConversionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(settings)
+}
+
+// Postback records a conversion reported by an advertiser's server, with the
+// click ID, the link's postback secret and an optional value as query or
+// form parameters.
+func (h *URLHandler) Postback(w http.ResponseWriter, r *http.Request) {
+	conversion, err := h.service.Convert(r.Context(), dto.ConversionInput{
+		ClickID: r.FormValue("click_id"),
+		Value:   r.FormValue("value"),
+		Secret:  r.FormValue("secret"),
+	})
+	if err != nil {
+		writeConversionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !conversion.Duplicate {
+		w.WriteHeader(http.StatusCreated)
+	}
+	_ = json.NewEncoder(w).Encode(conversion)
+}
+
+// Pixel records a conversion from a browser. It always answers with the
+// pixel, so a failed attribution never breaks the advertiser's page.
+func (h *URLHandler) Pixel(w http.ResponseWriter, r *http.Request) {
+	if _, err := h.service.ConvertPixel(r.Context(), r.FormValue("click_id")); err != nil && !isConversionRejection(err) {
+		log.Printf("conversion pixel: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "image/gif")
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write(pixel)
+}
+
+func isConversionRejection(err error) bool {
+	return errors.Is(err, exceptions.ErrInvalidConversion) ||
+		errors.Is(err, exceptions.ErrClickNotFound) ||
+		errors.Is(err, exceptions.ErrConversionExpired)
+}
+
+func writeConversionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, exceptions.ErrInvalidConversion):
+		http.Error(w, "Parâmetros inválidos", http.StatusBadRequest)
+	case errors.Is(err, exceptions.ErrInvalidPostbackSecret):
+		http.Error(w, "Segredo de postback inválido", http.StatusForbidden)
+	case errors.Is(err, exceptions.ErrClickNotFound):
+		http.Error(w, "Clique não encontrado", http.StatusNotFound)
+	case errors.Is(err, exceptions.ErrConversionExpired):
+		http.Error(w, "Clique fora da janela de atribuição", http.StatusUnprocessableEntity)
+	default:
+		writeStatsError(w, err)
+	}
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"url-shortener/internal/domain/exceptions"
+	"url-shortener/internal/interface/middleware"
+	"url-shortener/internal/services/dto"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Links lists the caller's links, filtered by the tag and folder query
+// parameters.
+func (h *URLHandler) Links(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	urls, err := h.service.Links(r.Context(), userID, dto.LinkFilter{
+		Tag:    q.Get("tag"),
+		Folder: q.Get("folder"),
+	})
+	if err != nil {
+		http.Error(w, "Erro ao listar links", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(urls)
+}
+
+func (h *URLHandler) SetLabels(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	id := chi.URLParam(r, "id")
+	if userID == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.Labels
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Parâmetros inválidos", http.StatusBadRequest)
+		return
+	}
+
+	url, err := h.service.SetLabels(r.Context(), id, userID, req)
+	if err != nil {
+		if errors.Is(err, exceptions.ErrInvalidLabels) {
+			http.Error(w, "Tags ou pasta inválidas", http.StatusBadRequest)
+			return
+		}
+		writeStatsError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(url)
+}
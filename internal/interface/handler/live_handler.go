@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"url-shortener/internal/interface/middleware"
+	"url-shortener/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// LiveHandler streams clicks over Server-Sent Events.
+type LiveHandler struct {
+	service   *services.URLService
+	heartbeat time.Duration
+}
+
+func NewLiveHandler(s *services.URLService, heartbeat time.Duration) *LiveHandler {
+	if heartbeat <= 0 {
+		heartbeat = 15 * time.Second
+	}
+	return &LiveHandler{service: s, heartbeat: heartbeat}
+}
+
+func (h *LiveHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	id := chi.URLParam(r, "id")
+	if userID == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sub, backlog, err := h.service.Live(r.Context(), id, userID, r.Header.Get("Last-Event-ID"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrTooManySubscribers), errors.Is(err, services.ErrClickBrokerClosed):
+			w.Header().Set("Retry-After", "10")
+			http.Error(w, "Serviço indisponível", http.StatusServiceUnavailable)
+		default:
+			writeStatsError(w, err)
+		}
+		return
+	}
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Keeps reverse proxies such as nginx from buffering the stream.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprint(w, "retry: 3000\n\n")
+	for _, event := range backlog {
+		if err := writeLiveEvent(w, event); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.Events:
+			if !ok {
+				return
+			}
+			if err := writeLiveEvent(w, event); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeLiveEvent(w http.ResponseWriter, event services.LiveEvent) error {
+	data, err := json.Marshal(event.Click)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: click\ndata: %s\n\n", event.ID, data)
+	return err
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"url-shortener/internal/domain/exceptions"
+	"url-shortener/internal/interface/middleware"
+	"url-shortener/internal/services/dto"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (h *URLHandler) SetMetadata(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	id := chi.URLParam(r, "id")
+	if userID == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.Preview
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Parâmetros inválidos", http.StatusBadRequest)
+		return
+	}
+
+	preview, err := h.service.SetMetadata(r.Context(), id, userID, req)
+	if err != nil {
+		if errors.Is(err, exceptions.ErrInvalidMetadata) {
+			http.Error(w, "Metadados inválidos", http.StatusBadRequest)
+			return
+		}
+		writeStatsError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(preview)
+}
+
+// Preview shows where a link goes without following it, for anyone.
+func (h *URLHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	preview, err := h.service.Preview(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeStatsError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(preview)
+}
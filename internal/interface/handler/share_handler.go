@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"url-shortener/internal/domain/exceptions"
+	"url-shortener/internal/interface/middleware"
+	"url-shortener/internal/services"
+	"url-shortener/internal/services/dto"
+
+	"github.com/go-chi/chi/v5"
+)
+
+//go:embed templates/shared_stats.html
+var templates embed.FS
+
+var sharedStatsPage = template.Must(template.New("shared_stats.html").Funcs(template.FuncMap{
+	"barHeight": func(clicks, peak int) float64 {
+		if peak == 0 {
+			return 0
+		}
+		return float64(clicks) * 100 / float64(peak)
+	},
+	"section": func(title string, breakdown *dto.Breakdown) map[string]any {
+		return map[string]any{"Title": title, "Breakdown": breakdown}
+	},
+}).ParseFS(templates, "templates/shared_stats.html"))
+
+// ShareHandler manages stats shares and serves the shared stats, as JSON and
+// as an HTML page, to anyone holding a share token.
+type ShareHandler struct {
+	service *services.ShareService
+}
+
+func NewShareHandler(s *services.ShareService) *ShareHandler {
+	return &ShareHandler{service: s}
+}
+
+func (h *ShareHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	id := chi.URLParam(r, "id")
+	if userID == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.ShareInput
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Parâmetros inválidos", http.StatusBadRequest)
+			return
+		}
+	}
+
+	share, err := h.service.Create(r.Context(), id, userID, req)
+	if err != nil {
+		writeShareError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(share)
+}
+
+func (h *ShareHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	id := chi.URLParam(r, "id")
+	if userID == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	shares, err := h.service.List(r.Context(), id, userID)
+	if err != nil {
+		writeShareError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(shares)
+}
+
+func (h *ShareHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	id := chi.URLParam(r, "id")
+	if userID == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.service.Revoke(r.Context(), id, userID, chi.URLParam(r, "shareID")); err != nil {
+		writeShareError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ShareHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.sharedStats(w, r)
+	if err != nil {
+		writeShareError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(stats)
+}
+
+// Page renders the shared stats as a standalone HTML page.
+func (h *ShareHandler) Page(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.sharedStats(w, r)
+	if err != nil {
+		writeShareError(w, err)
+		return
+	}
+
+	peak := 0
+	if stats.TimeSeries != nil {
+		for _, b := range stats.TimeSeries.Buckets {
+			peak = max(peak, b.Clicks)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	if err := sharedStatsPage.Execute(w, map[string]any{"Stats": stats, "Peak": peak}); err != nil {
+		log.Printf("shared stats page: %v", err)
+	}
+}
+
+// sharedStats also sets the headers common to both views: the token is in
+// the URL, so it must not leak through referrers, caches or search engines.
+func (h *ShareHandler) sharedStats(w http.ResponseWriter, r *http.Request) (*dto.SharedStats, error) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("X-Robots-Tag", "noindex")
+
+	q := r.URL.Query()
+	return h.service.Stats(r.Context(), chi.URLParam(r, "token"), dto.TimeSeriesInput{
+		Interval: q.Get("interval"),
+		From:     q.Get("from"),
+		To:       q.Get("to"),
+		TZ:       q.Get("tz"),
+	})
+}
+
+func writeShareError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, exceptions.ErrShareNotFound):
+		http.Error(w, "Link de compartilhamento não encontrado", http.StatusNotFound)
+	case errors.Is(err, exceptions.ErrInvalidShare):
+		http.Error(w, "Parâmetros inválidos", http.StatusBadRequest)
+	default:
+		writeStatsError(w, err)
+	}
+}
@@ -1,9 +1,12 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"log"
 	"net/http"
+	"strconv"
 	"url-shortener/internal/domain/exceptions"
 	"url-shortener/internal/interface/middleware"
 	"url-shortener/internal/services"
@@ -42,12 +45,20 @@ func (h *URLHandler) Shorten(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	url, err := h.service.Shorten(req.URL, userID)
+	url, err := h.service.Shorten(r.Context(), req, userID)
 	if err != nil {
 		if errors.Is(err, exceptions.ErrInvalidURL) {
 			http.Error(w, "URL inválida", http.StatusBadRequest)
 			return
 		}
+		if errors.Is(err, exceptions.ErrInvalidLabels) {
+			http.Error(w, "Tags ou pasta inválidas", http.StatusBadRequest)
+			return
+		}
+		if isCampaignError(err) {
+			writeCampaignError(w, err)
+			return
+		}
 		http.Error(w, "Erro ao encurtar", http.StatusInternalServerError)
 		return
 	}
@@ -59,17 +70,33 @@ func (h *URLHandler) Shorten(w http.ResponseWriter, r *http.Request) {
 func (h *URLHandler) Redirect(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
-	ip := r.RemoteAddr
-	userAgent := r.UserAgent()
-	referer := r.Referer()
+	visit := dto.Visit{
+		Method:             r.Method,
+		IP:                 middleware.ClientIP(r),
+		UserAgent:          r.UserAgent(),
+		Referer:            r.Referer(),
+		Accept:             r.Header.Get("Accept"),
+		ClientHintUA:       r.Header.Get("Sec-CH-UA"),
+		ClientHintMobile:   r.Header.Get("Sec-CH-UA-Mobile"),
+		ClientHintPlatform: r.Header.Get("Sec-CH-UA-Platform"),
 
-	url, err := h.service.Resolve(id, ip, userAgent, referer)
+		DoNotTrack:           r.Header.Get("DNT"),
+		GlobalPrivacyControl: r.Header.Get("Sec-GPC"),
+	}
+
+	redirect, err := h.service.Resolve(r.Context(), id, visit)
 	if err != nil {
 		http.NotFound(w, r)
 		return
 	}
 
-	http.Redirect(w, r, url.OriginalURL, http.StatusMovedPermanently)
+	// A click ID is unique to this visit, so the redirect must not be cached.
+	if redirect.ClickID != "" {
+		w.Header().Set("Cache-Control", "no-store")
+		http.Redirect(w, r, redirect.Location, http.StatusFound)
+		return
+	}
+	http.Redirect(w, r, redirect.Location, http.StatusMovedPermanently)
 }
 
 func (h *URLHandler) Stats(w http.ResponseWriter, r *http.Request) {
@@ -80,7 +107,7 @@ func (h *URLHandler) Stats(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	stats, err := h.service.Stats(id, userID)
+	stats, err := h.service.Stats(r.Context(), id, userID)
 	if err != nil {
 		if errors.Is(err, exceptions.ErrURLNotFound) {
 			http.Error(w, "URL não encontrada", http.StatusNoContent)
@@ -97,3 +124,246 @@ func (h *URLHandler) Stats(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(stats)
 }
+
+func (h *URLHandler) TimeSeries(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	id := chi.URLParam(r, "id")
+	if userID == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	input := dto.TimeSeriesInput{
+		Interval:    q.Get("interval"),
+		From:        q.Get("from"),
+		To:          q.Get("to"),
+		TZ:          q.Get("tz"),
+		IncludeBots: q.Get("include_bots") == "true",
+	}
+
+	series, err := h.service.TimeSeries(r.Context(), id, userID, input)
+	if err != nil {
+		writeStatsError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(series)
+}
+
+func (h *URLHandler) AccountAnalytics(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	input, err := accountAnalyticsInput(r)
+	if err != nil {
+		http.Error(w, "Parâmetros inválidos", http.StatusBadRequest)
+		return
+	}
+
+	analytics, err := h.service.AccountAnalytics(r.Context(), userID, input)
+	if err != nil {
+		writeStatsError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(analytics)
+}
+
+func accountAnalyticsInput(r *http.Request) (dto.AccountAnalyticsInput, error) {
+	q := r.URL.Query()
+	input := dto.AccountAnalyticsInput{
+		Interval:    q.Get("interval"),
+		From:        q.Get("from"),
+		To:          q.Get("to"),
+		TZ:          q.Get("tz"),
+		IncludeBots: q.Get("include_bots") == "true",
+		Tag:         q.Get("tag"),
+		Folder:      q.Get("folder"),
+	}
+	if raw := q.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil {
+			return input, err
+		}
+		input.Limit = limit
+	}
+	return input, nil
+}
+
+func (h *URLHandler) Clicks(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	id := chi.URLParam(r, "id")
+	if userID == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	input := dto.ClickLogInput{
+		From:        q.Get("from"),
+		To:          q.Get("to"),
+		RefererHost: q.Get("referer_host"),
+		UserAgent:   q.Get("user_agent"),
+		Class:       q.Get("class"),
+		Cursor:      q.Get("cursor"),
+	}
+	if raw := q.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil {
+			http.Error(w, "Parâmetros inválidos", http.StatusBadRequest)
+			return
+		}
+		input.Limit = limit
+	}
+
+	page, err := h.service.Clicks(r.Context(), id, userID, input)
+	if err != nil {
+		writeStatsError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(page)
+}
+
+func (h *URLHandler) Breakdown(w http.ResponseWriter, r *http.Request) {
+	h.breakdown(w, r, h.service.Breakdown)
+}
+
+func (h *URLHandler) Referrers(w http.ResponseWriter, r *http.Request) {
+	h.breakdown(w, r, h.service.Referrers)
+}
+
+func (h *URLHandler) Geo(w http.ResponseWriter, r *http.Request) {
+	h.breakdown(w, r, h.service.Geo)
+}
+
+func (h *URLHandler) GeoMap(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	id := chi.URLParam(r, "id")
+	if userID == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	geoMap, err := h.service.GeoMap(r.Context(), id, userID, dto.GeoMapInput{
+		From:        q.Get("from"),
+		To:          q.Get("to"),
+		IncludeBots: q.Get("include_bots") == "true",
+	})
+	if err != nil {
+		writeStatsError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(geoMap)
+}
+
+func (h *URLHandler) Export(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	id := chi.URLParam(r, "id")
+	if userID == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	export, err := h.service.Export(r.Context(), id, userID, exportInput(r))
+	if err != nil {
+		writeStatsError(w, err)
+		return
+	}
+	writeExport(w, r, export)
+}
+
+func (h *URLHandler) ExportAll(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	export, err := h.service.ExportAll(r.Context(), userID, exportInput(r))
+	if err != nil {
+		writeStatsError(w, err)
+		return
+	}
+	writeExport(w, r, export)
+}
+
+func exportInput(r *http.Request) dto.ExportInput {
+	q := r.URL.Query()
+	return dto.ExportInput{
+		Format:  q.Get("format"),
+		From:    q.Get("from"),
+		To:      q.Get("to"),
+		Columns: q.Get("columns"),
+	}
+}
+
+// writeExport streams an export. Once the first bytes are out the status can
+// no longer change, so a failure midway only truncates the download.
+func writeExport(w http.ResponseWriter, r *http.Request, export *services.ClickExport) {
+	w.Header().Set("Content-Type", export.ContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+export.Filename+`"`)
+
+	if err := export.Write(r.Context(), w); err != nil {
+		log.Printf("export %s failed: %v", export.Filename, err)
+	}
+}
+
+type breakdownFunc func(ctx context.Context, id, ownerID string, input dto.BreakdownInput) (*dto.Breakdown, error)
+
+func (h *URLHandler) breakdown(w http.ResponseWriter, r *http.Request, run breakdownFunc) {
+	userID := middleware.GetUserID(r.Context())
+	id := chi.URLParam(r, "id")
+	if userID == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	input := dto.BreakdownInput{
+		By:          q.Get("by"),
+		From:        q.Get("from"),
+		To:          q.Get("to"),
+		IncludeBots: q.Get("include_bots") == "true",
+	}
+	if raw := q.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil {
+			http.Error(w, "Parâmetros inválidos", http.StatusBadRequest)
+			return
+		}
+		input.Limit = limit
+	}
+
+	breakdown, err := run(r.Context(), id, userID, input)
+	if err != nil {
+		writeStatsError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(breakdown)
+}
+
+func writeStatsError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, exceptions.ErrURLNotFound):
+		http.Error(w, "URL não encontrada", http.StatusNotFound)
+	case errors.Is(err, exceptions.ErrUnauthorizedURLStatistics):
+		http.Error(w, "Sem permissão para visualizar", http.StatusForbidden)
+	case errors.Is(err, exceptions.ErrInvalidStatsQuery):
+		http.Error(w, "Parâmetros inválidos", http.StatusBadRequest)
+	default:
+		http.Error(w, "Erro ao buscar", http.StatusInternalServerError)
+	}
+}
@@ -29,8 +29,12 @@ func (h *UserHandler) Save(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.service.Save(&req)
+	user, err := h.service.Save(r.Context(), &req)
 	if err != nil {
+		if errors.Is(err, exceptions.ErrEmailAlreadyExists) {
+			http.Error(w, "Email já cadastrado", http.StatusConflict)
+			return
+		}
 		http.Error(w, "Erro ao criar usuário", http.StatusInternalServerError)
 		return
 	}
@@ -50,7 +54,7 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.service.LoginUser(&req)
+	user, err := h.service.LoginUser(r.Context(), &req)
 	if err != nil {
 		if errors.Is(err, exceptions.ErrInvalidCredentials) {
 			http.Error(w, "Credenciais invalidas", http.StatusForbidden)
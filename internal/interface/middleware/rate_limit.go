@@ -3,9 +3,7 @@ package middleware
 import (
 	"fmt"
 	"log"
-	"net"
 	"net/http"
-	"strings"
 	"sync"
 	"time"
 
@@ -53,7 +51,7 @@ func (rl *IPRateLimiter) getLimiter(ip string) *rate.Limiter {
 func (rl *IPRateLimiter) Middleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ip := getIP(r)
+			ip := ClientIP(r)
 			limiter := rl.getLimiter(ip)
 
 			if !limiter.Allow() {
@@ -81,15 +79,3 @@ func (rl *IPRateLimiter) cleanupVisitors() {
 		})
 	}
 }
-
-func getIP(r *http.Request) string {
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		parts := strings.Split(xff, ",")
-		return strings.TrimSpace(parts[0])
-	}
-	ip, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err != nil {
-		return r.RemoteAddr
-	}
-	return ip
-}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// RealIP sets the remote address of requests relayed by a trusted proxy to
+// the client address in X-Forwarded-For: the rightmost hop that is not a
+// trusted proxy itself. Anyone can send the header, so requests from other
+// peers keep their own address. trusted holds CIDRs or single addresses;
+// malformed entries are skipped.
+func RealIP(trusted []string) func(http.Handler) http.Handler {
+	var proxies []netip.Prefix
+	for _, item := range trusted {
+		prefix, err := parseProxy(item)
+		if err != nil {
+			log.Printf("trusted proxy %q ignored: %v", item, err)
+			continue
+		}
+		proxies = append(proxies, prefix)
+	}
+	isTrusted := func(addr netip.Addr) bool {
+		for _, prefix := range proxies {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			peer, err := netip.ParseAddr(ClientIP(r))
+			if err != nil || !isTrusted(peer.Unmap()) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+			for i := len(hops) - 1; i >= 0; i-- {
+				hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+				if err != nil {
+					break
+				}
+				r.RemoteAddr = hop.Unmap().String()
+				if !isTrusted(hop.Unmap()) {
+					break
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func parseProxy(item string) (netip.Prefix, error) {
+	if strings.Contains(item, "/") {
+		prefix, err := netip.ParsePrefix(item)
+		return prefix.Masked(), err
+	}
+	addr, err := netip.ParseAddr(item)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// ClientIP returns the address of the client. Behind trusted proxies, RealIP
+// has already replaced the remote address with it.
+func ClientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
@@ -0,0 +1,44 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"url-shortener/internal/interface/middleware"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRealIP(t *testing.T) {
+	var got string
+	h := middleware.RealIP([]string{"10.0.0.0/8", "192.0.2.1", "bogus"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = middleware.ClientIP(r)
+	}))
+
+	tests := []struct {
+		name   string
+		remote string
+		xff    []string
+		want   string
+	}{
+		{"untrusted peer", "203.0.113.7:5000", []string{"1.1.1.1"}, "203.0.113.7"},
+		{"no header", "10.0.0.5:5000", nil, "10.0.0.5"},
+		{"trusted peer", "10.0.0.5:5000", []string{"1.1.1.1"}, "1.1.1.1"},
+		{"spoofed first hop", "10.0.0.5:5000", []string{"6.6.6.6, 1.1.1.1"}, "1.1.1.1"},
+		{"proxy chain", "192.0.2.1:5000", []string{"6.6.6.6, 1.1.1.1, 10.1.2.3"}, "1.1.1.1"},
+		{"repeated header", "10.0.0.5:5000", []string{"6.6.6.6", "1.1.1.1"}, "1.1.1.1"},
+		{"malformed hop", "10.0.0.5:5000", []string{"1.1.1.1, junk"}, "10.0.0.5"},
+		{"ipv6 peer", "[2001:db8::1]:5000", []string{"1.1.1.1"}, "2001:db8::1"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = tt.remote
+		for _, v := range tt.xff {
+			r.Header.Add("X-Forwarded-For", v)
+		}
+
+		h.ServeHTTP(httptest.NewRecorder(), r)
+
+		assert.Equal(t, tt.want, got, tt.name)
+	}
+}
@@ -0,0 +1,517 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"log"
+	"math"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/exceptions"
+	"url-shortener/internal/domain/repository"
+	"url-shortener/internal/services/dto"
+	"url-shortener/pkg/linkmeta"
+)
+
+// anomalyBatch is how many links' hourly rollups are loaded at once.
+const anomalyBatch = 200
+
+// anomalyMaxHours caps the hours one run evaluates, so catching up after an
+// outage is spread over several runs.
+const anomalyMaxHours = 24
+
+// minAnomalyHistory is how long before the evaluated hour a link must have
+// had clicks to raise spike and bot burst alerts, so a link's launch is not
+// reported as a spike.
+const minAnomalyHistory = 24
+
+// AlertNotifier delivers an alert to the owner of the link. owner is nil
+// when the account could not be loaded.
+type AlertNotifier interface {
+	Notify(ctx context.Context, alert entity.Alert, owner *entity.User) error
+}
+
+type AnomalyConfig struct {
+	// Interval is how often the job looks for a newly rolled-up hour.
+	Interval time.Duration
+	// BaselineHours is the rolling window the evaluated hour is compared to.
+	BaselineHours int
+	// MinClicks keeps low-traffic links quiet: spikes and bot bursts need at
+	// least this many clicks in the hour, and a link only goes flat when at
+	// least this many clicks were expected over FlatHours.
+	MinClicks int
+	// SpikeFactor is how many times the baseline, and how many deviations
+	// above it, an hour must be to count as a spike.
+	SpikeFactor float64
+	// FlatHours is how many hours without human clicks make a link flat.
+	FlatHours int
+	// BotShare is the fraction of an hour's clicks that must come from bots
+	// for a bot burst.
+	BotShare float64
+	// Cooldown is the minimum time between two spike or bot burst alerts of
+	// the same link. A flat link alerts once per outage.
+	Cooldown time.Duration
+	// NotifyQueue bounds the alerts waiting to be sent; when it is full new
+	// alerts are only listed. NotifyTimeout bounds each delivery.
+	NotifyQueue   int
+	NotifyTimeout time.Duration
+}
+
+// AnomalyService compares each link's latest rolled-up hour with its rolling
+// baseline and alerts the owner of sudden spikes, drops to zero and bot-heavy
+// bursts. Alerts are unique per link, kind and hour, so replicas running the
+// job side by side notify once. Notifications are sent in the background, so
+// a slow endpoint cannot hold up detection.
+type AnomalyService struct {
+	urls      repository.URLRepository
+	users     repository.UserRepository
+	rollups   repository.RollupRepository
+	alerts    repository.AlertRepository
+	notifiers []AlertNotifier
+	cfg       AnomalyConfig
+
+	mu sync.Mutex
+
+	outbox    chan entity.Alert
+	outboxMu  sync.RWMutex
+	closed    bool
+	delivered chan struct{}
+
+	started atomic.Bool
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+func NewAnomalyService(urls repository.URLRepository, users repository.UserRepository, rollups repository.RollupRepository, alerts repository.AlertRepository, cfg AnomalyConfig, notifiers ...AlertNotifier) *AnomalyService {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Minute
+	}
+	if cfg.BaselineHours <= 0 {
+		cfg.BaselineHours = 7 * 24
+	}
+	if cfg.MinClicks <= 0 {
+		cfg.MinClicks = 20
+	}
+	if cfg.SpikeFactor <= 1 {
+		cfg.SpikeFactor = 3
+	}
+	if cfg.FlatHours <= 0 {
+		cfg.FlatHours = 6
+	}
+	if cfg.BotShare <= 0 || cfg.BotShare > 1 {
+		cfg.BotShare = 0.8
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = 24 * time.Hour
+	}
+	if cfg.NotifyQueue <= 0 {
+		cfg.NotifyQueue = 1000
+	}
+	if cfg.NotifyTimeout <= 0 {
+		cfg.NotifyTimeout = 30 * time.Second
+	}
+	s := &AnomalyService{
+		urls:      urls,
+		users:     users,
+		rollups:   rollups,
+		alerts:    alerts,
+		notifiers: notifiers,
+		cfg:       cfg,
+		outbox:    make(chan entity.Alert, cfg.NotifyQueue),
+		delivered: make(chan struct{}),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go s.deliver()
+	return s
+}
+
+// Run evaluates every hour folded into the rollups since the detector
+// checkpoint, up to anomalyMaxHours, and returns how many alerts it raised.
+// The first run starts from the last rolled-up hour rather than replaying
+// history.
+func (s *AnomalyService) Run(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	through, err := s.rollups.Checkpoint(ctx)
+	if err != nil || through.IsZero() {
+		return 0, err
+	}
+	hour, err := s.alerts.Checkpoint(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if hour.IsZero() {
+		hour = through.Add(-time.Hour)
+	}
+
+	raised := 0
+	for n := 0; hour.Before(through) && n < anomalyMaxHours; n++ {
+		r, err := s.Evaluate(ctx, hour)
+		raised += r
+		if err != nil {
+			return raised, err
+		}
+		hour = hour.Add(time.Hour)
+		if err := s.alerts.SetCheckpoint(ctx, hour); err != nil {
+			return raised, err
+		}
+	}
+	return raised, nil
+}
+
+// Evaluate checks the hour starting at hour for every link with clicks in
+// its baseline window.
+func (s *AnomalyService) Evaluate(ctx context.Context, hour time.Time) (int, error) {
+	from := hour.Add(-time.Duration(s.cfg.BaselineHours) * time.Hour)
+	to := hour.Add(time.Hour)
+
+	ids, err := s.rollups.ActiveLinks(ctx, from, to)
+	if err != nil {
+		return 0, err
+	}
+
+	raised := 0
+	for start := 0; start < len(ids); start += anomalyBatch {
+		batch := ids[start:min(start+anomalyBatch, len(ids))]
+		rollups, err := s.rollups.FindHourly(ctx, batch, from, to)
+		if err != nil {
+			return raised, err
+		}
+
+		series := make(map[string][]hourClicks, len(batch))
+		for _, id := range batch {
+			series[id] = make([]hourClicks, s.cfg.BaselineHours+1)
+		}
+		for _, r := range rollups {
+			hours, ok := series[r.URLID]
+			i := int(r.Start.Sub(from) / time.Hour)
+			if !ok || i < 0 || i >= len(hours) {
+				continue
+			}
+			hours[i] = hourClicks{human: r.Human, bots: r.Bots}
+		}
+
+		for _, id := range batch {
+			for _, a := range detectAnomalies(series[id], hour, s.cfg) {
+				ok, err := s.raise(ctx, id, a)
+				if err != nil {
+					return raised, err
+				}
+				if ok {
+					raised++
+				}
+			}
+		}
+	}
+	return raised, nil
+}
+
+// Alerts returns the latest alerts of ownerID, newest first.
+func (s *AnomalyService) Alerts(ctx context.Context, ownerID string, limit int) ([]dto.Alert, error) {
+	switch {
+	case limit == 0:
+		limit = 50
+	case limit < 0 || limit > 500:
+		return nil, exceptions.ErrInvalidStatsQuery
+	}
+
+	alerts, err := s.alerts.FindByOwner(ctx, ownerID, limit)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]dto.Alert, 0, len(alerts))
+	for _, a := range alerts {
+		result = append(result, dto.Alert{
+			ID:          a.ID,
+			URLID:       a.URLID,
+			OriginalURL: a.OriginalURL,
+			Kind:        a.Kind,
+			Hour:        a.Hour,
+			Observed:    a.Observed,
+			Baseline:    a.Baseline,
+			CreatedAt:   a.CreatedAt,
+		})
+	}
+	return result, nil
+}
+
+// SetWebhook stores where the alerts of ownerID are posted. The endpoint must
+// be an https URL on a public host; an empty URL removes it. Without a secret
+// one is generated and returned, so the receiver can verify signatures.
+func (s *AnomalyService) SetWebhook(ctx context.Context, ownerID string, input dto.WebhookInput) (*dto.Webhook, error) {
+	raw := strings.TrimSpace(input.URL)
+	if raw == "" {
+		if err := s.users.SetWebhook(ctx, ownerID, "", ""); err != nil {
+			return nil, err
+		}
+		return &dto.Webhook{}, nil
+	}
+	if !validWebhookURL(raw) {
+		return nil, exceptions.ErrInvalidWebhook
+	}
+
+	secret := input.Secret
+	switch {
+	case secret == "":
+		b := make([]byte, 32)
+		_, _ = rand.Read(b)
+		secret = base64.RawURLEncoding.EncodeToString(b)
+	case len(secret) < 16 || len(secret) > 256:
+		return nil, exceptions.ErrInvalidWebhook
+	}
+
+	if err := s.users.SetWebhook(ctx, ownerID, raw, secret); err != nil {
+		return nil, err
+	}
+	return &dto.Webhook{URL: raw, Secret: secret}, nil
+}
+
+// validWebhookURL rejects endpoints that are obviously internal. Hostnames
+// are checked again on the address dialed when the alert is posted.
+func validWebhookURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.User != nil || len(raw) > 2048 {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "" || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if ip := net.ParseIP(host); ip != nil && !linkmeta.IsPublic(ip) {
+		return false
+	}
+	return true
+}
+
+// raise stores and sends an alert, reporting false when it is cooling down
+// or was already raised.
+func (s *AnomalyService) raise(ctx context.Context, urlID string, a anomaly) (bool, error) {
+	last, err := s.alerts.LastHour(ctx, urlID, a.kind)
+	if err != nil {
+		return false, err
+	}
+	if !last.IsZero() {
+		if a.kind == entity.AlertFlat && !last.Before(a.hour) {
+			return false, nil
+		}
+		if a.kind != entity.AlertFlat && a.hour.Sub(last) < s.cfg.Cooldown {
+			return false, nil
+		}
+	}
+
+	url, err := s.urls.FindByID(ctx, urlID)
+	if errors.Is(err, exceptions.ErrURLNotFound) {
+		// Deleted since its clicks were rolled up.
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	alert := entity.Alert{
+		URLID:       urlID,
+		OwnerID:     url.OwnerID,
+		OriginalURL: url.OriginalURL,
+		Kind:        a.kind,
+		Hour:        a.hour,
+		Observed:    a.observed,
+		Baseline:    math.Round(a.baseline*10) / 10,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.alerts.Save(ctx, &alert); err != nil {
+		if errors.Is(err, exceptions.ErrAlertExists) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	s.notify(alert)
+	return true, nil
+}
+
+// notify queues alert for delivery without blocking. The alert stays listed
+// whether or not it is sent.
+func (s *AnomalyService) notify(alert entity.Alert) {
+	if len(s.notifiers) == 0 {
+		return
+	}
+	s.outboxMu.RLock()
+	defer s.outboxMu.RUnlock()
+
+	if s.closed {
+		return
+	}
+	select {
+	case s.outbox <- alert:
+	default:
+		log.Printf("anomaly: notification queue full, %s alert for %s not sent", alert.Kind, alert.URLID)
+	}
+}
+
+// deliver sends the queued alerts through every notifier until Close.
+// Failures are logged.
+func (s *AnomalyService) deliver() {
+	defer close(s.delivered)
+	for alert := range s.outbox {
+		owner, err := s.loadOwner(alert.OwnerID)
+		if err != nil {
+			log.Printf("anomaly: load owner %s: %v", alert.OwnerID, err)
+			owner = nil
+		}
+		for _, n := range s.notifiers {
+			ctx, cancel := context.WithTimeout(context.Background(), s.cfg.NotifyTimeout)
+			if err := n.Notify(ctx, alert, owner); err != nil {
+				log.Printf("anomaly: notify %s alert for %s: %v", alert.Kind, alert.URLID, err)
+			}
+			cancel()
+		}
+	}
+}
+
+func (s *AnomalyService) loadOwner(id string) (*entity.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.NotifyTimeout)
+	defer cancel()
+	return s.users.FindByID(ctx, id)
+}
+
+// Start runs the job every Interval until Close.
+func (s *AnomalyService) Start() {
+	s.started.Store(true)
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Interval)
+			if n, err := s.Run(ctx); err != nil {
+				log.Printf("anomaly: %v", err)
+			} else if n > 0 {
+				log.Printf("anomaly: raised %d alerts", n)
+			}
+			cancel()
+
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Close stops a started job, waiting for the current run to finish, then
+// waits for the queued notifications to be sent or ctx to end.
+func (s *AnomalyService) Close(ctx context.Context) error {
+	s.once.Do(func() { close(s.stop) })
+	if s.started.Load() {
+		select {
+		case <-s.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	s.outboxMu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.outbox)
+	}
+	s.outboxMu.Unlock()
+
+	select {
+	case <-s.delivered:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type hourClicks struct {
+	human int
+	bots  int
+}
+
+type anomaly struct {
+	kind     string
+	hour     time.Time
+	observed int
+	baseline float64
+}
+
+// detectAnomalies looks at the last of hours, which starts at hour, against
+// the ones before it. A flat alert is dated from the first hour without
+// clicks, so one outage maps to one alert however long it lasts.
+func detectAnomalies(hours []hourClicks, hour time.Time, cfg AnomalyConfig) []anomaly {
+	n := len(hours)
+	if n < 2 {
+		return nil
+	}
+	current, baseline := hours[n-1], hours[:n-1]
+	minClicks := float64(cfg.MinClicks)
+	var found []anomaly
+
+	history := false
+	for _, h := range baseline[:max(0, len(baseline)-minAnomalyHistory+1)] {
+		if h.human+h.bots > 0 {
+			history = true
+			break
+		}
+	}
+
+	if history {
+		mean, std := meanStd(baseline, func(h hourClicks) int { return h.human })
+		observed := float64(current.human)
+		if observed >= minClicks && observed >= cfg.SpikeFactor*mean && observed > mean+cfg.SpikeFactor*spread(mean, std) {
+			found = append(found, anomaly{kind: entity.AlertSpike, hour: hour, observed: current.human, baseline: mean})
+		}
+
+		mean, std = meanStd(baseline, func(h hourClicks) int { return h.bots })
+		bots, total := float64(current.bots), float64(current.bots+current.human)
+		if bots >= minClicks && bots >= cfg.BotShare*total && bots > mean+cfg.SpikeFactor*spread(mean, std) {
+			found = append(found, anomaly{kind: entity.AlertBotBurst, hour: hour, observed: current.bots, baseline: mean})
+		}
+	}
+
+	flat := 0
+	for i := n - 1; i >= 0 && hours[i].human == 0; i-- {
+		flat++
+	}
+	if flat >= cfg.FlatHours && flat < n {
+		mean, _ := meanStd(hours[:n-flat], func(h hourClicks) int { return h.human })
+		if mean*float64(cfg.FlatHours) >= minClicks {
+			start := hour.Add(-time.Duration(flat-1) * time.Hour)
+			found = append(found, anomaly{kind: entity.AlertFlat, hour: start, baseline: mean})
+		}
+	}
+	return found
+}
+
+func meanStd(hours []hourClicks, count func(hourClicks) int) (float64, float64) {
+	if len(hours) == 0 {
+		return 0, 0
+	}
+	var sum, sq float64
+	for _, h := range hours {
+		c := float64(count(h))
+		sum += c
+		sq += c * c
+	}
+	mean := sum / float64(len(hours))
+	return mean, math.Sqrt(max(0, sq/float64(len(hours))-mean*mean))
+}
+
+// spread is the deviation used for thresholds: at least the Poisson noise
+// of the mean, and never zero.
+func spread(mean, std float64) float64 {
+	return max(std, math.Sqrt(mean), 1)
+}
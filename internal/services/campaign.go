@@ -0,0 +1,286 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/exceptions"
+	"url-shortener/internal/domain/repository"
+	"url-shortener/internal/services/dto"
+)
+
+// WithCampaigns lets links be grouped into the campaigns stored in repo.
+func WithCampaigns(repo repository.CampaignRepository) URLServiceOption {
+	return func(s *URLService) {
+		s.campaigns = repo
+	}
+}
+
+func (s *URLService) CreateCampaign(ctx context.Context, ownerID string, input dto.CampaignInput) (*dto.Campaign, error) {
+	if s.campaigns == nil {
+		return nil, exceptions.ErrCampaignNotFound
+	}
+
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, exceptions.ErrInvalidCampaign
+	}
+	startsAt, endsAt, err := parseCampaignPeriod(input.StartsAt, input.EndsAt)
+	if err != nil {
+		return nil, exceptions.ErrInvalidCampaign
+	}
+
+	campaign := &entity.Campaign{
+		OwnerID:  ownerID,
+		Name:     name,
+		StartsAt: startsAt,
+		EndsAt:   endsAt,
+		UTM: entity.UTM{
+			Source:   strings.TrimSpace(input.UTM.Source),
+			Medium:   strings.TrimSpace(input.UTM.Medium),
+			Campaign: strings.TrimSpace(input.UTM.Campaign),
+			Term:     strings.TrimSpace(input.UTM.Term),
+			Content:  strings.TrimSpace(input.UTM.Content),
+		},
+		CreatedAt: time.Now(),
+	}
+	if err := s.campaigns.Save(ctx, campaign); err != nil {
+		return nil, err
+	}
+	return toCampaignDTO(campaign), nil
+}
+
+// Campaigns lists the campaigns of ownerID, newest first, including archived
+// ones only when archived is set.
+func (s *URLService) Campaigns(ctx context.Context, ownerID string, archived bool) ([]dto.Campaign, error) {
+	if s.campaigns == nil {
+		return []dto.Campaign{}, nil
+	}
+
+	campaigns, err := s.campaigns.FindByOwner(ctx, ownerID, archived)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]dto.Campaign, 0, len(campaigns))
+	for i := range campaigns {
+		result = append(result, *toCampaignDTO(&campaigns[i]))
+	}
+	return result, nil
+}
+
+// ArchiveCampaign archives a campaign, or restores it when archived is false.
+// Links of an archived campaign keep redirecting and reporting.
+func (s *URLService) ArchiveCampaign(ctx context.Context, id, ownerID string, archived bool) (*dto.Campaign, error) {
+	campaign, err := s.ownedCampaign(ctx, id, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	campaign.ArchivedAt = time.Time{}
+	if archived {
+		campaign.ArchivedAt = time.Now()
+	}
+	if err := s.campaigns.SetArchived(ctx, id, campaign.ArchivedAt); err != nil {
+		return nil, err
+	}
+	return toCampaignDTO(campaign), nil
+}
+
+// CampaignStats aggregates the clicks of the campaign's links like
+// AccountAnalytics, over the campaign period by default.
+func (s *URLService) CampaignStats(ctx context.Context, id, ownerID string, input dto.AccountAnalyticsInput) (*dto.CampaignStats, error) {
+	campaign, err := s.ownedCampaign(ctx, id, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.From == "" && input.To == "" {
+		if !campaign.StartsAt.IsZero() {
+			input.From = campaign.StartsAt.Format(time.RFC3339)
+		}
+		if !campaign.EndsAt.IsZero() {
+			input.To = campaign.EndsAt.Format(time.RFC3339)
+		}
+	}
+
+	analytics, err := s.aggregate(ctx, input, func() ([]entity.URL, error) {
+		return s.repo.FindByCampaign(ctx, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &dto.CampaignStats{Campaign: *toCampaignDTO(campaign), AccountAnalytics: *analytics}, nil
+}
+
+// SetCampaign moves a link into the campaign, adding the campaign's UTM
+// values its destination lacks, or out of any campaign when campaignID is
+// empty. UTM values the previous campaign added are removed first; values the
+// owner changed since are kept.
+func (s *URLService) SetCampaign(ctx context.Context, id, ownerID, campaignID string) (*entity.URL, error) {
+	url, err := s.ownedURL(ctx, id, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	var campaign *entity.Campaign
+	if campaignID != "" {
+		if campaign, err = s.openCampaign(ctx, campaignID, ownerID); err != nil {
+			return nil, err
+		}
+	}
+	if url.CampaignID != "" && url.CampaignID != campaignID {
+		previous, err := s.ownedCampaign(ctx, url.CampaignID, ownerID)
+		switch {
+		case err == nil:
+			url.OriginalURL = withoutUTM(url.OriginalURL, previous.UTM)
+		case !errors.Is(err, exceptions.ErrCampaignNotFound):
+			return nil, err
+		}
+	}
+	if campaign != nil {
+		url.OriginalURL = withUTM(url.OriginalURL, campaign.UTM)
+	}
+	url.CampaignID = campaignID
+
+	// Only the campaign and destination are written, so a concurrent edit
+	// of the link's labels or metadata is not undone.
+	if err := s.redirects.SetCampaign(ctx, url.ID, url.CampaignID, url.OriginalURL); err != nil {
+		return nil, err
+	}
+	s.uncache(url.ID)
+	return url, nil
+}
+
+// parseCampaignPeriod parses the optional bounds of a campaign. The end is
+// stored exclusive, so a bare end date is moved to the following midnight
+// and the campaign runs through that day.
+func parseCampaignPeriod(rawStart, rawEnd string) (startsAt, endsAt time.Time, err error) {
+	if rawStart != "" {
+		if startsAt, err = parseStatsTime(rawStart, time.UTC); err != nil {
+			return
+		}
+	}
+	if rawEnd != "" {
+		if endsAt, err = parseStatsTime(rawEnd, time.UTC); err != nil {
+			return
+		}
+		if _, perr := time.Parse(time.DateOnly, rawEnd); perr == nil {
+			endsAt = endsAt.AddDate(0, 0, 1)
+		}
+	}
+	if !startsAt.IsZero() && !endsAt.IsZero() && !startsAt.Before(endsAt) {
+		err = exceptions.ErrInvalidCampaign
+	}
+	return
+}
+
+func (s *URLService) ownedCampaign(ctx context.Context, id, ownerID string) (*entity.Campaign, error) {
+	if s.campaigns == nil {
+		return nil, exceptions.ErrCampaignNotFound
+	}
+	campaign, err := s.campaigns.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// Campaigns of other owners are reported as missing, not forbidden, so
+	// their IDs cannot be probed.
+	if campaign.OwnerID != ownerID {
+		return nil, exceptions.ErrCampaignNotFound
+	}
+	return campaign, nil
+}
+
+// openCampaign returns a campaign of ownerID that can take new links.
+func (s *URLService) openCampaign(ctx context.Context, id, ownerID string) (*entity.Campaign, error) {
+	campaign, err := s.ownedCampaign(ctx, id, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if !campaign.ArchivedAt.IsZero() {
+		return nil, exceptions.ErrCampaignArchived
+	}
+	return campaign, nil
+}
+
+// withUTM adds the utm_* parameters of utm that destination does not set.
+func withUTM(destination string, utm entity.UTM) string {
+	u, err := url.Parse(destination)
+	if err != nil {
+		return destination
+	}
+	existing := u.Query()
+
+	var params [][2]string
+	for _, p := range [][2]string{
+		{"utm_source", utm.Source},
+		{"utm_medium", utm.Medium},
+		{"utm_campaign", utm.Campaign},
+		{"utm_term", utm.Term},
+		{"utm_content", utm.Content},
+	} {
+		if p[1] != "" && !existing.Has(p[0]) {
+			params = append(params, p)
+		}
+	}
+	return appendQuery(destination, params...)
+}
+
+// withoutUTM removes the UTM parameters of destination that still hold the
+// values in utm, leaving the rest of the query untouched.
+func withoutUTM(destination string, utm entity.UTM) string {
+	u, err := url.Parse(destination)
+	if err != nil || u.RawQuery == "" {
+		return destination
+	}
+	defaults := map[string]string{
+		"utm_source":   utm.Source,
+		"utm_medium":   utm.Medium,
+		"utm_campaign": utm.Campaign,
+		"utm_term":     utm.Term,
+		"utm_content":  utm.Content,
+	}
+
+	var kept []string
+	for _, pair := range strings.Split(u.RawQuery, "&") {
+		k, v, _ := strings.Cut(pair, "=")
+		k, kerr := url.QueryUnescape(k)
+		v, verr := url.QueryUnescape(v)
+		if kerr == nil && verr == nil && v != "" && defaults[k] == v {
+			continue
+		}
+		kept = append(kept, pair)
+	}
+	u.RawQuery = strings.Join(kept, "&")
+	return u.String()
+}
+
+func toCampaignDTO(campaign *entity.Campaign) *dto.Campaign {
+	result := &dto.Campaign{
+		ID:   campaign.ID,
+		Name: campaign.Name,
+		UTM: dto.UTM{
+			Source:   campaign.UTM.Source,
+			Medium:   campaign.UTM.Medium,
+			Campaign: campaign.UTM.Campaign,
+			Term:     campaign.UTM.Term,
+			Content:  campaign.UTM.Content,
+		},
+		CreatedAt: campaign.CreatedAt,
+	}
+	if !campaign.StartsAt.IsZero() {
+		startsAt := campaign.StartsAt
+		result.StartsAt = &startsAt
+	}
+	if !campaign.EndsAt.IsZero() {
+		endsAt := campaign.EndsAt
+		result.EndsAt = &endsAt
+	}
+	if !campaign.ArchivedAt.IsZero() {
+		archivedAt := campaign.ArchivedAt
+		result.ArchivedAt = &archivedAt
+	}
+	return result
+}
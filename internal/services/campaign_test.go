@@ -0,0 +1,241 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/exceptions"
+	"url-shortener/internal/domain/repository"
+	"url-shortener/internal/services"
+	"url-shortener/internal/services/dto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockCampaignRepo struct {
+	mock.Mock
+}
+
+func (m *MockCampaignRepo) Save(ctx context.Context, campaign *entity.Campaign) error {
+	args := m.Called(ctx, campaign)
+	return args.Error(0)
+}
+
+func (m *MockCampaignRepo) FindByID(ctx context.Context, id string) (*entity.Campaign, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) != nil {
+		return args.Get(0).(*entity.Campaign), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockCampaignRepo) FindByOwner(ctx context.Context, ownerID string, archived bool) ([]entity.Campaign, error) {
+	args := m.Called(ctx, ownerID, archived)
+	if args.Get(0) != nil {
+		return args.Get(0).([]entity.Campaign), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockCampaignRepo) SetArchived(ctx context.Context, id string, at time.Time) error {
+	args := m.Called(ctx, id, at)
+	return args.Error(0)
+}
+
+func newCampaignService() (*services.URLService, *MockURLRepo, *MockCampaignRepo, *MockStatsRepo) {
+	urlRepo := new(MockURLRepo)
+	statsRepo := new(MockStatsRepo)
+	campaigns := new(MockCampaignRepo)
+
+	campaigns.On("FindByID", mock.Anything, "spring").Return(&entity.Campaign{
+		ID: "spring", OwnerID: "owner1", Name: "Spring",
+		StartsAt: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+		EndsAt:   time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC),
+		UTM:      entity.UTM{Source: "newsletter", Medium: "email", Campaign: "spring"},
+	}, nil)
+	campaigns.On("FindByID", mock.Anything, "autumn").Return(&entity.Campaign{
+		ID: "autumn", OwnerID: "owner1", Name: "Autumn",
+		EndsAt: time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC),
+		UTM:    entity.UTM{Source: "newsletter", Medium: "social", Campaign: "autumn", Content: "banner"},
+	}, nil).Maybe()
+	campaigns.On("FindByID", mock.Anything, "winter").Return(&entity.Campaign{
+		ID: "winter", OwnerID: "owner1", ArchivedAt: time.Now(),
+	}, nil)
+	campaigns.On("FindByID", mock.Anything, mock.Anything).Return(nil, exceptions.ErrCampaignNotFound)
+
+	idGen := new(MockIDGen)
+	idGen.On("Generate").Return("abc123", nil)
+	svc := services.NewURLService(urlRepo, idGen, statsRepo, services.WithCampaigns(campaigns))
+	return svc, urlRepo, campaigns, statsRepo
+}
+
+func TestURLService_Shorten_InCampaign(t *testing.T) {
+	svc, urlRepo, _, _ := newCampaignService()
+	urlRepo.On("Save", mock.Anything, mock.Anything).Return(nil)
+
+	url, err := svc.Shorten(context.Background(), dto.URL{
+		URL:        "https://shop.example/p?utm_source=ads#top",
+		CampaignID: "spring",
+	}, "owner1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "spring", url.CampaignID)
+	// The link's own utm_source wins over the campaign default.
+	assert.Equal(t, "https://shop.example/p?utm_source=ads&utm_medium=email&utm_campaign=spring#top", url.OriginalURL)
+}
+
+func TestURLService_Shorten_RejectsUnusableCampaigns(t *testing.T) {
+	svc, urlRepo, _, _ := newCampaignService()
+
+	_, err := svc.Shorten(context.Background(), dto.URL{URL: "https://example.com", CampaignID: "winter"}, "owner1")
+	assert.ErrorIs(t, err, exceptions.ErrCampaignArchived)
+
+	_, err = svc.Shorten(context.Background(), dto.URL{URL: "https://example.com", CampaignID: "spring"}, "intruder")
+	assert.ErrorIs(t, err, exceptions.ErrCampaignNotFound)
+
+	_, err = svc.Shorten(context.Background(), dto.URL{URL: "https://example.com", CampaignID: "missing"}, "owner1")
+	assert.ErrorIs(t, err, exceptions.ErrCampaignNotFound)
+	urlRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestURLService_SetCampaign(t *testing.T) {
+	svc, urlRepo, _, _ := newCampaignService()
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{
+		ID: "url1", OwnerID: "owner1", OriginalURL: "https://example.com",
+	}, nil)
+	urlRepo.On("SetCampaign", mock.Anything, "url1", "spring",
+		"https://example.com?utm_source=newsletter&utm_medium=email&utm_campaign=spring").Return(nil).Once()
+
+	_, err := svc.SetCampaign(context.Background(), "url1", "owner1", "spring")
+	assert.NoError(t, err)
+
+	_, err = svc.SetCampaign(context.Background(), "url1", "owner1", "winter")
+	assert.ErrorIs(t, err, exceptions.ErrCampaignArchived)
+
+	_, err = svc.SetCampaign(context.Background(), "url1", "intruder", "spring")
+	assert.ErrorIs(t, err, exceptions.ErrUnauthorizedURLStatistics)
+	urlRepo.AssertNumberOfCalls(t, "SetCampaign", 1)
+}
+
+func TestURLService_SetCampaign_ReplacesPreviousUTM(t *testing.T) {
+	svc, urlRepo, _, _ := newCampaignService()
+
+	// utm_medium was edited by the owner after joining spring, so it stays.
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{
+		ID: "url1", OwnerID: "owner1", CampaignID: "spring",
+		OriginalURL: "https://example.com/p?ref=home&utm_source=newsletter&utm_medium=sms&utm_campaign=spring#top",
+	}, nil)
+	urlRepo.On("SetCampaign", mock.Anything, "url1", mock.Anything, mock.Anything).Return(nil)
+
+	url, err := svc.SetCampaign(context.Background(), "url1", "owner1", "autumn")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "autumn", url.CampaignID)
+	assert.Equal(t, "https://example.com/p?ref=home&utm_medium=sms&utm_source=newsletter&utm_campaign=autumn&utm_content=banner#top", url.OriginalURL)
+
+	url, err = svc.SetCampaign(context.Background(), "url1", "owner1", "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/p?ref=home&utm_medium=sms#top", url.OriginalURL)
+}
+
+func TestURLService_CreateAndArchiveCampaign(t *testing.T) {
+	svc, _, campaigns, _ := newCampaignService()
+
+	campaigns.On("Save", mock.Anything, mock.MatchedBy(func(c *entity.Campaign) bool {
+		return c.OwnerID == "owner1" && c.Name == "Summer" && c.UTM.Source == "ads" &&
+			c.StartsAt.Equal(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC))
+	})).Run(func(args mock.Arguments) {
+		args.Get(1).(*entity.Campaign).ID = "summer"
+	}).Return(nil).Once()
+
+	campaign, err := svc.CreateCampaign(context.Background(), "owner1", dto.CampaignInput{
+		Name: " Summer ", StartsAt: "2025-06-01", UTM: dto.UTM{Source: "ads"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "summer", campaign.ID)
+	assert.Nil(t, campaign.EndsAt)
+
+	// A bare end date includes that day, so a campaign can run for one day.
+	campaigns.On("Save", mock.Anything, mock.MatchedBy(func(c *entity.Campaign) bool {
+		return c.Name == "Flash" &&
+			c.StartsAt.Equal(time.Date(2025, 7, 4, 0, 0, 0, 0, time.UTC)) &&
+			c.EndsAt.Equal(time.Date(2025, 7, 5, 0, 0, 0, 0, time.UTC))
+	})).Return(nil).Once()
+	_, err = svc.CreateCampaign(context.Background(), "owner1", dto.CampaignInput{
+		Name: "Flash", StartsAt: "2025-07-04", EndsAt: "2025-07-04",
+	})
+	assert.NoError(t, err)
+
+	for _, input := range []dto.CampaignInput{
+		{Name: " "},
+		{Name: "Bad", StartsAt: "June"},
+		{Name: "Backwards", StartsAt: "2025-06-02", EndsAt: "2025-06-01"},
+		{Name: "Empty", StartsAt: "2025-06-02T10:00:00Z", EndsAt: "2025-06-02T10:00:00Z"},
+	} {
+		_, err := svc.CreateCampaign(context.Background(), "owner1", input)
+		assert.ErrorIs(t, err, exceptions.ErrInvalidCampaign, input.Name)
+	}
+
+	campaigns.On("SetArchived", mock.Anything, "spring", mock.MatchedBy(func(at time.Time) bool {
+		return !at.IsZero()
+	})).Return(nil).Once()
+	campaigns.On("SetArchived", mock.Anything, "winter", time.Time{}).Return(nil).Once()
+
+	archived, err := svc.ArchiveCampaign(context.Background(), "spring", "owner1", true)
+	assert.NoError(t, err)
+	assert.NotNil(t, archived.ArchivedAt)
+
+	restored, err := svc.ArchiveCampaign(context.Background(), "winter", "owner1", false)
+	assert.NoError(t, err)
+	assert.Nil(t, restored.ArchivedAt)
+
+	_, err = svc.ArchiveCampaign(context.Background(), "spring", "intruder", true)
+	assert.ErrorIs(t, err, exceptions.ErrCampaignNotFound)
+	campaigns.AssertExpectations(t)
+}
+
+func TestURLService_CampaignStats_DefaultsToCampaignPeriod(t *testing.T) {
+	svc, urlRepo, _, statsRepo := newCampaignService()
+
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	urlRepo.On("FindByCampaign", mock.Anything, "spring").Return([]entity.URL{
+		{ID: "url1", OriginalURL: "https://a.example"},
+		{ID: "url2", OriginalURL: "https://b.example"},
+	}, nil)
+	statsRepo.On("TimeSeries", mock.Anything, mock.MatchedBy(func(q repository.TimeSeriesQuery) bool {
+		return assert.ObjectsAreEqual([]string{"url1", "url2"}, q.URLIDs) && q.From.Equal(from) && q.To.Equal(to)
+	})).Return([]entity.ClickBucket{{Start: from, Clicks: 5}}, nil)
+	statsRepo.On("Breakdown", mock.Anything, mock.Anything).Return([]entity.BreakdownRow{{Key: "url1", Clicks: 5}}, 5, nil)
+
+	stats, err := svc.CampaignStats(context.Background(), "spring", "owner1", dto.AccountAnalyticsInput{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Spring", stats.Campaign.Name)
+	assert.Equal(t, 2, stats.Links)
+	assert.Equal(t, 5, stats.Total)
+
+	_, err = svc.CampaignStats(context.Background(), "spring", "intruder", dto.AccountAnalyticsInput{})
+	assert.ErrorIs(t, err, exceptions.ErrCampaignNotFound)
+}
+
+func TestURLService_CampaignStats_EndWithoutStart(t *testing.T) {
+	svc, urlRepo, _, statsRepo := newCampaignService()
+
+	// autumn has an end but no start.
+	to := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+	urlRepo.On("FindByCampaign", mock.Anything, "autumn").Return([]entity.URL{{ID: "url1"}}, nil)
+	statsRepo.On("TimeSeries", mock.Anything, mock.MatchedBy(func(q repository.TimeSeriesQuery) bool {
+		return q.To.Equal(to)
+	})).Return([]entity.ClickBucket{}, nil)
+	statsRepo.On("Breakdown", mock.Anything, mock.Anything).Return([]entity.BreakdownRow{}, 0, nil)
+
+	stats, err := svc.CampaignStats(context.Background(), "autumn", "owner1", dto.AccountAnalyticsInput{})
+
+	assert.NoError(t, err)
+	assert.True(t, stats.To.Equal(to))
+}
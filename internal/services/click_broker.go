@@ -0,0 +1,213 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/services/dto"
+)
+
+var (
+	ErrTooManySubscribers = errors.New("too many live subscribers")
+	ErrClickBrokerClosed  = errors.New("click broker closed")
+)
+
+type ClickBrokerConfig struct {
+	// MaxSubscribers caps concurrent subscriptions across all links.
+	MaxSubscribers int
+	// History is how many recent events per link are kept for clients
+	// reconnecting with Last-Event-ID.
+	History int
+	// Retention is how long the history of a link outlives its last
+	// subscriber.
+	Retention time.Duration
+	// Buffer is the per-subscriber queue; events for a subscriber that falls
+	// further behind are dropped.
+	Buffer int
+}
+
+// LiveEvent is a click as pushed to live subscribers. IDs increase across the
+// whole broker, so a client can resume from the last ID it saw.
+type LiveEvent struct {
+	ID    uint64
+	Click dto.LiveClick
+}
+
+type Subscription struct {
+	Events <-chan LiveEvent
+
+	broker *ClickBroker
+	urlID  string
+	events chan LiveEvent
+	once   sync.Once
+}
+
+// Close unsubscribes. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.once.Do(func() { s.broker.unsubscribe(s) })
+}
+
+type liveLink struct {
+	subs    map[*Subscription]struct{}
+	history []LiveEvent
+	idle    time.Time
+}
+
+// ClickBroker fans clicks out from the redirect path to live subscribers in
+// this process. Publishing never blocks: links nobody watches are ignored and
+// slow subscribers miss events rather than holding up redirects.
+type ClickBroker struct {
+	cfg ClickBrokerConfig
+
+	mu     sync.Mutex
+	links  map[string]*liveLink
+	count  int
+	lastID uint64
+	closed bool
+}
+
+func NewClickBroker(cfg ClickBrokerConfig) *ClickBroker {
+	if cfg.MaxSubscribers <= 0 {
+		cfg.MaxSubscribers = 100
+	}
+	if cfg.History <= 0 {
+		cfg.History = 100
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = time.Minute
+	}
+	if cfg.Buffer <= 0 {
+		cfg.Buffer = 64
+	}
+	return &ClickBroker{cfg: cfg, links: make(map[string]*liveLink)}
+}
+
+func (b *ClickBroker) Publish(stat *entity.URLStat) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	link, ok := b.links[stat.URLID]
+	if !ok || b.closed {
+		return
+	}
+
+	b.lastID++
+	event := LiveEvent{ID: b.lastID, Click: liveClick(stat)}
+
+	link.history = append(link.history, event)
+	if len(link.history) > b.cfg.History {
+		link.history = link.history[len(link.history)-b.cfg.History:]
+	}
+
+	for sub := range link.subs {
+		select {
+		case sub.events <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe starts watching urlID. The returned backlog holds the retained
+// events after lastEventID, oldest first; pass 0 for a fresh connection.
+func (b *ClickBroker) Subscribe(urlID string, lastEventID uint64) (*Subscription, []LiveEvent, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, nil, ErrClickBrokerClosed
+	}
+	if b.count >= b.cfg.MaxSubscribers {
+		return nil, nil, ErrTooManySubscribers
+	}
+	b.sweep(time.Now())
+
+	link, ok := b.links[urlID]
+	if !ok {
+		link = &liveLink{subs: make(map[*Subscription]struct{})}
+		b.links[urlID] = link
+	}
+
+	var backlog []LiveEvent
+	if lastEventID > 0 {
+		for _, event := range link.history {
+			if event.ID > lastEventID {
+				backlog = append(backlog, event)
+			}
+		}
+	}
+
+	events := make(chan LiveEvent, b.cfg.Buffer)
+	sub := &Subscription{Events: events, broker: b, urlID: urlID, events: events}
+	link.subs[sub] = struct{}{}
+	b.count++
+
+	return sub, backlog, nil
+}
+
+// Close ends every subscription, closing their Events channels, and rejects
+// new ones. Call it when the server starts shutting down so that streaming
+// requests finish.
+func (b *ClickBroker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for _, link := range b.links {
+		for sub := range link.subs {
+			close(sub.events)
+		}
+		link.subs = nil
+	}
+	b.links = make(map[string]*liveLink)
+	b.count = 0
+}
+
+func (b *ClickBroker) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.count
+}
+
+func (b *ClickBroker) unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	link, ok := b.links[sub.urlID]
+	if !ok {
+		return
+	}
+	if _, ok := link.subs[sub]; !ok {
+		return
+	}
+	delete(link.subs, sub)
+	b.count--
+	if len(link.subs) == 0 {
+		link.idle = time.Now()
+	}
+}
+
+// sweep forgets the history of links that have had no subscriber for longer
+// than the retention.
+func (b *ClickBroker) sweep(now time.Time) {
+	for id, link := range b.links {
+		if len(link.subs) == 0 && now.Sub(link.idle) > b.cfg.Retention {
+			delete(b.links, id)
+		}
+	}
+}
+
+func liveClick(stat *entity.URLStat) dto.LiveClick {
+	return dto.LiveClick{
+		URLID:         stat.URLID,
+		ClickedAt:     stat.ClickedAt,
+		Class:         stat.Class,
+		RefererHost:   stat.RefererHost,
+		RefererSource: stat.RefererSource,
+		Browser:       stat.Browser,
+		OS:            stat.OS,
+		Device:        stat.Device,
+		Country:       stat.Country,
+		City:          stat.City,
+	}
+}
@@ -0,0 +1,102 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/services"
+	"url-shortener/internal/services/dto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestClickBroker_FansOutPerLink(t *testing.T) {
+	broker := services.NewClickBroker(services.ClickBrokerConfig{})
+
+	a, _, err := broker.Subscribe("url1", 0)
+	assert.NoError(t, err)
+	b, _, err := broker.Subscribe("url2", 0)
+	assert.NoError(t, err)
+
+	broker.Publish(&entity.URLStat{URLID: "url1", Country: "BR"})
+
+	event := <-a.Events
+	assert.Equal(t, uint64(1), event.ID)
+	assert.Equal(t, "BR", event.Click.Country)
+	assert.Len(t, b.Events, 0)
+}
+
+func TestClickBroker_ReplaysAfterLastEventID(t *testing.T) {
+	broker := services.NewClickBroker(services.ClickBrokerConfig{History: 2})
+
+	first, _, _ := broker.Subscribe("url1", 0)
+	for i := 0; i < 3; i++ {
+		broker.Publish(&entity.URLStat{URLID: "url1"})
+	}
+	first.Close()
+
+	_, backlog, err := broker.Subscribe("url1", 2)
+	assert.NoError(t, err)
+	assert.Len(t, backlog, 1)
+	assert.Equal(t, uint64(3), backlog[0].ID)
+
+	// Only the two most recent events are retained.
+	_, backlog, _ = broker.Subscribe("url1", 1)
+	assert.Equal(t, []uint64{2, 3}, []uint64{backlog[0].ID, backlog[1].ID})
+}
+
+func TestClickBroker_CapsSubscribers(t *testing.T) {
+	broker := services.NewClickBroker(services.ClickBrokerConfig{MaxSubscribers: 1})
+
+	sub, _, err := broker.Subscribe("url1", 0)
+	assert.NoError(t, err)
+	_, _, err = broker.Subscribe("url2", 0)
+	assert.ErrorIs(t, err, services.ErrTooManySubscribers)
+
+	sub.Close()
+	sub.Close()
+	_, _, err = broker.Subscribe("url2", 0)
+	assert.NoError(t, err)
+}
+
+func TestClickBroker_SlowSubscriberDoesNotBlock(t *testing.T) {
+	broker := services.NewClickBroker(services.ClickBrokerConfig{Buffer: 1})
+	sub, _, _ := broker.Subscribe("url1", 0)
+
+	for i := 0; i < 10; i++ {
+		broker.Publish(&entity.URLStat{URLID: "url1"})
+	}
+
+	assert.Len(t, sub.Events, 1)
+}
+
+func TestClickBroker_CloseEndsSubscriptions(t *testing.T) {
+	broker := services.NewClickBroker(services.ClickBrokerConfig{})
+	sub, _, _ := broker.Subscribe("url1", 0)
+
+	broker.Close()
+
+	_, ok := <-sub.Events
+	assert.False(t, ok)
+	_, _, err := broker.Subscribe("url1", 0)
+	assert.ErrorIs(t, err, services.ErrClickBrokerClosed)
+}
+
+func TestURLService_Resolve_PublishesLiveClick(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	broker := services.NewClickBroker(services.ClickBrokerConfig{})
+	svc := services.NewURLService(urlRepo, new(MockIDGen), new(MockStatsRepo),
+		services.WithClickRecorder(&captureRecorder{}), services.WithLiveClicks(broker))
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{ID: "url1", OwnerID: "owner1"}, nil)
+
+	sub, _, err := svc.Live(context.Background(), "url1", "owner1", "")
+	assert.NoError(t, err)
+
+	_, err = svc.Resolve(context.Background(), "url1", dto.Visit{IP: "1.2.3.4", Referer: "https://t.co/x"})
+	assert.NoError(t, err)
+
+	event := <-sub.Events
+	assert.Equal(t, "t.co", event.Click.RefererHost)
+}
@@ -0,0 +1,275 @@
+package services
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/exceptions"
+	"url-shortener/internal/domain/repository"
+	"url-shortener/internal/services/dto"
+)
+
+// exportFlushRows is how often encoders hand buffered rows to the writer, so
+// large exports reach the client while the cursor is still open.
+const exportFlushRows = 500
+
+type exportColumn struct {
+	name  string
+	value func(s *entity.URLStat) any
+}
+
+// exportColumns are the columns an export may select, in default order.
+var exportColumns = []exportColumn{
+	{"id", func(s *entity.URLStat) any { return s.ID }},
+	{"url_id", func(s *entity.URLStat) any { return s.URLID }},
+	{"clicked_at", func(s *entity.URLStat) any { return s.ClickedAt.UTC() }},
+	{"ip", func(s *entity.URLStat) any { return s.IP }},
+	{"user_agent", func(s *entity.URLStat) any { return s.UserAgent }},
+	{"referer", func(s *entity.URLStat) any { return s.Referer }},
+	{"referer_host", func(s *entity.URLStat) any { return s.RefererHost }},
+	{"referer_source", func(s *entity.URLStat) any { return s.RefererSource }},
+	{"browser", func(s *entity.URLStat) any { return s.Browser }},
+	{"browser_version", func(s *entity.URLStat) any { return s.BrowserVersion }},
+	{"os", func(s *entity.URLStat) any { return s.OS }},
+	{"os_version", func(s *entity.URLStat) any { return s.OSVersion }},
+	{"device", func(s *entity.URLStat) any { return s.Device }},
+	{"class", func(s *entity.URLStat) any { return s.Class }},
+	{"country", func(s *entity.URLStat) any { return s.Country }},
+	{"region", func(s *entity.URLStat) any { return s.Region }},
+	{"city", func(s *entity.URLStat) any { return s.City }},
+	{"lat", func(s *entity.URLStat) any { return s.Latitude }},
+	{"lon", func(s *entity.URLStat) any { return s.Longitude }},
+	{"click_id", func(s *entity.URLStat) any { return s.ClickID }},
+}
+
+var exportContentTypes = map[string]string{
+	"csv":    "text/csv; charset=utf-8",
+	"ndjson": "application/x-ndjson",
+}
+
+// ClickExport is a validated export, ready to be streamed once the caller has
+// sent its headers.
+type ClickExport struct {
+	Filename    string
+	ContentType string
+	write       func(ctx context.Context, w io.Writer) error
+}
+
+func (e *ClickExport) Write(ctx context.Context, w io.Writer) error {
+	return e.write(ctx, w)
+}
+
+type exportOptions struct {
+	format  string
+	columns []exportColumn
+	from    time.Time
+	to      time.Time
+}
+
+// Export streams every click of a link, bots included, as CSV or NDJSON.
+func (s *URLService) Export(ctx context.Context, id, ownerID string, input dto.ExportInput) (*ClickExport, error) {
+	if _, err := s.ownedURL(ctx, id, ownerID); err != nil {
+		return nil, err
+	}
+	opts, err := parseExportInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ClickExport{
+		Filename:    id + "." + opts.format,
+		ContentType: exportContentTypes[opts.format],
+		write: func(ctx context.Context, w io.Writer) error {
+			return s.streamClicks(ctx, w, id, opts)
+		},
+	}, nil
+}
+
+// ExportAll streams the clicks of every link of ownerID as a zip archive with
+// one file per link.
+func (s *URLService) ExportAll(ctx context.Context, ownerID string, input dto.ExportInput) (*ClickExport, error) {
+	opts, err := parseExportInput(input)
+	if err != nil {
+		return nil, err
+	}
+	urls, err := s.repo.FindByOwner(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ClickExport{
+		Filename:    "clicks-" + opts.format + ".zip",
+		ContentType: "application/zip",
+		write: func(ctx context.Context, w io.Writer) error {
+			archive := zip.NewWriter(w)
+			for _, url := range urls {
+				f, err := archive.CreateHeader(&zip.FileHeader{
+					Name:     url.ID + "." + opts.format,
+					Method:   zip.Deflate,
+					Modified: time.Now(),
+				})
+				if err != nil {
+					return err
+				}
+				if err := s.streamClicks(ctx, f, url.ID, opts); err != nil {
+					return err
+				}
+			}
+			return archive.Close()
+		},
+	}, nil
+}
+
+func (s *URLService) streamClicks(ctx context.Context, w io.Writer, id string, opts exportOptions) error {
+	enc := newRowEncoder(opts.format, w, opts.columns)
+	if err := enc.header(); err != nil {
+		return err
+	}
+
+	rows := 0
+	err := s.statsRepo.Stream(ctx, repository.ExportQuery{URLID: id, From: opts.from, To: opts.to}, func(stat *entity.URLStat) error {
+		if err := enc.row(stat); err != nil {
+			return err
+		}
+		if rows++; rows%exportFlushRows == 0 {
+			return enc.flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return enc.flush()
+}
+
+func parseExportInput(input dto.ExportInput) (exportOptions, error) {
+	opts := exportOptions{format: input.Format, columns: exportColumns}
+	if opts.format == "" {
+		opts.format = "csv"
+	}
+	if _, ok := exportContentTypes[opts.format]; !ok {
+		return opts, exceptions.ErrInvalidStatsQuery
+	}
+
+	if input.Columns != "" {
+		opts.columns = nil
+		for _, name := range strings.Split(input.Columns, ",") {
+			column, ok := findExportColumn(strings.TrimSpace(name))
+			if !ok {
+				return opts, exceptions.ErrInvalidStatsQuery
+			}
+			opts.columns = append(opts.columns, column)
+		}
+	}
+
+	var err error
+	opts.from, opts.to, err = parseStatsRange(input.From, input.To)
+	return opts, err
+}
+
+func findExportColumn(name string) (exportColumn, bool) {
+	for _, column := range exportColumns {
+		if column.name == name {
+			return column, true
+		}
+	}
+	return exportColumn{}, false
+}
+
+type rowEncoder interface {
+	header() error
+	row(stat *entity.URLStat) error
+	flush() error
+}
+
+func newRowEncoder(format string, w io.Writer, columns []exportColumn) rowEncoder {
+	if format == "ndjson" {
+		return &ndjsonEncoder{w: bufio.NewWriter(w), columns: columns}
+	}
+	return &csvEncoder{w: csv.NewWriter(w), columns: columns, record: make([]string, len(columns))}
+}
+
+type csvEncoder struct {
+	w       *csv.Writer
+	columns []exportColumn
+	record  []string
+}
+
+func (e *csvEncoder) header() error {
+	for i, column := range e.columns {
+		e.record[i] = column.name
+	}
+	return e.w.Write(e.record)
+}
+
+func (e *csvEncoder) row(stat *entity.URLStat) error {
+	for i, column := range e.columns {
+		switch v := column.value(stat).(type) {
+		case string:
+			e.record[i] = escapeFormula(v)
+		case time.Time:
+			e.record[i] = v.Format(time.RFC3339Nano)
+		case float64:
+			e.record[i] = ""
+			if v != 0 {
+				e.record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+			}
+		}
+	}
+	return e.w.Write(e.record)
+}
+
+func (e *csvEncoder) flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// escapeFormula keeps spreadsheets from evaluating a cell as a formula. User
+// agents and referers are sent by visitors, so a click could otherwise plant
+// one in the owner's export.
+func escapeFormula(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
+
+// ndjsonEncoder writes one object per line, keeping the selected column order.
+type ndjsonEncoder struct {
+	w       *bufio.Writer
+	columns []exportColumn
+}
+
+func (e *ndjsonEncoder) header() error {
+	return nil
+}
+
+func (e *ndjsonEncoder) row(stat *entity.URLStat) error {
+	e.w.WriteByte('{')
+	for i, column := range e.columns {
+		if i > 0 {
+			e.w.WriteByte(',')
+		}
+		name, _ := json.Marshal(column.name)
+		value, err := json.Marshal(column.value(stat))
+		if err != nil {
+			return err
+		}
+		e.w.Write(name)
+		e.w.WriteByte(':')
+		e.w.Write(value)
+	}
+	_, err := e.w.WriteString("}\n")
+	return err
+}
+
+func (e *ndjsonEncoder) flush() error {
+	return e.w.Flush()
+}
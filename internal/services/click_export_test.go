@@ -0,0 +1,134 @@
+package services_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/exceptions"
+	"url-shortener/internal/domain/repository"
+	"url-shortener/internal/services"
+	"url-shortener/internal/services/dto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var exportedClicks = []entity.URLStat{
+	{ID: "s1", URLID: "url1", ClickedAt: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC), Referer: "https://t.co/x", Country: "BR", Latitude: -23.5},
+	{ID: "s2", URLID: "url1", ClickedAt: time.Date(2025, 3, 2, 8, 30, 0, 0, time.UTC), UserAgent: `Quote "me", please`},
+}
+
+func TestURLService_Export_CSVColumns(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	statsRepo := new(MockStatsRepo)
+	svc := services.NewURLService(urlRepo, new(MockIDGen), statsRepo)
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{ID: "url1", OwnerID: "owner1"}, nil)
+	statsRepo.On("Stream", mock.Anything, repository.ExportQuery{
+		URLID: "url1",
+		From:  time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+	}).Return(exportedClicks, nil)
+
+	export, err := svc.Export(context.Background(), "url1", "owner1", dto.ExportInput{
+		From:    "2025-03-01",
+		Columns: "clicked_at, user_agent,lat",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "url1.csv", export.Filename)
+
+	var out bytes.Buffer
+	assert.NoError(t, export.Write(context.Background(), &out))
+	assert.Equal(t, "clicked_at,user_agent,lat\n"+
+		"2025-03-01T12:00:00Z,,-23.5\n"+
+		`2025-03-02T08:30:00Z,"Quote ""me"", please",`+"\n", out.String())
+}
+
+func TestURLService_Export_NDJSONKeepsColumnOrder(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	statsRepo := new(MockStatsRepo)
+	svc := services.NewURLService(urlRepo, new(MockIDGen), statsRepo)
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{ID: "url1", OwnerID: "owner1"}, nil)
+	statsRepo.On("Stream", mock.Anything, repository.ExportQuery{URLID: "url1"}).Return(exportedClicks[:1], nil)
+
+	export, err := svc.Export(context.Background(), "url1", "owner1", dto.ExportInput{Format: "ndjson", Columns: "id,country,clicked_at"})
+	assert.NoError(t, err)
+
+	var out bytes.Buffer
+	assert.NoError(t, export.Write(context.Background(), &out))
+	assert.Equal(t, `{"id":"s1","country":"BR","clicked_at":"2025-03-01T12:00:00Z"}`+"\n", out.String())
+}
+
+func TestURLService_Export_EscapesFormulasInCSVOnly(t *testing.T) {
+	clicks := []entity.URLStat{
+		{ID: "s1", UserAgent: "=HYPERLINK(\"https://evil.example\")", Referer: "@SUM(A1)"},
+		{ID: "s2", UserAgent: "+cmd", Referer: "-2+3"},
+	}
+
+	for format, want := range map[string]string{
+		"csv": "user_agent,referer\n" +
+			`"'=HYPERLINK(""https://evil.example"")",'@SUM(A1)` + "\n" +
+			"'+cmd,'-2+3\n",
+		"ndjson": `{"user_agent":"=HYPERLINK(\"https://evil.example\")","referer":"@SUM(A1)"}` + "\n" +
+			`{"user_agent":"+cmd","referer":"-2+3"}` + "\n",
+	} {
+		urlRepo := new(MockURLRepo)
+		statsRepo := new(MockStatsRepo)
+		svc := services.NewURLService(urlRepo, new(MockIDGen), statsRepo)
+
+		urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{ID: "url1", OwnerID: "owner1"}, nil)
+		statsRepo.On("Stream", mock.Anything, repository.ExportQuery{URLID: "url1"}).Return(clicks, nil)
+
+		export, err := svc.Export(context.Background(), "url1", "owner1", dto.ExportInput{Format: format, Columns: "user_agent,referer"})
+		assert.NoError(t, err)
+
+		var out bytes.Buffer
+		assert.NoError(t, export.Write(context.Background(), &out))
+		assert.Equal(t, want, out.String(), format)
+	}
+}
+
+func TestURLService_Export_InvalidInput(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	svc := services.NewURLService(urlRepo, new(MockIDGen), new(MockStatsRepo))
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{ID: "url1", OwnerID: "owner1"}, nil)
+
+	_, err := svc.Export(context.Background(), "url1", "owner1", dto.ExportInput{Format: "xlsx"})
+	assert.ErrorIs(t, err, exceptions.ErrInvalidStatsQuery)
+	_, err = svc.Export(context.Background(), "url1", "owner1", dto.ExportInput{Columns: "id,password"})
+	assert.ErrorIs(t, err, exceptions.ErrInvalidStatsQuery)
+	_, err = svc.Export(context.Background(), "url1", "intruder", dto.ExportInput{})
+	assert.ErrorIs(t, err, exceptions.ErrUnauthorizedURLStatistics)
+}
+
+func TestURLService_ExportAll_OneFilePerLink(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	statsRepo := new(MockStatsRepo)
+	svc := services.NewURLService(urlRepo, new(MockIDGen), statsRepo)
+
+	urlRepo.On("FindByOwner", mock.Anything, "owner1").Return([]entity.URL{{ID: "url1"}, {ID: "url2"}}, nil)
+	statsRepo.On("Stream", mock.Anything, repository.ExportQuery{URLID: "url1"}).Return(exportedClicks, nil)
+	statsRepo.On("Stream", mock.Anything, repository.ExportQuery{URLID: "url2"}).Return(nil, nil)
+
+	export, err := svc.ExportAll(context.Background(), "owner1", dto.ExportInput{Format: "ndjson", Columns: "id"})
+	assert.NoError(t, err)
+	assert.Equal(t, "application/zip", export.ContentType)
+
+	var out bytes.Buffer
+	assert.NoError(t, export.Write(context.Background(), &out))
+
+	archive, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	assert.NoError(t, err)
+	assert.Len(t, archive.File, 2)
+
+	f, err := archive.File[0].Open()
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(f)
+	assert.Equal(t, "url1.ndjson", archive.File[0].Name)
+	assert.Equal(t, "{\"id\":\"s1\"}\n{\"id\":\"s2\"}\n", string(body))
+}
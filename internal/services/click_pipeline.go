@@ -0,0 +1,290 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/repository"
+	"url-shortener/pkg/botdetect"
+)
+
+var ErrClickPipelineClosed = errors.New("click pipeline closed")
+
+// ClickRecorder persists clicks captured on the redirect path. Implementations
+// must not make the redirect wait on, or fail because of, analytics writes.
+type ClickRecorder interface {
+	Record(ctx context.Context, stat *entity.URLStat)
+}
+
+// syncClickRecorder writes each click inline. It is the fallback when no
+// pipeline is configured; write failures are logged and never surface to the
+// visitor. It does not track unique visitors or the bot and tracked click
+// counters.
+type syncClickRecorder struct {
+	repo      repository.URLRepository
+	statsRepo repository.URLStatsRepository
+}
+
+func (r *syncClickRecorder) Record(ctx context.Context, stat *entity.URLStat) {
+	if isHuman(stat) {
+		if err := r.repo.IncrementClick(ctx, stat.URLID); err != nil {
+			log.Printf("click: increment %s: %v", stat.URLID, err)
+		}
+	}
+	if err := r.statsRepo.Save(ctx, stat); err != nil {
+		log.Printf("click: save stat for %s: %v", stat.URLID, err)
+	}
+}
+
+type ClickPipelineConfig struct {
+	QueueSize      int
+	Workers        int
+	BatchSize      int
+	FlushInterval  time.Duration
+	EnqueueTimeout time.Duration
+	WriteTimeout   time.Duration
+
+	// Transactor, when set, writes each batch's raw clicks and counter
+	// increments atomically so the two can never drift apart.
+	Transactor repository.Transactor
+
+	// Visitors, when set, receives the unique visitor sketch updates of
+	// every written batch.
+	Visitors repository.UniqueVisitorRepository
+}
+
+// ClickPipelineStats is a point-in-time snapshot of the pipeline counters.
+type ClickPipelineStats struct {
+	Enqueued uint64 `json:"enqueued"`
+	Dropped  uint64 `json:"dropped"`
+	Written  uint64 `json:"written"`
+	Failed   uint64 `json:"failed"`
+	Batches  uint64 `json:"batches"`
+	Queued   int    `json:"queued"`
+}
+
+// ClickPipeline records clicks through a bounded queue drained by a pool of
+// workers. Each worker batches raw click inserts and coalesces counter
+// increments per link before writing.
+//
+// When the queue is full Record waits up to EnqueueTimeout for room and then
+// drops the click, so a slow database degrades analytics instead of
+// redirects.
+type ClickPipeline struct {
+	repo      repository.URLRepository
+	statsRepo repository.URLStatsRepository
+	cfg       ClickPipelineConfig
+
+	queue  chan *entity.URLStat
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+
+	enqueued atomic.Uint64
+	dropped  atomic.Uint64
+	written  atomic.Uint64
+	failed   atomic.Uint64
+	batches  atomic.Uint64
+}
+
+func NewClickPipeline(repo repository.URLRepository, statsRepo repository.URLStatsRepository, cfg ClickPipelineConfig) *ClickPipeline {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 10000
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 2
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = 10 * time.Second
+	}
+
+	p := &ClickPipeline{
+		repo:      repo,
+		statsRepo: statsRepo,
+		cfg:       cfg,
+		queue:     make(chan *entity.URLStat, cfg.QueueSize),
+	}
+
+	for i := 0; i < cfg.Workers; i++ {
+		p.wg.Add(1)
+		go p.work()
+	}
+
+	return p
+}
+
+func (p *ClickPipeline) Record(_ context.Context, stat *entity.URLStat) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		p.dropped.Add(1)
+		return
+	}
+
+	select {
+	case p.queue <- stat:
+		p.enqueued.Add(1)
+		return
+	default:
+	}
+
+	if p.cfg.EnqueueTimeout > 0 {
+		timer := time.NewTimer(p.cfg.EnqueueTimeout)
+		defer timer.Stop()
+		select {
+		case p.queue <- stat:
+			p.enqueued.Add(1)
+			return
+		case <-timer.C:
+		}
+	}
+
+	p.dropped.Add(1)
+}
+
+// Close stops accepting clicks and waits for the workers to flush everything
+// already queued, or for ctx to expire.
+func (p *ClickPipeline) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrClickPipelineClosed
+	}
+	p.closed = true
+	close(p.queue)
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *ClickPipeline) Stats() ClickPipelineStats {
+	return ClickPipelineStats{
+		Enqueued: p.enqueued.Load(),
+		Dropped:  p.dropped.Load(),
+		Written:  p.written.Load(),
+		Failed:   p.failed.Load(),
+		Batches:  p.batches.Load(),
+		Queued:   len(p.queue),
+	}
+}
+
+func (p *ClickPipeline) work() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*entity.URLStat, 0, p.cfg.BatchSize)
+	for {
+		select {
+		case stat, ok := <-p.queue:
+			if !ok {
+				p.flush(batch)
+				return
+			}
+			batch = append(batch, stat)
+			if len(batch) >= p.cfg.BatchSize {
+				p.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			p.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (p *ClickPipeline) flush(batch []*entity.URLStat) {
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.cfg.WriteTimeout)
+	defer cancel()
+
+	p.batches.Add(1)
+
+	var err error
+	if p.cfg.Transactor != nil {
+		err = p.cfg.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			return p.write(ctx, batch)
+		})
+	} else {
+		err = p.write(ctx, batch)
+	}
+	if err != nil {
+		log.Printf("click pipeline: write %d clicks: %v", len(batch), err)
+		p.failed.Add(uint64(len(batch)))
+		return
+	}
+
+	p.written.Add(uint64(len(batch)))
+
+	if p.cfg.Visitors != nil {
+		if err := p.cfg.Visitors.Observe(ctx, visitorObservations(batch)); err != nil {
+			log.Printf("click pipeline: observe unique visitors: %v", err)
+		}
+	}
+}
+
+func (p *ClickPipeline) write(ctx context.Context, batch []*entity.URLStat) error {
+	if err := p.statsRepo.SaveMany(ctx, batch); err != nil {
+		return err
+	}
+	return p.repo.IncrementClicks(ctx, coalesceClicks(batch))
+}
+
+func coalesceClicks(batch []*entity.URLStat) []repository.ClickIncrement {
+	index := make(map[string]int)
+	var increments []repository.ClickIncrement
+
+	for _, stat := range batch {
+		i, ok := index[stat.URLID]
+		if !ok {
+			index[stat.URLID] = len(increments)
+			increments = append(increments, repository.ClickIncrement{URLID: stat.URLID})
+			i = len(increments) - 1
+		}
+		if isHuman(stat) {
+			increments[i].Count++
+			if stat.ClickID != "" {
+				increments[i].Tracked++
+			}
+		} else {
+			increments[i].Bots++
+		}
+		if stat.ClickedAt.After(increments[i].LastClick) {
+			increments[i].LastClick = stat.ClickedAt
+		}
+	}
+
+	return increments
+}
+
+// isHuman treats unclassified clicks as human, matching how stored clicks
+// that predate classification are counted.
+func isHuman(stat *entity.URLStat) bool {
+	return stat.Class == "" || stat.Class == botdetect.ClassHuman
+}
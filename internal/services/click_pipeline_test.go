@@ -0,0 +1,166 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/repository"
+	"url-shortener/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestClickPipeline_BatchesAndCoalesces(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	statsRepo := new(MockStatsRepo)
+
+	var saved []*entity.URLStat
+	var increments []repository.ClickIncrement
+	statsRepo.On("SaveMany", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		saved = append(saved, args.Get(1).([]*entity.URLStat)...)
+	}).Return(nil)
+	urlRepo.On("IncrementClicks", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		increments = append(increments, args.Get(1).([]repository.ClickIncrement)...)
+	}).Return(nil)
+
+	p := services.NewClickPipeline(urlRepo, statsRepo, services.ClickPipelineConfig{
+		Workers:       1,
+		BatchSize:     10,
+		FlushInterval: time.Hour,
+	})
+
+	now := time.Now()
+	p.Record(context.Background(), &entity.URLStat{URLID: "a", ClickedAt: now, Class: "human"})
+	p.Record(context.Background(), &entity.URLStat{URLID: "b", ClickedAt: now, Class: "human"})
+	p.Record(context.Background(), &entity.URLStat{URLID: "a", ClickedAt: now.Add(time.Second), Class: "human", ClickID: "c1"})
+	p.Record(context.Background(), &entity.URLStat{URLID: "b", ClickedAt: now, Class: "preview", ClickID: "c2"})
+
+	assert.NoError(t, p.Close(context.Background()))
+
+	assert.Len(t, saved, 4)
+	assert.Equal(t, []repository.ClickIncrement{
+		{URLID: "a", Count: 2, Tracked: 1, LastClick: now.Add(time.Second)},
+		{URLID: "b", Count: 1, Bots: 1, LastClick: now},
+	}, increments)
+
+	stats := p.Stats()
+	assert.Equal(t, uint64(4), stats.Written)
+	assert.Equal(t, uint64(1), stats.Batches)
+}
+
+func TestClickPipeline_DropsWhenFull(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	statsRepo := new(MockStatsRepo)
+
+	release := make(chan struct{})
+	statsRepo.On("SaveMany", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		<-release
+	}).Return(nil)
+	urlRepo.On("IncrementClicks", mock.Anything, mock.Anything).Return(nil)
+
+	p := services.NewClickPipeline(urlRepo, statsRepo, services.ClickPipelineConfig{
+		QueueSize:     1,
+		Workers:       1,
+		BatchSize:     1,
+		FlushInterval: time.Hour,
+	})
+
+	// The first click is picked up by the worker, which then blocks on the
+	// write; the second fills the queue and the rest are dropped.
+	p.Record(context.Background(), &entity.URLStat{URLID: "a"})
+	assert.Eventually(t, func() bool { return p.Stats().Queued == 0 }, time.Second, time.Millisecond)
+	for i := 0; i < 4; i++ {
+		p.Record(context.Background(), &entity.URLStat{URLID: "a"})
+	}
+
+	assert.Equal(t, uint64(3), p.Stats().Dropped)
+
+	close(release)
+	assert.NoError(t, p.Close(context.Background()))
+	assert.Equal(t, uint64(2), p.Stats().Written)
+}
+
+func TestClickPipeline_CountsFailedWrites(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	statsRepo := new(MockStatsRepo)
+
+	statsRepo.On("SaveMany", mock.Anything, mock.Anything).Return(errors.New("db down"))
+
+	p := services.NewClickPipeline(urlRepo, statsRepo, services.ClickPipelineConfig{Workers: 1})
+	p.Record(context.Background(), &entity.URLStat{URLID: "a"})
+
+	assert.NoError(t, p.Close(context.Background()))
+	assert.Equal(t, uint64(1), p.Stats().Failed)
+	urlRepo.AssertNotCalled(t, "IncrementClicks", mock.Anything, mock.Anything)
+}
+
+func TestClickPipeline_RecordAfterClose(t *testing.T) {
+	p := services.NewClickPipeline(new(MockURLRepo), new(MockStatsRepo), services.ClickPipelineConfig{})
+
+	assert.NoError(t, p.Close(context.Background()))
+	p.Record(context.Background(), &entity.URLStat{URLID: "a"})
+
+	assert.Equal(t, uint64(1), p.Stats().Dropped)
+	assert.ErrorIs(t, p.Close(context.Background()), services.ErrClickPipelineClosed)
+}
+
+type MockTransactor struct {
+	mock.Mock
+}
+
+func (m *MockTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	m.Called(ctx)
+	return fn(ctx)
+}
+
+func TestClickPipeline_TransactionalWrites(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	statsRepo := new(MockStatsRepo)
+	tx := new(MockTransactor)
+
+	tx.On("WithinTransaction", mock.Anything).Return()
+	statsRepo.On("SaveMany", mock.Anything, mock.Anything).Return(nil)
+	urlRepo.On("IncrementClicks", mock.Anything, mock.Anything).Return(errors.New("write conflict"))
+
+	p := services.NewClickPipeline(urlRepo, statsRepo, services.ClickPipelineConfig{
+		Workers:    1,
+		Transactor: tx,
+	})
+	p.Record(context.Background(), &entity.URLStat{URLID: "a"})
+
+	assert.NoError(t, p.Close(context.Background()))
+	tx.AssertNumberOfCalls(t, "WithinTransaction", 1)
+	assert.Equal(t, uint64(1), p.Stats().Failed)
+	assert.Equal(t, uint64(0), p.Stats().Written)
+}
+
+func TestClickPipeline_ObservesUniqueVisitors(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	statsRepo := new(MockStatsRepo)
+	visitorRepo := new(MockVisitorRepo)
+
+	statsRepo.On("SaveMany", mock.Anything, mock.Anything).Return(nil)
+	urlRepo.On("IncrementClicks", mock.Anything, mock.Anything).Return(nil)
+
+	var observed []entity.VisitorObservation
+	visitorRepo.On("Observe", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		observed = append(observed, args.Get(1).([]entity.VisitorObservation)...)
+	}).Return(nil)
+
+	p := services.NewClickPipeline(urlRepo, statsRepo, services.ClickPipelineConfig{
+		Workers:  1,
+		Visitors: visitorRepo,
+	})
+
+	clickedAt := time.Date(2025, 3, 1, 15, 30, 0, 0, time.UTC)
+	p.Record(context.Background(), &entity.URLStat{URLID: "a", ClickedAt: clickedAt, VisitorHash: 0xDEADBEEF})
+	p.Record(context.Background(), &entity.URLStat{URLID: "a", ClickedAt: clickedAt})
+
+	assert.NoError(t, p.Close(context.Background()))
+	assert.Len(t, observed, 1)
+	assert.Equal(t, "a", observed[0].URLID)
+	assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), observed[0].Day)
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"log"
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/exceptions"
+	"url-shortener/internal/domain/repository"
+	"url-shortener/internal/services/dto"
+)
+
+// maxAttributionWindow bounds the per-link attribution window.
+const maxAttributionWindow = 365 * 24 * time.Hour
+
+var clickIDParamPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,32}$`)
+
+// WithConversions issues click IDs on the redirects of links that track
+// conversions and attributes conversions reported for them within window,
+// unless a link sets its own.
+func WithConversions(repo repository.ConversionRepository, window time.Duration) URLServiceOption {
+	return func(s *URLService) {
+		s.conversions = repo
+		s.conversionWindow = window
+	}
+}
+
+// ConfigureConversions sets how a link tracks conversions.
+func (s *URLService) ConfigureConversions(ctx context.Context, id, ownerID string, settings dto.ConversionSettings) (*dto.ConversionSettings, error) {
+	url, err := s.ownedURL(ctx, id, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.ClickIDParam != "" && !clickIDParamPattern.MatchString(settings.ClickIDParam) {
+		return nil, exceptions.ErrInvalidConversion
+	}
+	var window time.Duration
+	if settings.AttributionWindow != "" {
+		window, err = time.ParseDuration(settings.AttributionWindow)
+		if err != nil || window <= 0 || window > maxAttributionWindow {
+			return nil, exceptions.ErrInvalidConversion
+		}
+	}
+
+	url.ClickIDParam = settings.ClickIDParam
+	url.AttributionWindow = window
+	// The secret is kept when tracking is turned off, so postbacks for
+	// clicks already issued still authenticate.
+	if url.PostbackSecret == "" && url.ClickIDParam != "" {
+		url.PostbackSecret = newPostbackSecret()
+	}
+	if err := s.redirects.SetConversions(ctx, url.ID, url.ClickIDParam, url.AttributionWindow, url.PostbackSecret); err != nil {
+		return nil, err
+	}
+	s.uncache(url.ID)
+
+	settings.PostbackSecret = url.PostbackSecret
+	return &settings, nil
+}
+
+// Convert attributes a conversion reported by the advertiser's server to the
+// click that carried input.ClickID. input.Secret must be the postback secret
+// of the clicked link. Clicks convert once; repeated postbacks are reported
+// as duplicates.
+func (s *URLService) Convert(ctx context.Context, input dto.ConversionInput) (*dto.Conversion, error) {
+	if s.conversions == nil || input.ClickID == "" || input.Secret == "" {
+		return nil, exceptions.ErrInvalidConversion
+	}
+
+	var value float64
+	if input.Value != "" {
+		v, err := strconv.ParseFloat(input.Value, 64)
+		if err != nil || v < 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil, exceptions.ErrInvalidConversion
+		}
+		value = v
+	}
+
+	return s.convert(ctx, input.ClickID, value, func(url *entity.URL) error {
+		if url.PostbackSecret == "" || subtle.ConstantTimeCompare([]byte(input.Secret), []byte(url.PostbackSecret)) != 1 {
+			return exceptions.ErrInvalidPostbackSecret
+		}
+		return nil
+	})
+}
+
+// ConvertPixel attributes a conversion reported by the visitor's browser.
+// The click ID is all the page knows, so pixel conversions carry no value.
+func (s *URLService) ConvertPixel(ctx context.Context, clickID string) (*dto.Conversion, error) {
+	if s.conversions == nil || clickID == "" {
+		return nil, exceptions.ErrInvalidConversion
+	}
+	return s.convert(ctx, clickID, 0, nil)
+}
+
+func (s *URLService) convert(ctx context.Context, clickID string, value float64, authorize func(*entity.URL) error) (*dto.Conversion, error) {
+	click, err := s.findClick(ctx, clickID)
+	if err != nil {
+		return nil, err
+	}
+	url, err := s.findForRedirect(ctx, click.URLID)
+	if err != nil {
+		return nil, exceptions.ErrClickNotFound
+	}
+	if authorize != nil {
+		if err := authorize(url); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	window := url.AttributionWindow
+	if window <= 0 {
+		window = s.conversionWindow
+	}
+	if window > 0 && now.Sub(click.ClickedAt) > window {
+		return nil, exceptions.ErrConversionExpired
+	}
+
+	conversion := &entity.Conversion{
+		URLID:       click.URLID,
+		ClickID:     clickID,
+		Value:       value,
+		ClickedAt:   click.ClickedAt,
+		ConvertedAt: now,
+	}
+	result := &dto.Conversion{
+		ClickID:     conversion.ClickID,
+		URLID:       conversion.URLID,
+		Value:       conversion.Value,
+		ConvertedAt: conversion.ConvertedAt,
+	}
+	if err := s.conversions.Save(ctx, conversion); err != nil {
+		if !errors.Is(err, exceptions.ErrConversionExists) {
+			return nil, err
+		}
+		result.Duplicate = true
+	}
+
+	return result, nil
+}
+
+func (s *URLService) conversionStats(ctx context.Context, url *entity.URL) (*dto.ConversionStats, error) {
+	totals, err := s.conversions.Totals(ctx, url.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &dto.ConversionStats{
+		TrackedClicks: url.TrackedClickCount,
+		Conversions:   totals.Conversions,
+		Value:         totals.Value,
+	}
+	if url.TrackedClickCount > 0 {
+		stats.Rate = math.Round(float64(totals.Conversions)*1000/float64(url.TrackedClickCount)) / 10
+	}
+	return stats, nil
+}
+
+// issueClickID stores a new click ID for a click on url. The ID is kept for
+// the longest window the link could be given, since its window may change
+// after the click. On failure the redirect goes out without a click ID
+// rather than with one that can never convert.
+func (s *URLService) issueClickID(ctx context.Context, url *entity.URL, clickedAt time.Time) (string, bool) {
+	click := &entity.IssuedClick{
+		ClickID:   newClickID(),
+		URLID:     url.ID,
+		ClickedAt: clickedAt,
+	}
+	if s.conversionWindow > 0 {
+		click.ExpireAt = clickedAt.Add(max(s.conversionWindow, maxAttributionWindow))
+	}
+	if err := s.conversions.IssueClick(ctx, click); err != nil {
+		log.Printf("conversions: issue click id for %s: %v", url.ID, err)
+		return "", false
+	}
+	return click.ClickID, true
+}
+
+// findClick looks up an issued click ID. Click IDs issued before they were
+// stored on their own are only found on their click, while it is retained.
+func (s *URLService) findClick(ctx context.Context, clickID string) (*entity.IssuedClick, error) {
+	click, err := s.conversions.FindClick(ctx, clickID)
+	if !errors.Is(err, exceptions.ErrClickNotFound) {
+		return click, err
+	}
+
+	stat, err := s.statsRepo.FindByClickID(ctx, clickID)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.IssuedClick{ClickID: clickID, URLID: stat.URLID, ClickedAt: stat.ClickedAt}, nil
+}
+
+// newClickID returns a random, URL safe click ID.
+func newClickID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// newPostbackSecret returns a random, URL safe postback secret.
+func newPostbackSecret() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// withClickID appends param=clickID to the query of destination.
+func withClickID(destination, param, clickID string) string {
+	return appendQuery(destination, [2]string{param, clickID})
+}
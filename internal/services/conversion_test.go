@@ -0,0 +1,259 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/exceptions"
+	"url-shortener/internal/services"
+	"url-shortener/internal/services/dto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockConversionRepo struct {
+	mock.Mock
+}
+
+func (m *MockConversionRepo) Save(ctx context.Context, conversion *entity.Conversion) error {
+	args := m.Called(ctx, conversion)
+	return args.Error(0)
+}
+
+func (m *MockConversionRepo) Totals(ctx context.Context, urlID string) (entity.ConversionTotals, error) {
+	args := m.Called(ctx, urlID)
+	return args.Get(0).(entity.ConversionTotals), args.Error(1)
+}
+
+func (m *MockConversionRepo) IssueClick(ctx context.Context, click *entity.IssuedClick) error {
+	args := m.Called(ctx, click)
+	return args.Error(0)
+}
+
+func (m *MockConversionRepo) FindClick(ctx context.Context, clickID string) (*entity.IssuedClick, error) {
+	args := m.Called(ctx, clickID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.IssuedClick), args.Error(1)
+}
+
+func TestURLService_Resolve_AppendsClickID(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	conversions := new(MockConversionRepo)
+	recorder := &captureRecorder{}
+	svc := services.NewURLService(urlRepo, new(MockIDGen), new(MockStatsRepo),
+		services.WithClickRecorder(recorder), services.WithConversions(conversions, time.Hour))
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{
+		ID: "url1", OriginalURL: "https://shop.example/p?utm_source=x#top", ClickIDParam: "clid",
+	}, nil)
+	var issued []*entity.IssuedClick
+	conversions.On("IssueClick", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		issued = append(issued, args.Get(1).(*entity.IssuedClick))
+	}).Return(nil)
+
+	redirect, err := svc.Resolve(context.Background(), "url1", browserVisit("1.2.3.4"))
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, redirect.ClickID)
+	assert.Equal(t, "https://shop.example/p?utm_source=x&clid="+redirect.ClickID+"#top", redirect.Location)
+	assert.Equal(t, redirect.ClickID, recorder.stats[0].ClickID)
+
+	// The click ID outlives the one hour window, which the link may widen.
+	assert.Equal(t, redirect.ClickID, issued[0].ClickID)
+	assert.Equal(t, "url1", issued[0].URLID)
+	assert.True(t, issued[0].ClickedAt.Equal(recorder.stats[0].ClickedAt))
+	assert.Equal(t, 365*24*time.Hour, issued[0].ExpireAt.Sub(issued[0].ClickedAt))
+
+	again, _ := svc.Resolve(context.Background(), "url1", browserVisit("1.2.3.4"))
+	assert.NotEqual(t, redirect.ClickID, again.ClickID)
+}
+
+func TestURLService_Resolve_NoClickIDWhenNotStored(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	conversions := new(MockConversionRepo)
+	recorder := &captureRecorder{}
+	svc := services.NewURLService(urlRepo, new(MockIDGen), new(MockStatsRepo),
+		services.WithClickRecorder(recorder), services.WithConversions(conversions, time.Hour))
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{
+		ID: "url1", OriginalURL: "https://shop.example/p", ClickIDParam: "clid",
+	}, nil)
+	conversions.On("IssueClick", mock.Anything, mock.Anything).Return(errors.New("mongo down"))
+
+	redirect, err := svc.Resolve(context.Background(), "url1", browserVisit("1.2.3.4"))
+
+	assert.NoError(t, err)
+	assert.Empty(t, redirect.ClickID)
+	assert.Equal(t, "https://shop.example/p", redirect.Location)
+	assert.Empty(t, recorder.stats[0].ClickID)
+}
+
+func TestURLService_Resolve_NoClickIDForBots(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	recorder := &captureRecorder{}
+	svc := services.NewURLService(urlRepo, new(MockIDGen), new(MockStatsRepo),
+		services.WithClickRecorder(recorder), services.WithConversions(new(MockConversionRepo), time.Hour))
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{
+		ID: "url1", OriginalURL: "https://shop.example/p", ClickIDParam: "clid",
+	}, nil)
+
+	for _, ua := range []string{
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+		"Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)",
+	} {
+		visit := browserVisit("1.2.3.4")
+		visit.UserAgent = ua
+
+		redirect, err := svc.Resolve(context.Background(), "url1", visit)
+
+		assert.NoError(t, err)
+		assert.Empty(t, redirect.ClickID, ua)
+		assert.Equal(t, "https://shop.example/p", redirect.Location, ua)
+	}
+	for _, stat := range recorder.stats {
+		assert.Empty(t, stat.ClickID)
+	}
+}
+
+func TestURLService_Resolve_NoClickIDWhenOptedOut(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	recorder := &captureRecorder{}
+	svc := services.NewURLService(urlRepo, new(MockIDGen), new(MockStatsRepo),
+		services.WithClickRecorder(recorder),
+		services.WithConversions(new(MockConversionRepo), time.Hour),
+		services.WithPrivacy(services.PrivacyPolicy{HonorOptOut: true}))
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{
+		ID: "url1", OriginalURL: "https://shop.example/p", ClickIDParam: "clid",
+	}, nil)
+
+	redirect, err := svc.Resolve(context.Background(), "url1", dto.Visit{DoNotTrack: "1"})
+
+	assert.NoError(t, err)
+	assert.Empty(t, redirect.ClickID)
+	assert.Equal(t, "https://shop.example/p", redirect.Location)
+	assert.Empty(t, recorder.stats[0].ClickID)
+}
+
+func TestURLService_Convert(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	statsRepo := new(MockStatsRepo)
+	conversions := new(MockConversionRepo)
+	svc := services.NewURLService(urlRepo, new(MockIDGen), statsRepo, services.WithConversions(conversions, 24*time.Hour))
+
+	clickedAt := time.Now().Add(-2 * time.Hour)
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{ID: "url1", ClickIDParam: "clid", PostbackSecret: "s1"}, nil)
+	urlRepo.On("FindByID", mock.Anything, "url2").Return(&entity.URL{ID: "url2", ClickIDParam: "clid", AttributionWindow: time.Hour, PostbackSecret: "s2"}, nil)
+	conversions.On("FindClick", mock.Anything, "fresh").Return(&entity.IssuedClick{URLID: "url1", ClickID: "fresh", ClickedAt: clickedAt}, nil)
+	conversions.On("FindClick", mock.Anything, "late").Return(&entity.IssuedClick{URLID: "url2", ClickID: "late", ClickedAt: clickedAt}, nil)
+	conversions.On("FindClick", mock.Anything, mock.Anything).Return(nil, exceptions.ErrClickNotFound)
+	// legacy was issued before click IDs were stored on their own.
+	statsRepo.On("FindByClickID", mock.Anything, "legacy").Return(&entity.URLStat{URLID: "url1", ClickID: "legacy", ClickedAt: clickedAt}, nil)
+	statsRepo.On("FindByClickID", mock.Anything, "unknown").Return(nil, exceptions.ErrClickNotFound)
+	conversions.On("Save", mock.Anything, mock.MatchedBy(func(c *entity.Conversion) bool {
+		return c.URLID == "url1" && c.ClickID == "fresh" && c.Value == 19.9 && c.ClickedAt.Equal(clickedAt)
+	})).Return(nil).Once()
+
+	conversion, err := svc.Convert(context.Background(), dto.ConversionInput{ClickID: "fresh", Value: "19.9", Secret: "s1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "url1", conversion.URLID)
+	assert.False(t, conversion.Duplicate)
+
+	conversions.On("Save", mock.Anything, mock.Anything).Return(exceptions.ErrConversionExists).Once()
+	conversion, err = svc.Convert(context.Background(), dto.ConversionInput{ClickID: "fresh", Secret: "s1"})
+	assert.NoError(t, err)
+	assert.True(t, conversion.Duplicate)
+
+	conversions.On("Save", mock.Anything, mock.MatchedBy(func(c *entity.Conversion) bool {
+		return c.URLID == "url1" && c.ClickID == "legacy"
+	})).Return(nil).Once()
+	_, err = svc.Convert(context.Background(), dto.ConversionInput{ClickID: "legacy", Secret: "s1"})
+	assert.NoError(t, err)
+
+	// url2 overrides the default window with one hour.
+	_, err = svc.Convert(context.Background(), dto.ConversionInput{ClickID: "late", Secret: "s2"})
+	assert.ErrorIs(t, err, exceptions.ErrConversionExpired)
+
+	// The secret must be the one of the clicked link.
+	_, err = svc.Convert(context.Background(), dto.ConversionInput{ClickID: "fresh", Value: "1000", Secret: "s2"})
+	assert.ErrorIs(t, err, exceptions.ErrInvalidPostbackSecret)
+
+	_, err = svc.Convert(context.Background(), dto.ConversionInput{ClickID: "unknown", Secret: "s1"})
+	assert.ErrorIs(t, err, exceptions.ErrClickNotFound)
+
+	for _, value := range []string{"abc", "-1", "NaN", "Inf"} {
+		_, err = svc.Convert(context.Background(), dto.ConversionInput{ClickID: "fresh", Value: value, Secret: "s1"})
+		assert.ErrorIs(t, err, exceptions.ErrInvalidConversion, value)
+	}
+	_, err = svc.Convert(context.Background(), dto.ConversionInput{})
+	assert.ErrorIs(t, err, exceptions.ErrInvalidConversion)
+	_, err = svc.Convert(context.Background(), dto.ConversionInput{ClickID: "fresh"})
+	assert.ErrorIs(t, err, exceptions.ErrInvalidConversion)
+
+	conversions.AssertNumberOfCalls(t, "Save", 3)
+}
+
+func TestURLService_ConvertPixel(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	conversions := new(MockConversionRepo)
+	svc := services.NewURLService(urlRepo, new(MockIDGen), new(MockStatsRepo), services.WithConversions(conversions, 24*time.Hour))
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{ID: "url1", ClickIDParam: "clid", PostbackSecret: "s1"}, nil)
+	conversions.On("FindClick", mock.Anything, "fresh").Return(&entity.IssuedClick{URLID: "url1", ClickID: "fresh", ClickedAt: time.Now()}, nil)
+	conversions.On("Save", mock.Anything, mock.MatchedBy(func(c *entity.Conversion) bool {
+		return c.ClickID == "fresh" && c.Value == 0
+	})).Return(nil).Once()
+
+	conversion, err := svc.ConvertPixel(context.Background(), "fresh")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "url1", conversion.URLID)
+	conversions.AssertExpectations(t)
+}
+
+func TestURLService_Stats_ReportsConversionRate(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	conversions := new(MockConversionRepo)
+	svc := services.NewURLService(urlRepo, new(MockIDGen), new(MockStatsRepo), services.WithConversions(conversions, 0))
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{
+		ID: "url1", OwnerID: "owner1", ClickCount: 500, ClickIDParam: "clid", TrackedClickCount: 400,
+	}, nil)
+	conversions.On("Totals", mock.Anything, "url1").Return(entity.ConversionTotals{Conversions: 10, Value: 250}, nil)
+
+	stats, err := svc.Stats(context.Background(), "url1", "owner1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, &dto.ConversionStats{TrackedClicks: 400, Conversions: 10, Value: 250, Rate: 2.5}, stats.StatsResume.Conversions)
+}
+
+func TestURLService_ConfigureConversions(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	svc := services.NewURLService(urlRepo, new(MockIDGen), new(MockStatsRepo))
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{ID: "url1", OwnerID: "owner1"}, nil)
+	urlRepo.On("SetConversions", mock.Anything, "url1", "clid", 168*time.Hour, mock.AnythingOfType("string")).Return(nil).Once()
+
+	settings, err := svc.ConfigureConversions(context.Background(), "url1", "owner1", dto.ConversionSettings{ClickIDParam: "clid", AttributionWindow: "168h"})
+	assert.NoError(t, err)
+	assert.Len(t, settings.PostbackSecret, 32)
+
+	for _, settings := range []dto.ConversionSettings{
+		{ClickIDParam: "click id"},
+		{ClickIDParam: "clid", AttributionWindow: "-1h"},
+		{ClickIDParam: "clid", AttributionWindow: "9000h"},
+	} {
+		_, err := svc.ConfigureConversions(context.Background(), "url1", "owner1", settings)
+		assert.ErrorIs(t, err, exceptions.ErrInvalidConversion)
+	}
+
+	_, err = svc.ConfigureConversions(context.Background(), "url1", "someone", dto.ConversionSettings{})
+	assert.ErrorIs(t, err, exceptions.ErrUnauthorizedURLStatistics)
+	urlRepo.AssertNumberOfCalls(t, "SetConversions", 1)
+}
@@ -0,0 +1,26 @@
+package dto
+
+import "time"
+
+type Alert struct {
+	ID          string    `json:"id"`
+	URLID       string    `json:"url_id"`
+	OriginalURL string    `json:"original_url"`
+	Kind        string    `json:"kind"`
+	Hour        time.Time `json:"hour"`
+	Observed    int       `json:"observed"`
+	Baseline    float64   `json:"baseline"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// WebhookInput sets where alerts are posted. An empty URL removes the
+// webhook; an empty secret has one generated.
+type WebhookInput struct {
+	URL    string `json:"url"`
+	Secret string `json:"secret"`
+}
+
+type Webhook struct {
+	URL    string `json:"url"`
+	Secret string `json:"secret,omitempty"`
+}
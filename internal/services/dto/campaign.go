@@ -0,0 +1,44 @@
+package dto
+
+import "time"
+
+type UTM struct {
+	Source   string `json:"source,omitempty"`
+	Medium   string `json:"medium,omitempty"`
+	Campaign string `json:"campaign,omitempty"`
+	Term     string `json:"term,omitempty"`
+	Content  string `json:"content,omitempty"`
+}
+
+// CampaignInput creates a campaign. StartsAt and EndsAt take the same
+// formats as stats ranges and are optional. A timestamp EndsAt is exclusive;
+// a bare date includes that day.
+type CampaignInput struct {
+	Name     string `json:"name" validate:"required,max=200"`
+	StartsAt string `json:"starts_at"`
+	EndsAt   string `json:"ends_at"`
+	UTM      UTM    `json:"utm"`
+}
+
+type Campaign struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	StartsAt   *time.Time `json:"starts_at,omitempty"`
+	EndsAt     *time.Time `json:"ends_at,omitempty"`
+	UTM        UTM        `json:"utm"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+}
+
+// CampaignLink attaches a link to a campaign, or detaches it when CampaignID
+// is empty.
+type CampaignLink struct {
+	CampaignID string `json:"campaign_id"`
+}
+
+// CampaignStats reports the clicks of every link in a campaign, over the
+// campaign period unless the request sets another.
+type CampaignStats struct {
+	Campaign Campaign `json:"campaign"`
+	AccountAnalytics
+}
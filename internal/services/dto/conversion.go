@@ -0,0 +1,46 @@
+package dto
+
+import "time"
+
+// Redirect is where a visit is sent. ClickID is set when the link tracks
+// conversions and Location carries it.
+type Redirect struct {
+	Location string
+	ClickID  string
+}
+
+// ConversionSettings configures conversion tracking of a link. An empty
+// ClickIDParam turns it off; AttributionWindow is a Go duration such as
+// "168h", empty for the default. PostbackSecret is set by the service and
+// must accompany the advertiser's postbacks.
+type ConversionSettings struct {
+	ClickIDParam      string `json:"click_id_param"`
+	AttributionWindow string `json:"attribution_window"`
+	PostbackSecret    string `json:"postback_secret,omitempty"`
+}
+
+type ConversionInput struct {
+	ClickID string
+	Value   string
+	Secret  string
+}
+
+// Conversion is the outcome of a postback. Duplicate reports a repeated
+// postback for a click that had already converted, which is not counted
+// again.
+type Conversion struct {
+	ClickID     string    `json:"click_id"`
+	URLID       string    `json:"url_id"`
+	Value       float64   `json:"value,omitempty"`
+	ConvertedAt time.Time `json:"converted_at"`
+	Duplicate   bool      `json:"duplicate,omitempty"`
+}
+
+// ConversionStats reports the conversions of a link. Rate is the percentage
+// of tracked clicks that converted.
+type ConversionStats struct {
+	TrackedClicks int     `json:"tracked_clicks"`
+	Conversions   int     `json:"conversions"`
+	Value         float64 `json:"value"`
+	Rate          float64 `json:"rate"`
+}
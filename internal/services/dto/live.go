@@ -0,0 +1,18 @@
+package dto
+
+import "time"
+
+// LiveClick is the payload of a live click event. It leaves out the IP and
+// raw user agent.
+type LiveClick struct {
+	URLID         string    `json:"url_id"`
+	ClickedAt     time.Time `json:"clicked_at"`
+	Class         string    `json:"class,omitempty"`
+	RefererHost   string    `json:"referer_host,omitempty"`
+	RefererSource string    `json:"referer_source,omitempty"`
+	Browser       string    `json:"browser,omitempty"`
+	OS            string    `json:"os,omitempty"`
+	Device        string    `json:"device,omitempty"`
+	Country       string    `json:"country,omitempty"`
+	City          string    `json:"city,omitempty"`
+}
@@ -0,0 +1,14 @@
+package dto
+
+// PurgeScope is the outcome of purging one account, or every other account
+// when Owner is empty.
+type PurgeScope struct {
+	Owner     string `json:"owner,omitempty"`
+	Retention string `json:"retention"`
+	Deleted   int    `json:"deleted"`
+}
+
+type PurgeReport struct {
+	Scopes  []PurgeScope `json:"scopes"`
+	Deleted int          `json:"deleted"`
+}
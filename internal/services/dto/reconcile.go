@@ -0,0 +1,14 @@
+package dto
+
+type ClickDiscrepancy struct {
+	URLID   string `json:"url_id"`
+	Counter int    `json:"counter"`
+	Logged  int    `json:"logged"`
+	Diff    int    `json:"diff"`
+}
+
+type ReconcileReport struct {
+	Checked       int                `json:"checked"`
+	Discrepancies []ClickDiscrepancy `json:"discrepancies"`
+	Fixed         int                `json:"fixed"`
+}
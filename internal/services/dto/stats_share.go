@@ -0,0 +1,30 @@
+package dto
+
+import "time"
+
+// ShareInput mints a stats share. ExpiresIn is a Go duration such as "720h",
+// empty for a share that never expires.
+type ShareInput struct {
+	ExpiresIn string `json:"expires_in"`
+}
+
+type Share struct {
+	ID        string     `json:"id"`
+	URLID     string     `json:"url_id"`
+	Token     string     `json:"token"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Revoked   bool       `json:"revoked,omitempty"`
+}
+
+// SharedStats is the read-only view of a link's stats behind a share token.
+type SharedStats struct {
+	URLID       string      `json:"url_id"`
+	OriginalURL string      `json:"original_url"`
+	ExpiresAt   *time.Time  `json:"expires_at,omitempty"`
+	Resume      Resume      `json:"resume"`
+	TimeSeries  *TimeSeries `json:"timeseries"`
+	Referrers   *Breakdown  `json:"referrers"`
+	Countries   *Breakdown  `json:"countries"`
+	Devices     *Breakdown  `json:"devices"`
+}
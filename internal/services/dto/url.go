@@ -1,5 +1,50 @@
 package dto
 
+import "time"
+
 type URL struct {
-	URL string `json:"url" validate:"required,url"`
+	URL        string   `json:"url" validate:"required,url"`
+	CampaignID string   `json:"campaign_id,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	Folder     string   `json:"folder,omitempty"`
+}
+
+// Labels replaces the tags and folder of a link; an empty Folder takes the
+// link out of its folder.
+type Labels struct {
+	Tags   []string `json:"tags"`
+	Folder string   `json:"folder"`
+}
+
+// LinkFilter narrows a link listing to one tag and/or folder.
+type LinkFilter struct {
+	Tag    string
+	Folder string
+}
+
+// Preview describes the page a link points to. As input it sets the owner's
+// overrides, where empty fields fall back to the fetched values.
+type Preview struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+	Favicon     string `json:"favicon,omitempty"`
+}
+
+type LinkPreview struct {
+	ID          string   `json:"id"`
+	OriginalURL string   `json:"original_url"`
+	Preview     *Preview `json:"preview,omitempty"`
+}
+
+// Link is a link as shown in listings.
+type Link struct {
+	ID          string    `json:"id"`
+	OriginalURL string    `json:"original_url"`
+	CreatedAt   time.Time `json:"created_at"`
+	Clicks      int       `json:"clicks"`
+	CampaignID  string    `json:"campaign_id,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	Folder      string    `json:"folder,omitempty"`
+	Preview     *Preview  `json:"preview,omitempty"`
 }
@@ -4,7 +4,11 @@ import "time"
 
 type Resume struct {
 	Clicks    int       `json:"clicks"`
+	Uniques   uint64    `json:"uniques"`
+	BotClicks int       `json:"bot_clicks"`
 	LastClick time.Time `json:"last_click"`
+
+	Conversions *ConversionStats `json:"conversions,omitempty"`
 }
 
 type Data struct {
@@ -14,9 +18,148 @@ type Data struct {
 	IP        string    `json:"ip"`
 	UserAgent string    `json:"user_agent"`
 	Referer   string    `json:"referer"`
+
+	RefererHost   string `json:"referer_host,omitempty"`
+	RefererSource string `json:"referer_source,omitempty"`
+
+	Browser        string `json:"browser,omitempty"`
+	BrowserVersion string `json:"browser_version,omitempty"`
+	OS             string `json:"os,omitempty"`
+	OSVersion      string `json:"os_version,omitempty"`
+	Device         string `json:"device,omitempty"`
+	Class          string `json:"class,omitempty"`
+	OptOut         bool   `json:"opt_out,omitempty"`
+	ClickID        string `json:"click_id,omitempty"`
+
+	Country string `json:"country,omitempty"`
+	Region  string `json:"region,omitempty"`
+	City    string `json:"city,omitempty"`
 }
 
 type URLStats struct {
 	StatsResume Resume `json:"resume"`
-	StatsData   []Data `json:"data"`
+}
+
+type ClickLogInput struct {
+	From        string
+	To          string
+	RefererHost string
+	UserAgent   string
+	Class       string
+	Cursor      string
+	Limit       int
+}
+
+type ClickPage struct {
+	Data       []Data `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+type TimeSeriesInput struct {
+	Interval    string
+	From        string
+	To          string
+	TZ          string
+	IncludeBots bool
+}
+
+// Bucket counts the clicks from Start to the next bucket. Partial marks the
+// first and last buckets when the range starts or ends inside them, so they
+// count only part of their interval.
+type Bucket struct {
+	Start   time.Time `json:"start"`
+	Clicks  int       `json:"clicks"`
+	Partial bool      `json:"partial,omitempty"`
+}
+
+type TimeSeries struct {
+	URLID    string    `json:"url_id"`
+	Interval string    `json:"interval"`
+	TZ       string    `json:"tz"`
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	Total    int       `json:"total"`
+	Buckets  []Bucket  `json:"buckets"`
+}
+
+type BreakdownInput struct {
+	By          string
+	From        string
+	To          string
+	Limit       int
+	IncludeBots bool
+}
+
+type BreakdownRow struct {
+	Key     string  `json:"key"`
+	Clicks  int     `json:"clicks"`
+	Percent float64 `json:"percent"`
+}
+
+type Breakdown struct {
+	URLID string         `json:"url_id"`
+	By    string         `json:"by"`
+	Total int            `json:"total"`
+	Rows  []BreakdownRow `json:"rows"`
+}
+
+type GeoMapInput struct {
+	From        string
+	To          string
+	IncludeBots bool
+}
+
+type GeoPoint struct {
+	Lat    float64 `json:"lat"`
+	Lon    float64 `json:"lon"`
+	Clicks int     `json:"clicks"`
+}
+
+// GeoMap is shaped for map widgets: Countries feeds a choropleth keyed by ISO
+// country code and Points a bubble or heat layer.
+type GeoMap struct {
+	URLID     string         `json:"url_id"`
+	Countries map[string]int `json:"countries"`
+	Points    []GeoPoint     `json:"points"`
+}
+
+type ExportInput struct {
+	Format  string
+	From    string
+	To      string
+	Columns string
+}
+
+type AccountAnalyticsInput struct {
+	Interval    string
+	From        string
+	To          string
+	TZ          string
+	Limit       int
+	IncludeBots bool
+
+	// Tag and Folder restrict the aggregate to the links filed under them.
+	Tag    string
+	Folder string
+}
+
+type LinkClicks struct {
+	URLID       string  `json:"url_id"`
+	OriginalURL string  `json:"original_url"`
+	Clicks      int     `json:"clicks"`
+	Percent     float64 `json:"percent"`
+}
+
+type AccountAnalytics struct {
+	Links        int            `json:"links"`
+	Interval     string         `json:"interval"`
+	TZ           string         `json:"tz"`
+	From         time.Time      `json:"from"`
+	To           time.Time      `json:"to"`
+	Total        int            `json:"total"`
+	Uniques      uint64         `json:"uniques"`
+	Buckets      []Bucket       `json:"buckets"`
+	TopLinks     []LinkClicks   `json:"top_links"`
+	TopReferrers []BreakdownRow `json:"top_referrers"`
+	TopCountries []BreakdownRow `json:"top_countries"`
 }
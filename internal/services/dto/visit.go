@@ -0,0 +1,19 @@
+package dto
+
+// Visit is what the redirect handler captures about a click.
+type Visit struct {
+	Method    string
+	IP        string
+	UserAgent string
+	Referer   string
+	Accept    string
+
+	// Sec-CH-UA, Sec-CH-UA-Mobile and Sec-CH-UA-Platform client hints.
+	ClientHintUA       string
+	ClientHintMobile   string
+	ClientHintPlatform string
+
+	// DNT and Sec-GPC opt-out signals.
+	DoNotTrack           string
+	GlobalPrivacyControl string
+}
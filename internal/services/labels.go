@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/exceptions"
+	"url-shortener/internal/domain/repository"
+	"url-shortener/internal/services/dto"
+)
+
+const (
+	maxTags         = 20
+	maxTagLength    = 50
+	maxFolderLength = 100
+)
+
+// Links lists the links of ownerID, oldest first, narrowed to filter.
+func (s *URLService) Links(ctx context.Context, ownerID string, filter dto.LinkFilter) ([]dto.Link, error) {
+	urls, err := s.repo.List(ctx, repository.URLFilter{
+		OwnerID: ownerID,
+		Tag:     strings.TrimSpace(filter.Tag),
+		Folder:  strings.TrimSpace(filter.Folder),
+	})
+	if err != nil {
+		return nil, err
+	}
+	links := make([]dto.Link, 0, len(urls))
+	for i := range urls {
+		url := &urls[i]
+		links = append(links, dto.Link{
+			ID:          url.ID,
+			OriginalURL: url.OriginalURL,
+			CreatedAt:   url.CreatedAt,
+			Clicks:      url.ClickCount,
+			CampaignID:  url.CampaignID,
+			Tags:        url.Tags,
+			Folder:      url.Folder,
+			Preview:     toPreviewDTO(url),
+		})
+	}
+	return links, nil
+}
+
+// SetLabels replaces the tags and folder of a link.
+func (s *URLService) SetLabels(ctx context.Context, id, ownerID string, labels dto.Labels) (*entity.URL, error) {
+	tags, folder, err := normalizeLabels(labels.Tags, labels.Folder)
+	if err != nil {
+		return nil, err
+	}
+	url, err := s.ownedURL(ctx, id, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	url.Tags = tags
+	url.Folder = folder
+	if err := s.redirects.SetLabels(ctx, url.ID, url.Tags, url.Folder); err != nil {
+		return nil, err
+	}
+	s.uncache(url.ID)
+	return url, nil
+}
+
+// normalizeLabels trims tags and folder and drops empty and repeated tags.
+// Tags are matched exactly, so "Promo" and "promo" are different tags.
+func normalizeLabels(rawTags []string, rawFolder string) ([]string, string, error) {
+	var tags []string
+	seen := make(map[string]bool, len(rawTags))
+	for _, tag := range rawTags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > maxTagLength {
+			return nil, "", exceptions.ErrInvalidLabels
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	if len(tags) > maxTags {
+		return nil, "", exceptions.ErrInvalidLabels
+	}
+
+	folder := strings.TrimSpace(rawFolder)
+	if len(folder) > maxFolderLength {
+		return nil, "", exceptions.ErrInvalidLabels
+	}
+	return tags, folder, nil
+}
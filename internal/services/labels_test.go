@@ -0,0 +1,102 @@
+package services_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/exceptions"
+	"url-shortener/internal/domain/repository"
+	"url-shortener/internal/services"
+	"url-shortener/internal/services/dto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestURLService_Shorten_WithLabels(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	idGen := new(MockIDGen)
+	svc := services.NewURLService(urlRepo, idGen, new(MockStatsRepo))
+
+	idGen.On("Generate").Return("abc123", nil)
+	urlRepo.On("Save", mock.Anything, mock.Anything).Return(nil)
+
+	url, err := svc.Shorten(context.Background(), dto.URL{
+		URL:    "https://example.com",
+		Tags:   []string{" promo", "", "promo", "Q1 "},
+		Folder: " Marketing ",
+	}, "owner1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"promo", "Q1"}, url.Tags)
+	assert.Equal(t, "Marketing", url.Folder)
+
+	_, err = svc.Shorten(context.Background(), dto.URL{
+		URL:  "https://example.com",
+		Tags: []string{strings.Repeat("x", 51)},
+	}, "owner1")
+	assert.ErrorIs(t, err, exceptions.ErrInvalidLabels)
+	urlRepo.AssertNumberOfCalls(t, "Save", 1)
+}
+
+func TestURLService_SetLabels(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	svc := services.NewURLService(urlRepo, new(MockIDGen), new(MockStatsRepo))
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{
+		ID: "url1", OwnerID: "owner1", Tags: []string{"old"}, Folder: "Archive",
+	}, nil)
+	urlRepo.On("SetLabels", mock.Anything, "url1", []string{"new"}, "").Return(nil).Once()
+
+	_, err := svc.SetLabels(context.Background(), "url1", "owner1", dto.Labels{Tags: []string{"new"}})
+	assert.NoError(t, err)
+
+	tooMany := make([]string, 21)
+	for i := range tooMany {
+		tooMany[i] = strings.Repeat("t", i+1)
+	}
+	_, err = svc.SetLabels(context.Background(), "url1", "owner1", dto.Labels{Tags: tooMany})
+	assert.ErrorIs(t, err, exceptions.ErrInvalidLabels)
+
+	_, err = svc.SetLabels(context.Background(), "url1", "intruder", dto.Labels{})
+	assert.ErrorIs(t, err, exceptions.ErrUnauthorizedURLStatistics)
+	urlRepo.AssertNumberOfCalls(t, "SetLabels", 1)
+}
+
+func TestURLService_Links_FiltersByTagAndFolder(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	svc := services.NewURLService(urlRepo, new(MockIDGen), new(MockStatsRepo))
+
+	urlRepo.On("List", mock.Anything, repository.URLFilter{OwnerID: "owner1", Tag: "promo", Folder: "Marketing"}).
+		Return([]entity.URL{{ID: "url1"}}, nil)
+	urlRepo.On("List", mock.Anything, repository.URLFilter{OwnerID: "owner1", Tag: "none"}).Return(nil, nil)
+
+	urls, err := svc.Links(context.Background(), "owner1", dto.LinkFilter{Tag: " promo ", Folder: "Marketing"})
+	assert.NoError(t, err)
+	assert.Len(t, urls, 1)
+
+	urls, err = svc.Links(context.Background(), "owner1", dto.LinkFilter{Tag: "none"})
+	assert.NoError(t, err)
+	assert.NotNil(t, urls)
+	assert.Empty(t, urls)
+}
+
+func TestURLService_AccountAnalytics_PerTag(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	statsRepo := new(MockStatsRepo)
+	svc := services.NewURLService(urlRepo, new(MockIDGen), statsRepo)
+
+	urlRepo.On("List", mock.Anything, repository.URLFilter{OwnerID: "owner1", Tag: "promo"}).
+		Return([]entity.URL{{ID: "url2", OriginalURL: "https://b.example"}}, nil)
+	statsRepo.On("TimeSeries", mock.Anything, mock.MatchedBy(func(q repository.TimeSeriesQuery) bool {
+		return assert.ObjectsAreEqual([]string{"url2"}, q.URLIDs)
+	})).Return([]entity.ClickBucket{}, nil)
+	statsRepo.On("Breakdown", mock.Anything, mock.Anything).Return([]entity.BreakdownRow{{Key: "url2", Clicks: 7}}, 7, nil)
+
+	analytics, err := svc.AccountAnalytics(context.Background(), "owner1", dto.AccountAnalyticsInput{Tag: "promo"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, analytics.Links)
+	urlRepo.AssertNotCalled(t, "FindByOwner", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+	"url-shortener/internal/domain/entity"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// LinkCache is a size-bounded, in-process LRU of resolved links. Concurrent
+// misses for the same code share a single load through singleflight, so a
+// burst of traffic on a cold link costs one repository call.
+//
+// Remove only reaches the local replica, so entries also expire after a TTL:
+// that bounds how long other replicas keep redirecting to an edited link.
+type LinkCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	ll       *list.List
+	items    map[string]*list.Element
+	group    singleflight.Group
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// LinkCacheStats is a point-in-time snapshot of the cache counters.
+type LinkCacheStats struct {
+	Hits     uint64 `json:"hits"`
+	Misses   uint64 `json:"misses"`
+	Size     int    `json:"size"`
+	Capacity int    `json:"capacity"`
+}
+
+type linkCacheEntry struct {
+	url     *entity.URL
+	expires time.Time
+}
+
+// NewLinkCache keeps up to capacity links, each for at most ttl. A zero ttl
+// keeps them until evicted, which is only safe with a single replica.
+func NewLinkCache(capacity int, ttl time.Duration) *LinkCache {
+	return &LinkCache{
+		capacity: capacity,
+		ttl:      ttl,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// Get returns the cached link for id, calling load on a miss. Errors are not
+// cached. The load runs detached from the caller's cancellation because its
+// result is shared with every other caller waiting on the same id.
+func (c *LinkCache) Get(ctx context.Context, id string, load func(ctx context.Context, id string) (*entity.URL, error)) (*entity.URL, error) {
+	if url, ok := c.lookup(id); ok {
+		c.hits.Add(1)
+		return url, nil
+	}
+	c.misses.Add(1)
+
+	v, err, _ := c.group.Do(id, func() (any, error) {
+		if url, ok := c.lookup(id); ok {
+			return url, nil
+		}
+		url, err := load(context.WithoutCancel(ctx), id)
+		if err != nil {
+			return nil, err
+		}
+		c.Add(url)
+		return url, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*entity.URL), nil
+}
+
+func (c *LinkCache) Add(url *entity.URL) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &linkCacheEntry{url: url}
+	if c.ttl > 0 {
+		entry.expires = time.Now().Add(c.ttl)
+	}
+	if el, ok := c.items[url.ID]; ok {
+		el.Value = entry
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	c.items[url.ID] = c.ll.PushFront(entry)
+	if c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*linkCacheEntry).url.ID)
+	}
+}
+
+func (c *LinkCache) Remove(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[id]; ok {
+		c.ll.Remove(el)
+		delete(c.items, id)
+	}
+}
+
+func (c *LinkCache) Stats() LinkCacheStats {
+	c.mu.Lock()
+	size := c.ll.Len()
+	c.mu.Unlock()
+
+	return LinkCacheStats{
+		Hits:     c.hits.Load(),
+		Misses:   c.misses.Load(),
+		Size:     size,
+		Capacity: c.capacity,
+	}
+}
+
+func (c *LinkCache) lookup(id string) (*entity.URL, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[id]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*linkCacheEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		c.ll.Remove(el)
+		delete(c.items, id)
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return entry.url, true
+}
@@ -0,0 +1,139 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/services"
+	"url-shortener/internal/services/dto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestLinkCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := services.NewLinkCache(2, 0)
+
+	cache.Add(&entity.URL{ID: "a"})
+	cache.Add(&entity.URL{ID: "b"})
+
+	_, _ = cache.Get(context.Background(), "a", func(ctx context.Context, id string) (*entity.URL, error) {
+		t.Fatal("a should be cached")
+		return nil, nil
+	})
+
+	cache.Add(&entity.URL{ID: "c"})
+
+	loads := 0
+	_, err := cache.Get(context.Background(), "b", func(ctx context.Context, id string) (*entity.URL, error) {
+		loads++
+		return &entity.URL{ID: id}, nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, loads)
+	assert.Equal(t, 2, cache.Stats().Size)
+}
+
+func TestLinkCache_ExpiresEntries(t *testing.T) {
+	cache := services.NewLinkCache(10, 20*time.Millisecond)
+	cache.Add(&entity.URL{ID: "a", OriginalURL: "https://old.example"})
+
+	time.Sleep(30 * time.Millisecond)
+
+	url, err := cache.Get(context.Background(), "a", func(ctx context.Context, id string) (*entity.URL, error) {
+		return &entity.URL{ID: id, OriginalURL: "https://new.example"}, nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://new.example", url.OriginalURL)
+	assert.Equal(t, uint64(1), cache.Stats().Misses)
+}
+
+func TestLinkCache_CollapsesConcurrentMisses(t *testing.T) {
+	cache := services.NewLinkCache(10, 0)
+
+	var loads atomic.Int32
+	release := make(chan struct{})
+	load := func(ctx context.Context, id string) (*entity.URL, error) {
+		loads.Add(1)
+		<-release
+		return &entity.URL{ID: id, OriginalURL: "https://example.com"}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			url, err := cache.Get(context.Background(), "hot", load)
+			assert.NoError(t, err)
+			assert.Equal(t, "https://example.com", url.OriginalURL)
+		}()
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), loads.Load())
+}
+
+func TestLinkCache_DoesNotCacheErrors(t *testing.T) {
+	cache := services.NewLinkCache(10, 0)
+
+	_, err := cache.Get(context.Background(), "x", func(ctx context.Context, id string) (*entity.URL, error) {
+		return nil, errors.New("boom")
+	})
+	assert.EqualError(t, err, "boom")
+
+	url, err := cache.Get(context.Background(), "x", func(ctx context.Context, id string) (*entity.URL, error) {
+		return &entity.URL{ID: id}, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "x", url.ID)
+	assert.Equal(t, uint64(0), cache.Stats().Hits)
+}
+
+func TestURLService_Resolve_UsesLinkCache(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	statsRepo := new(MockStatsRepo)
+	idGen := new(MockIDGen)
+	cache := services.NewLinkCache(10, 0)
+
+	svc := services.NewURLService(urlRepo, idGen, statsRepo, services.WithLinkCache(cache))
+
+	urlEntity := &entity.URL{ID: "abc123", OriginalURL: "https://example.com"}
+	urlRepo.On("FindByID", mock.Anything, "abc123").Return(urlEntity, nil)
+	urlRepo.On("IncrementClick", mock.Anything, "abc123").Return(nil)
+	statsRepo.On("Save", mock.Anything, mock.AnythingOfType("*entity.URLStat")).Return(nil)
+
+	for i := 0; i < 3; i++ {
+		_, err := svc.Resolve(context.Background(), "abc123", dto.Visit{IP: "1.2.3.4", UserAgent: "user-agent", Referer: "referer"})
+		assert.NoError(t, err)
+	}
+
+	urlRepo.AssertNumberOfCalls(t, "FindByID", 1)
+	assert.Equal(t, uint64(2), cache.Stats().Hits)
+}
+
+func TestURLService_WarmCache(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	statsRepo := new(MockStatsRepo)
+	idGen := new(MockIDGen)
+	cache := services.NewLinkCache(10, 0)
+
+	svc := services.NewURLService(urlRepo, idGen, statsRepo, services.WithLinkCache(cache))
+
+	urlRepo.On("FindTop", mock.Anything, 2).Return([]entity.URL{{ID: "a"}, {ID: "b"}}, nil)
+
+	n, err := svc.WarmCache(context.Background(), 2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, 2, cache.Stats().Size)
+}
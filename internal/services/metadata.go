@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/exceptions"
+	"url-shortener/internal/domain/repository"
+	"url-shortener/internal/services/dto"
+	"url-shortener/pkg/linkmeta"
+)
+
+var ErrMetadataWorkerClosed = errors.New("metadata worker closed")
+
+const (
+	maxMetadataTitle       = 300
+	maxMetadataDescription = 1000
+	maxMetadataURL         = 2048
+)
+
+// MetadataFetcher loads the metadata of a destination page.
+// *linkmeta.Fetcher is the production implementation.
+type MetadataFetcher interface {
+	Fetch(ctx context.Context, rawURL string) (linkmeta.Metadata, error)
+}
+
+type MetadataWorkerConfig struct {
+	QueueSize int
+	Workers   int
+	// Timeout bounds each fetch, including the write of its result.
+	Timeout time.Duration
+}
+
+// MetadataWorkerStats is a point-in-time snapshot of the worker counters.
+type MetadataWorkerStats struct {
+	Enqueued uint64 `json:"enqueued"`
+	Dropped  uint64 `json:"dropped"`
+	Fetched  uint64 `json:"fetched"`
+	Failed   uint64 `json:"failed"`
+	Queued   int    `json:"queued"`
+}
+
+type metadataJob struct {
+	id          string
+	destination string
+}
+
+// MetadataWorker fetches destination metadata off the request path through
+// a bounded queue. When the queue is full the fetch is skipped: metadata is
+// a nicety and never worth slowing down Shorten.
+type MetadataWorker struct {
+	repo    repository.URLRepository
+	fetcher MetadataFetcher
+	cfg     MetadataWorkerConfig
+
+	queue  chan metadataJob
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	mu     sync.RWMutex
+	closed bool
+
+	enqueued atomic.Uint64
+	dropped  atomic.Uint64
+	fetched  atomic.Uint64
+	failed   atomic.Uint64
+}
+
+func NewMetadataWorker(repo repository.URLRepository, fetcher MetadataFetcher, cfg MetadataWorkerConfig) *MetadataWorker {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1000
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 2
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &MetadataWorker{
+		repo:    repo,
+		fetcher: fetcher,
+		cfg:     cfg,
+		queue:   make(chan metadataJob, cfg.QueueSize),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	for i := 0; i < cfg.Workers; i++ {
+		w.wg.Add(1)
+		go w.run()
+	}
+	return w
+}
+
+// Enqueue schedules a fetch of destination for link id without blocking.
+func (w *MetadataWorker) Enqueue(id, destination string) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		w.dropped.Add(1)
+		return
+	}
+	select {
+	case w.queue <- metadataJob{id: id, destination: destination}:
+		w.enqueued.Add(1)
+	default:
+		w.dropped.Add(1)
+	}
+}
+
+// Close stops accepting links and aborts the fetches in flight; queued links
+// are dropped. It waits for the workers to exit or ctx to end.
+func (w *MetadataWorker) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return ErrMetadataWorkerClosed
+	}
+	w.closed = true
+	close(w.queue)
+	w.mu.Unlock()
+	w.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *MetadataWorker) Stats() MetadataWorkerStats {
+	return MetadataWorkerStats{
+		Enqueued: w.enqueued.Load(),
+		Dropped:  w.dropped.Load(),
+		Fetched:  w.fetched.Load(),
+		Failed:   w.failed.Load(),
+		Queued:   len(w.queue),
+	}
+}
+
+func (w *MetadataWorker) run() {
+	defer w.wg.Done()
+	for job := range w.queue {
+		if w.ctx.Err() != nil {
+			w.dropped.Add(1)
+			continue
+		}
+		if err := w.fetch(job); err != nil {
+			w.failed.Add(1)
+			log.Printf("metadata: fetch %s: %v", job.id, err)
+			continue
+		}
+		w.fetched.Add(1)
+	}
+}
+
+func (w *MetadataWorker) fetch(job metadataJob) error {
+	ctx, cancel := context.WithTimeout(w.ctx, w.cfg.Timeout)
+	defer cancel()
+
+	m, err := w.fetcher.Fetch(ctx, job.destination)
+	if err != nil {
+		return err
+	}
+	return w.repo.SetMetadata(ctx, job.id, entity.LinkMetadata{
+		Title:       m.Title,
+		OGTitle:     m.OGTitle,
+		Description: m.OGDescription,
+		Image:       m.OGImage,
+		Favicon:     m.Favicon,
+		FetchedAt:   time.Now(),
+	})
+}
+
+// WithMetadata fetches the metadata of every new link through worker.
+func WithMetadata(worker *MetadataWorker) URLServiceOption {
+	return func(s *URLService) {
+		s.metadata = worker
+	}
+}
+
+// SetMetadata replaces the owner's overrides of a link's metadata. Empty
+// fields fall back to the fetched values.
+func (s *URLService) SetMetadata(ctx context.Context, id, ownerID string, input dto.Preview) (*dto.Preview, error) {
+	override, err := parseMetadataOverride(input)
+	if err != nil {
+		return nil, err
+	}
+	url, err := s.ownedURL(ctx, id, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	url.MetadataOverride = override
+	if err := s.redirects.SetMetadataOverride(ctx, url.ID, url.MetadataOverride); err != nil {
+		return nil, err
+	}
+	s.uncache(url.ID)
+	if preview := toPreviewDTO(url); preview != nil {
+		return preview, nil
+	}
+	return &dto.Preview{}, nil
+}
+
+// Preview returns what a link points to, for showing before following it.
+func (s *URLService) Preview(ctx context.Context, id string) (*dto.LinkPreview, error) {
+	url, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, exceptions.ErrURLNotFound
+	}
+	return &dto.LinkPreview{ID: url.ID, OriginalURL: url.OriginalURL, Preview: toPreviewDTO(url)}, nil
+}
+
+func parseMetadataOverride(input dto.Preview) (entity.LinkMetadata, error) {
+	override := entity.LinkMetadata{
+		Title:       strings.TrimSpace(input.Title),
+		Description: strings.TrimSpace(input.Description),
+		Image:       strings.TrimSpace(input.Image),
+		Favicon:     strings.TrimSpace(input.Favicon),
+	}
+	if utf8.RuneCountInString(override.Title) > maxMetadataTitle ||
+		utf8.RuneCountInString(override.Description) > maxMetadataDescription {
+		return entity.LinkMetadata{}, exceptions.ErrInvalidMetadata
+	}
+	for _, raw := range []string{override.Image, override.Favicon} {
+		if raw != "" && !isWebURL(raw) {
+			return entity.LinkMetadata{}, exceptions.ErrInvalidMetadata
+		}
+	}
+	return override, nil
+}
+
+func isWebURL(raw string) bool {
+	if len(raw) > maxMetadataURL {
+		return false
+	}
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// toPreviewDTO returns nil when nothing is known about the destination.
+func toPreviewDTO(url *entity.URL) *dto.Preview {
+	preview := url.Preview()
+	if preview.Title == "" && preview.Description == "" && preview.Image == "" && preview.Favicon == "" {
+		return nil
+	}
+	return &dto.Preview{
+		Title:       preview.Title,
+		Description: preview.Description,
+		Image:       preview.Image,
+		Favicon:     preview.Favicon,
+	}
+}
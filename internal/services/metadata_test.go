@@ -0,0 +1,154 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/exceptions"
+	"url-shortener/internal/services"
+	"url-shortener/internal/services/dto"
+	"url-shortener/pkg/linkmeta"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// stubFetcher answers from pages and blocks on every URL in hold until the
+// context ends.
+type stubFetcher struct {
+	mu      sync.Mutex
+	pages   map[string]linkmeta.Metadata
+	hold    map[string]bool
+	fetched []string
+}
+
+func (f *stubFetcher) Fetch(ctx context.Context, rawURL string) (linkmeta.Metadata, error) {
+	f.mu.Lock()
+	f.fetched = append(f.fetched, rawURL)
+	m, ok := f.pages[rawURL]
+	hold := f.hold[rawURL]
+	f.mu.Unlock()
+
+	if hold {
+		<-ctx.Done()
+		return linkmeta.Metadata{}, ctx.Err()
+	}
+	if !ok {
+		return linkmeta.Metadata{}, errors.New("not found")
+	}
+	return m, nil
+}
+
+func TestMetadataWorker_StoresFetchedMetadata(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	fetcher := &stubFetcher{pages: map[string]linkmeta.Metadata{
+		"https://shop.example/sale": {Title: "Sale | Shop", OGTitle: "Sale", OGImage: "https://shop.example/i.png"},
+	}}
+	worker := services.NewMetadataWorker(urlRepo, fetcher, services.MetadataWorkerConfig{Workers: 1})
+
+	urlRepo.On("SetMetadata", mock.Anything, "url1", mock.MatchedBy(func(m entity.LinkMetadata) bool {
+		return m.Title == "Sale | Shop" && m.OGTitle == "Sale" && m.Image == "https://shop.example/i.png" && !m.FetchedAt.IsZero()
+	})).Return(nil).Once()
+
+	worker.Enqueue("url1", "https://shop.example/sale")
+	worker.Enqueue("url2", "https://gone.example")
+
+	assert.Eventually(t, func() bool {
+		stats := worker.Stats()
+		return stats.Fetched == 1 && stats.Failed == 1
+	}, time.Second, 5*time.Millisecond)
+	assert.NoError(t, worker.Close(context.Background()))
+	urlRepo.AssertExpectations(t)
+
+	worker.Enqueue("url3", "https://shop.example/sale")
+	assert.Equal(t, uint64(1), worker.Stats().Dropped)
+	assert.ErrorIs(t, worker.Close(context.Background()), services.ErrMetadataWorkerClosed)
+}
+
+func TestMetadataWorker_DropsWhenFullAndAbortsOnClose(t *testing.T) {
+	fetcher := &stubFetcher{hold: map[string]bool{"https://slow.example": true}}
+	worker := services.NewMetadataWorker(new(MockURLRepo), fetcher, services.MetadataWorkerConfig{
+		Workers: 1, QueueSize: 1, Timeout: time.Minute,
+	})
+
+	worker.Enqueue("url1", "https://slow.example")
+	assert.Eventually(t, func() bool { return worker.Stats().Queued == 0 }, time.Second, 5*time.Millisecond)
+	worker.Enqueue("url2", "https://slow.example")
+	worker.Enqueue("url3", "https://slow.example")
+	assert.Equal(t, uint64(1), worker.Stats().Dropped)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, worker.Close(ctx))
+
+	fetcher.mu.Lock()
+	defer fetcher.mu.Unlock()
+	assert.Equal(t, []string{"https://slow.example"}, fetcher.fetched)
+}
+
+func TestURLService_Shorten_QueuesMetadataFetch(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	idGen := new(MockIDGen)
+	fetcher := &stubFetcher{pages: map[string]linkmeta.Metadata{"https://example.com": {Title: "Example"}}}
+	worker := services.NewMetadataWorker(urlRepo, fetcher, services.MetadataWorkerConfig{Workers: 1})
+	svc := services.NewURLService(urlRepo, idGen, new(MockStatsRepo), services.WithMetadata(worker))
+
+	idGen.On("Generate").Return("abc123", nil)
+	urlRepo.On("Save", mock.Anything, mock.Anything).Return(nil)
+	urlRepo.On("SetMetadata", mock.Anything, "abc123", mock.Anything).Return(nil)
+
+	_, err := svc.Shorten(context.Background(), dto.URL{URL: "https://example.com"}, "owner1")
+
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return worker.Stats().Fetched == 1 }, time.Second, 5*time.Millisecond)
+	assert.NoError(t, worker.Close(context.Background()))
+}
+
+func TestURLService_SetMetadataAndPreview(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	svc := services.NewURLService(urlRepo, new(MockIDGen), new(MockStatsRepo))
+
+	link := &entity.URL{ID: "url1", OwnerID: "owner1", OriginalURL: "https://shop.example/sale", Metadata: entity.LinkMetadata{
+		Title: "Sale | Shop", OGTitle: "Sale", Description: "Half price", Favicon: "https://shop.example/favicon.ico",
+	}}
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(link, nil)
+	urlRepo.On("FindByID", mock.Anything, "missing").Return(nil, exceptions.ErrURLNotFound)
+	urlRepo.On("SetMetadataOverride", mock.Anything, "url1", entity.LinkMetadata{
+		Title: "Summer sale", Image: "https://cdn.example/cover.png",
+	}).Return(nil).Once()
+
+	preview, err := svc.Preview(context.Background(), "url1")
+	assert.NoError(t, err)
+	assert.Equal(t, "Sale", preview.Preview.Title)
+
+	updated, err := svc.SetMetadata(context.Background(), "url1", "owner1", dto.Preview{
+		Title: " Summer sale ", Image: "https://cdn.example/cover.png",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &dto.Preview{
+		Title:       "Summer sale",
+		Description: "Half price",
+		Image:       "https://cdn.example/cover.png",
+		Favicon:     "https://shop.example/favicon.ico",
+	}, updated)
+
+	for _, input := range []dto.Preview{
+		{Image: "javascript:alert(1)"},
+		{Favicon: "/relative.ico"},
+		{Title: strings.Repeat("t", 301)},
+	} {
+		_, err := svc.SetMetadata(context.Background(), "url1", "owner1", input)
+		assert.ErrorIs(t, err, exceptions.ErrInvalidMetadata)
+	}
+
+	_, err = svc.SetMetadata(context.Background(), "url1", "intruder", dto.Preview{})
+	assert.ErrorIs(t, err, exceptions.ErrUnauthorizedURLStatistics)
+
+	_, err = svc.Preview(context.Background(), "missing")
+	assert.ErrorIs(t, err, exceptions.ErrURLNotFound)
+	urlRepo.AssertNumberOfCalls(t, "SetMetadataOverride", 1)
+}
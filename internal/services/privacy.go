@@ -0,0 +1,111 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"sync"
+	"time"
+	"url-shortener/internal/services/dto"
+)
+
+// IPMode is how the client IP of a click is stored.
+type IPMode string
+
+const (
+	// IPFull stores the address as received.
+	IPFull IPMode = "full"
+	// IPTruncate zeroes the host part: IPv4 to /24, IPv6 to /48.
+	IPTruncate IPMode = "truncate"
+	// IPHash stores a keyed hash whose salt rotates every UTC day.
+	IPHash IPMode = "hash"
+	// IPDrop stores no address.
+	IPDrop IPMode = "drop"
+)
+
+// PrivacyPolicy controls what personal data a click keeps. Location and the
+// unique visitor fingerprint are derived from the raw IP before it is
+// anonymized, and the fingerprint itself is never stored.
+type PrivacyPolicy struct {
+	IP IPMode
+	// DropUserAgent discards the raw user agent once it has been parsed into
+	// browser, OS and device.
+	DropUserAgent bool
+	// HonorOptOut records visits sent with DNT: 1 or Sec-GPC: 1 as a bare
+	// click, with no IP, user agent, referrer, location or fingerprint.
+	HonorOptOut bool
+}
+
+func optedOut(visit dto.Visit) bool {
+	return visit.DoNotTrack == "1" || visit.GlobalPrivacyControl == "1"
+}
+
+// ipAnonymizer applies an IPMode. Hash salts are random, kept in memory only
+// and replaced at UTC midnight, so once a day is over its hashes cannot be
+// linked back to an address, nor to the hashes of other days. Instances do
+// not share salts.
+type ipAnonymizer struct {
+	mode IPMode
+	now  func() time.Time
+
+	mu   sync.Mutex
+	day  string
+	salt []byte
+}
+
+func newIPAnonymizer(mode IPMode) *ipAnonymizer {
+	switch mode {
+	case IPFull, IPTruncate, IPHash, IPDrop:
+	case "":
+		mode = IPFull
+	default:
+		// An unknown mode fails closed.
+		mode = IPDrop
+	}
+	return &ipAnonymizer{mode: mode, now: time.Now}
+}
+
+func (a *ipAnonymizer) anonymize(ip string) string {
+	switch a.mode {
+	case IPFull:
+		return ip
+	case IPTruncate:
+		return truncateIP(ip)
+	case IPHash:
+		if ip == "" {
+			return ""
+		}
+		mac := hmac.New(sha256.New, a.dailySalt())
+		mac.Write([]byte(ip))
+		return "h:" + hex.EncodeToString(mac.Sum(nil)[:16])
+	default:
+		return ""
+	}
+}
+
+func (a *ipAnonymizer) dailySalt() []byte {
+	day := a.now().UTC().Format("2006-01-02")
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if day != a.day {
+		salt := make([]byte, 32)
+		_, _ = rand.Read(salt)
+		a.day, a.salt = day, salt
+	}
+	return a.salt
+}
+
+// truncateIP keeps the network of an address; anything unparsable is dropped.
+func truncateIP(ip string) string {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return ""
+	}
+	if v4 := addr.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return addr.Mask(net.CIDRMask(48, 128)).String()
+}
@@ -0,0 +1,98 @@
+package services_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/services"
+	"url-shortener/internal/services/dto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func resolveWithPrivacy(t *testing.T, policy services.PrivacyPolicy, visits ...dto.Visit) []*entity.URLStat {
+	t.Helper()
+	urlRepo := new(MockURLRepo)
+	recorder := &captureRecorder{}
+	svc := services.NewURLService(urlRepo, new(MockIDGen), new(MockStatsRepo),
+		services.WithClickRecorder(recorder), services.WithPrivacy(policy))
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{ID: "url1"}, nil)
+
+	for _, visit := range visits {
+		_, err := svc.Resolve(context.Background(), "url1", visit)
+		assert.NoError(t, err)
+	}
+	return recorder.stats
+}
+
+func TestURLService_Privacy_TruncatesIPs(t *testing.T) {
+	stats := resolveWithPrivacy(t, services.PrivacyPolicy{IP: services.IPTruncate},
+		dto.Visit{IP: "203.0.113.77"},
+		dto.Visit{IP: "2001:db8:1234:5678::1"},
+		dto.Visit{IP: "garbage"},
+	)
+
+	assert.Equal(t, "203.0.113.0", stats[0].IP)
+	assert.Equal(t, "2001:db8:1234::", stats[1].IP)
+	assert.Equal(t, "", stats[2].IP)
+}
+
+func TestURLService_Privacy_HashesIPs(t *testing.T) {
+	stats := resolveWithPrivacy(t, services.PrivacyPolicy{IP: services.IPHash},
+		dto.Visit{IP: "203.0.113.77"},
+		dto.Visit{IP: "203.0.113.77"},
+		dto.Visit{IP: "203.0.113.78"},
+	)
+
+	assert.True(t, strings.HasPrefix(stats[0].IP, "h:"))
+	assert.NotContains(t, stats[0].IP, "203.0.113")
+	assert.Equal(t, stats[0].IP, stats[1].IP)
+	assert.NotEqual(t, stats[0].IP, stats[2].IP)
+}
+
+func TestURLService_Privacy_DropsIPAndUserAgent(t *testing.T) {
+	stats := resolveWithPrivacy(t, services.PrivacyPolicy{IP: services.IPDrop, DropUserAgent: true}, browserVisit("203.0.113.77"))
+
+	assert.Equal(t, "", stats[0].IP)
+	assert.Equal(t, "", stats[0].UserAgent)
+	assert.NotEmpty(t, stats[0].Browser)
+}
+
+func TestURLService_Privacy_UnknownModeFailsClosed(t *testing.T) {
+	stats := resolveWithPrivacy(t, services.PrivacyPolicy{IP: "pseudonymize"}, dto.Visit{IP: "203.0.113.77"})
+
+	assert.Equal(t, "", stats[0].IP)
+}
+
+func TestURLService_Privacy_HonorsOptOut(t *testing.T) {
+	optOut := browserVisit("203.0.113.77")
+	optOut.Referer = "https://t.co/x"
+	optOut.GlobalPrivacyControl = "1"
+	dnt := browserVisit("203.0.113.78")
+	dnt.DoNotTrack = "1"
+
+	stats := resolveWithPrivacy(t, services.PrivacyPolicy{IP: services.IPFull, HonorOptOut: true}, optOut, dnt)
+
+	for _, stat := range stats {
+		assert.True(t, stat.OptOut)
+		assert.Equal(t, "human", stat.Class)
+		assert.Equal(t, "", stat.IP)
+		assert.Equal(t, "", stat.UserAgent)
+		assert.Equal(t, "", stat.Referer)
+		assert.Equal(t, "", stat.Browser)
+		assert.Zero(t, stat.VisitorHash)
+	}
+}
+
+func TestURLService_Privacy_OptOutIgnoredWhenDisabled(t *testing.T) {
+	visit := browserVisit("203.0.113.77")
+	visit.DoNotTrack = "1"
+
+	stats := resolveWithPrivacy(t, services.PrivacyPolicy{IP: services.IPFull}, visit)
+
+	assert.False(t, stats[0].OptOut)
+	assert.Equal(t, "203.0.113.77", stats[0].IP)
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"url-shortener/internal/domain/repository"
+	"url-shortener/internal/services/dto"
+)
+
+// ReconcileService compares each link's click_count with the number of raw
+// clicks logged for it, and optionally corrects the counter to match the log.
+type ReconcileService struct {
+	repo      repository.URLRepository
+	statsRepo repository.URLStatsRepository
+	purged    bool
+}
+
+type ReconcileOption func(*ReconcileService)
+
+// WithPurgedClicks tells the reconciler that raw clicks are deleted by a
+// retention policy. A log shorter than the counter is then expected, so only
+// counters that fall short of the log are reported.
+func WithPurgedClicks() ReconcileOption {
+	return func(s *ReconcileService) {
+		s.purged = true
+	}
+}
+
+func NewReconcileService(repo repository.URLRepository, statsRepo repository.URLStatsRepository, opts ...ReconcileOption) *ReconcileService {
+	s := &ReconcileService{
+		repo:      repo,
+		statsRepo: statsRepo,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Reconcile reports every link whose counter disagrees with its click log.
+// With fix set, counters are adjusted by the difference rather than
+// overwritten, so increments landing while the job runs are kept; a batch
+// caught half-written by the run shows up again, and is corrected, on the
+// next one.
+func (s *ReconcileService) Reconcile(ctx context.Context, fix bool) (*dto.ReconcileReport, error) {
+	counters, err := s.repo.ClickCounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	logged, err := s.statsRepo.CountByURL(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &dto.ReconcileReport{Checked: len(counters)}
+	for id, counter := range counters {
+		if logged[id] == counter || (s.purged && logged[id] < counter) {
+			continue
+		}
+		report.Discrepancies = append(report.Discrepancies, dto.ClickDiscrepancy{
+			URLID:   id,
+			Counter: counter,
+			Logged:  logged[id],
+			Diff:    logged[id] - counter,
+		})
+	}
+	sort.Slice(report.Discrepancies, func(i, j int) bool {
+		return report.Discrepancies[i].URLID < report.Discrepancies[j].URLID
+	})
+
+	if !fix {
+		return report, nil
+	}
+
+	for _, d := range report.Discrepancies {
+		if err := s.repo.AdjustClickCount(ctx, d.URLID, d.Diff); err != nil {
+			return report, err
+		}
+		report.Fixed++
+	}
+
+	return report, nil
+}
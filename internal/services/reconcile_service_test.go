@@ -0,0 +1,82 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"url-shortener/internal/services"
+	"url-shortener/internal/services/dto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestReconcileService_ReportsDiscrepancies(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	statsRepo := new(MockStatsRepo)
+
+	svc := services.NewReconcileService(urlRepo, statsRepo)
+
+	urlRepo.On("ClickCounts", mock.Anything).Return(map[string]int{"a": 5, "b": 3, "c": 2}, nil)
+	statsRepo.On("CountByURL", mock.Anything).Return(map[string]int{"a": 5, "b": 4}, nil)
+
+	report, err := svc.Reconcile(context.Background(), false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, report.Checked)
+	assert.Equal(t, []dto.ClickDiscrepancy{
+		{URLID: "b", Counter: 3, Logged: 4, Diff: 1},
+		{URLID: "c", Counter: 2, Logged: 0, Diff: -2},
+	}, report.Discrepancies)
+	assert.Equal(t, 0, report.Fixed)
+	urlRepo.AssertNotCalled(t, "AdjustClickCount", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestReconcileService_FixAdjustsCounters(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	statsRepo := new(MockStatsRepo)
+
+	svc := services.NewReconcileService(urlRepo, statsRepo)
+
+	urlRepo.On("ClickCounts", mock.Anything).Return(map[string]int{"a": 5, "b": 3}, nil)
+	statsRepo.On("CountByURL", mock.Anything).Return(map[string]int{"a": 6, "b": 3}, nil)
+	urlRepo.On("AdjustClickCount", mock.Anything, "a", 1).Return(nil)
+
+	report, err := svc.Reconcile(context.Background(), true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Fixed)
+	urlRepo.AssertExpectations(t)
+}
+
+func TestReconcileService_PurgedClicksOnlyReportsShortfalls(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	statsRepo := new(MockStatsRepo)
+
+	svc := services.NewReconcileService(urlRepo, statsRepo, services.WithPurgedClicks())
+
+	urlRepo.On("ClickCounts", mock.Anything).Return(map[string]int{"a": 50, "b": 3}, nil)
+	statsRepo.On("CountByURL", mock.Anything).Return(map[string]int{"a": 10, "b": 4}, nil)
+	urlRepo.On("AdjustClickCount", mock.Anything, "b", 1).Return(nil)
+
+	report, err := svc.Reconcile(context.Background(), true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []dto.ClickDiscrepancy{{URLID: "b", Counter: 3, Logged: 4, Diff: 1}}, report.Discrepancies)
+	urlRepo.AssertExpectations(t)
+}
+
+func TestReconcileService_StatsRepoError(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	statsRepo := new(MockStatsRepo)
+
+	svc := services.NewReconcileService(urlRepo, statsRepo)
+
+	urlRepo.On("ClickCounts", mock.Anything).Return(map[string]int{"a": 5}, nil)
+	statsRepo.On("CountByURL", mock.Anything).Return(nil, errors.New("stats error"))
+
+	report, err := svc.Reconcile(context.Background(), true)
+
+	assert.EqualError(t, err, "stats error")
+	assert.Nil(t, report)
+}
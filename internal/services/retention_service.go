@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"time"
+	"url-shortener/internal/domain/repository"
+	"url-shortener/internal/services/dto"
+)
+
+// RetentionPolicy is how long raw clicks are kept. Zero keeps them forever.
+// Accounts overrides Default per owner, and may itself be zero. MinAge, when
+// set, raises any shorter positive retention to it.
+type RetentionPolicy struct {
+	Default  time.Duration
+	Accounts map[string]time.Duration
+	MinAge   time.Duration
+}
+
+func (p RetentionPolicy) For(ownerID string) time.Duration {
+	if d, ok := p.Accounts[ownerID]; ok {
+		return p.clamp(d)
+	}
+	return p.clamp(p.Default)
+}
+
+func (p RetentionPolicy) clamp(d time.Duration) time.Duration {
+	if d > 0 && d < p.MinAge {
+		return p.MinAge
+	}
+	return d
+}
+
+// MinRetention is the shortest retention that lets the rollup job fold an
+// hour of clicks, lag after it ends, before they expire. The extra hour
+// covers the hour being folded plus a late run.
+func MinRetention(lag time.Duration) time.Duration {
+	return lag + 2*time.Hour
+}
+
+// Enabled reports whether any raw click can ever be deleted.
+func (p RetentionPolicy) Enabled() bool {
+	if p.Default > 0 {
+		return true
+	}
+	for _, d := range p.Accounts {
+		if d > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// RetentionService purges raw clicks past their retention. New clicks carry
+// an expire_at that a TTL index enforces, so the purge only matters for
+// clicks recorded before retention was configured or before it was
+// shortened. Link counters and unique visitor sketches are never purged.
+type RetentionService struct {
+	repo      repository.URLRepository
+	statsRepo repository.URLStatsRepository
+	rollups   repository.RollupRepository
+	policy    RetentionPolicy
+}
+
+type RetentionOption func(*RetentionService)
+
+// WithRollupCheckpoint never purges clicks the rollup job has not folded yet,
+// since stats read those hours from the raw clicks.
+func WithRollupCheckpoint(rollups repository.RollupRepository) RetentionOption {
+	return func(s *RetentionService) {
+		s.rollups = rollups
+	}
+}
+
+func NewRetentionService(repo repository.URLRepository, statsRepo repository.URLStatsRepository, policy RetentionPolicy, opts ...RetentionOption) *RetentionService {
+	s := &RetentionService{
+		repo:      repo,
+		statsRepo: statsRepo,
+		policy:    policy,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *RetentionService) Purge(ctx context.Context, now time.Time) (*dto.PurgeReport, error) {
+	report := &dto.PurgeReport{}
+
+	var checkpoint time.Time
+	if s.rollups != nil {
+		var err error
+		if checkpoint, err = s.rollups.Checkpoint(ctx); err != nil {
+			return report, err
+		}
+		if checkpoint.IsZero() {
+			return report, nil
+		}
+	}
+	cutoff := func(retention time.Duration) time.Time {
+		before := now.Add(-retention)
+		if !checkpoint.IsZero() && checkpoint.Before(before) {
+			return checkpoint
+		}
+		return before
+	}
+
+	owners := make([]string, 0, len(s.policy.Accounts))
+	for owner := range s.policy.Accounts {
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+
+	var overridden []string
+	for _, owner := range owners {
+		urls, err := s.repo.FindByOwner(ctx, owner)
+		if err != nil {
+			return report, err
+		}
+		ids := make([]string, 0, len(urls))
+		for _, url := range urls {
+			ids = append(ids, url.ID)
+		}
+		overridden = append(overridden, ids...)
+
+		retention := s.policy.For(owner)
+		if retention <= 0 || len(ids) == 0 {
+			continue
+		}
+		deleted, err := s.statsRepo.DeleteBefore(ctx, repository.PurgeQuery{URLIDs: ids, Before: cutoff(retention)})
+		if err != nil {
+			return report, err
+		}
+		report.Scopes = append(report.Scopes, dto.PurgeScope{Owner: owner, Retention: retention.String(), Deleted: deleted})
+		report.Deleted += deleted
+	}
+
+	if retention := s.policy.clamp(s.policy.Default); retention > 0 {
+		deleted, err := s.statsRepo.DeleteBefore(ctx, repository.PurgeQuery{ExcludeURLIDs: overridden, Before: cutoff(retention)})
+		if err != nil {
+			return report, err
+		}
+		report.Scopes = append(report.Scopes, dto.PurgeScope{Retention: retention.String(), Deleted: deleted})
+		report.Deleted += deleted
+	}
+
+	return report, nil
+}
@@ -0,0 +1,122 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/repository"
+	"url-shortener/internal/services"
+	"url-shortener/internal/services/dto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRetentionService_PurgesPerAccountThenGlobally(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	statsRepo := new(MockStatsRepo)
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	svc := services.NewRetentionService(urlRepo, statsRepo, services.RetentionPolicy{
+		Default:  90 * 24 * time.Hour,
+		Accounts: map[string]time.Duration{"short": 24 * time.Hour, "forever": 0},
+	})
+
+	urlRepo.On("FindByOwner", mock.Anything, "forever").Return([]entity.URL{{ID: "f1"}}, nil)
+	urlRepo.On("FindByOwner", mock.Anything, "short").Return([]entity.URL{{ID: "s1"}, {ID: "s2"}}, nil)
+	statsRepo.On("DeleteBefore", mock.Anything, repository.PurgeQuery{
+		URLIDs: []string{"s1", "s2"},
+		Before: now.Add(-24 * time.Hour),
+	}).Return(7, nil)
+	statsRepo.On("DeleteBefore", mock.Anything, repository.PurgeQuery{
+		ExcludeURLIDs: []string{"f1", "s1", "s2"},
+		Before:        now.Add(-90 * 24 * time.Hour),
+	}).Return(100, nil)
+
+	report, err := svc.Purge(context.Background(), now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 107, report.Deleted)
+	assert.Equal(t, []dto.PurgeScope{
+		{Owner: "short", Retention: "24h0m0s", Deleted: 7},
+		{Retention: "2160h0m0s", Deleted: 100},
+	}, report.Scopes)
+}
+
+func TestRetentionService_KeepsClicksPastRollupCheckpoint(t *testing.T) {
+	statsRepo := new(MockStatsRepo)
+	rollups := new(MockRollupRepo)
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	checkpoint := now.Add(-36 * time.Hour)
+
+	svc := services.NewRetentionService(new(MockURLRepo), statsRepo, services.RetentionPolicy{
+		Default: time.Hour,
+		MinAge:  services.MinRetention(2 * time.Minute),
+	}, services.WithRollupCheckpoint(rollups))
+
+	rollups.On("Checkpoint", mock.Anything).Return(checkpoint, nil)
+	statsRepo.On("DeleteBefore", mock.Anything, repository.PurgeQuery{Before: checkpoint}).Return(3, nil)
+
+	report, err := svc.Purge(context.Background(), now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []dto.PurgeScope{{Retention: "2h2m0s", Deleted: 3}}, report.Scopes)
+}
+
+func TestRetentionService_WaitsForFirstRollup(t *testing.T) {
+	statsRepo := new(MockStatsRepo)
+	rollups := new(MockRollupRepo)
+	svc := services.NewRetentionService(new(MockURLRepo), statsRepo, services.RetentionPolicy{Default: 24 * time.Hour}, services.WithRollupCheckpoint(rollups))
+
+	rollups.On("Checkpoint", mock.Anything).Return(time.Time{}, nil)
+
+	report, err := svc.Purge(context.Background(), time.Now())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, report.Deleted)
+	statsRepo.AssertNotCalled(t, "DeleteBefore", mock.Anything, mock.Anything)
+}
+
+func TestRetentionPolicy_MinAge(t *testing.T) {
+	policy := services.RetentionPolicy{
+		Default:  time.Hour,
+		Accounts: map[string]time.Duration{"forever": 0, "long": 48 * time.Hour},
+		MinAge:   3 * time.Hour,
+	}
+
+	assert.Equal(t, 3*time.Hour, policy.For("anyone"))
+	assert.Equal(t, time.Duration(0), policy.For("forever"))
+	assert.Equal(t, 48*time.Hour, policy.For("long"))
+}
+
+func TestRetentionService_NothingToPurge(t *testing.T) {
+	statsRepo := new(MockStatsRepo)
+	svc := services.NewRetentionService(new(MockURLRepo), statsRepo, services.RetentionPolicy{})
+
+	report, err := svc.Purge(context.Background(), time.Now())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, report.Deleted)
+	statsRepo.AssertNotCalled(t, "DeleteBefore", mock.Anything, mock.Anything)
+}
+
+func TestURLService_Resolve_StampsRetention(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	recorder := &captureRecorder{}
+	svc := services.NewURLService(urlRepo, new(MockIDGen), new(MockStatsRepo),
+		services.WithClickRecorder(recorder),
+		services.WithRetention(services.RetentionPolicy{
+			Default:  30 * 24 * time.Hour,
+			Accounts: map[string]time.Duration{"vip": 0},
+		}))
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{ID: "url1", OwnerID: "owner1"}, nil)
+	urlRepo.On("FindByID", mock.Anything, "url2").Return(&entity.URL{ID: "url2", OwnerID: "vip"}, nil)
+
+	_, _ = svc.Resolve(context.Background(), "url1", dto.Visit{})
+	_, _ = svc.Resolve(context.Background(), "url2", dto.Visit{})
+
+	assert.Equal(t, recorder.stats[0].ClickedAt.Add(30*24*time.Hour), recorder.stats[0].ExpireAt)
+	assert.True(t, recorder.stats[1].ExpireAt.IsZero())
+}
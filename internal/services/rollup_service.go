@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/repository"
+	"url-shortener/pkg/botdetect"
+)
+
+type RollupConfig struct {
+	// Interval is how often the background job looks for new hours to fold.
+	Interval time.Duration
+	// Lag keeps the job away from hours whose clicks may still be sitting in
+	// the click pipeline.
+	Lag time.Duration
+	// MaxHours bounds the hours folded per run, so a long backfill proceeds
+	// in steps that each leave a checkpoint behind.
+	MaxHours int
+	// Owner identifies this replica in the job lease.
+	Owner    string
+	LeaseTTL time.Duration
+}
+
+// RollupService folds raw clicks into hourly and daily rollups. Each hour is
+// recomputed from the raw clicks and written over its previous rollups, and
+// each daily rollup is recomputed from its hourly ones, so runs are
+// idempotent. Progress is checkpointed after every hour and a lease keeps
+// replicas from working at the same time.
+type RollupService struct {
+	statsRepo repository.URLStatsRepository
+	rollups   repository.RollupRepository
+	cfg       RollupConfig
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+func NewRollupService(statsRepo repository.URLStatsRepository, rollups repository.RollupRepository, cfg RollupConfig) *RollupService {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.Lag <= 0 {
+		cfg.Lag = 2 * time.Minute
+	}
+	if cfg.MaxHours <= 0 {
+		cfg.MaxHours = 24
+	}
+	if cfg.LeaseTTL <= 0 {
+		cfg.LeaseTTL = 5 * time.Minute
+	}
+	return &RollupService{
+		statsRepo: statsRepo,
+		rollups:   rollups,
+		cfg:       cfg,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Run folds the complete hours since the checkpoint, up to MaxHours, and
+// returns how many it folded. It does nothing while another replica holds
+// the lease.
+func (s *RollupService) Run(ctx context.Context, now time.Time) (int, error) {
+	ok, err := s.rollups.AcquireLease(ctx, s.cfg.Owner, s.cfg.LeaseTTL)
+	if err != nil || !ok {
+		return 0, err
+	}
+	defer func() {
+		if err := s.rollups.ReleaseLease(context.WithoutCancel(ctx), s.cfg.Owner); err != nil {
+			log.Printf("rollup: release lease: %v", err)
+		}
+	}()
+
+	hour, err := s.rollups.Checkpoint(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if hour.IsZero() {
+		oldest, err := s.statsRepo.OldestClick(ctx)
+		if err != nil || oldest.IsZero() {
+			return 0, err
+		}
+		hour = oldest.UTC().Truncate(time.Hour)
+	}
+
+	end := now.Add(-s.cfg.Lag).UTC().Truncate(time.Hour)
+	folded := 0
+	for ; hour.Before(end) && folded < s.cfg.MaxHours; hour = hour.Add(time.Hour) {
+		if err := s.foldHour(ctx, hour); err != nil {
+			return folded, err
+		}
+		if err := s.rollups.SetCheckpoint(ctx, hour.Add(time.Hour)); err != nil {
+			return folded, err
+		}
+		folded++
+
+		if ok, err := s.rollups.AcquireLease(ctx, s.cfg.Owner, s.cfg.LeaseTTL); err != nil || !ok {
+			return folded, err
+		}
+	}
+
+	return folded, nil
+}
+
+func (s *RollupService) foldHour(ctx context.Context, hour time.Time) error {
+	byLink := make(map[string]*entity.Rollup)
+	err := s.statsRepo.Stream(ctx, repository.ExportQuery{From: hour, To: hour.Add(time.Hour)}, func(stat *entity.URLStat) error {
+		r, ok := byLink[stat.URLID]
+		if !ok {
+			r = newRollup(stat.URLID, entity.RollupHour, hour)
+			byLink[stat.URLID] = r
+		}
+		switch stat.Class {
+		case botdetect.ClassBot:
+			r.Bots++
+		case botdetect.ClassPreview:
+			r.Previews++
+		default:
+			r.Human++
+			r.Countries[stat.Country]++
+			r.Devices[stat.Device]++
+			r.Referrers[stat.RefererHost]++
+		}
+		return nil
+	})
+	if err != nil || len(byLink) == 0 {
+		return err
+	}
+
+	hourly := make([]entity.Rollup, 0, len(byLink))
+	urlIDs := make([]string, 0, len(byLink))
+	for id, r := range byLink {
+		hourly = append(hourly, *r)
+		urlIDs = append(urlIDs, id)
+	}
+	if err := s.rollups.Upsert(ctx, hourly); err != nil {
+		return err
+	}
+
+	day := hour.Truncate(24 * time.Hour)
+	hours, err := s.rollups.FindHourly(ctx, urlIDs, day, day.Add(24*time.Hour))
+	if err != nil {
+		return err
+	}
+	return s.rollups.Upsert(ctx, sumRollups(hours, entity.RollupDay, day))
+}
+
+// Start runs the job every Interval until Close.
+func (s *RollupService) Start() {
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), s.cfg.LeaseTTL)
+			if n, err := s.Run(ctx, time.Now()); err != nil {
+				log.Printf("rollup: %v", err)
+			} else if n > 0 {
+				log.Printf("rollup: folded %d hours", n)
+			}
+			cancel()
+
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Close stops a started job, waiting for the current run to finish.
+func (s *RollupService) Close(ctx context.Context) error {
+	s.once.Do(func() { close(s.stop) })
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func newRollup(urlID, granularity string, start time.Time) *entity.Rollup {
+	return &entity.Rollup{
+		URLID:       urlID,
+		Granularity: granularity,
+		Start:       start,
+		Countries:   make(map[string]int),
+		Devices:     make(map[string]int),
+		Referrers:   make(map[string]int),
+	}
+}
+
+// sumRollups adds up rollups per link into rollups of the given granularity
+// starting at start.
+func sumRollups(rollups []entity.Rollup, granularity string, start time.Time) []entity.Rollup {
+	byLink := make(map[string]*entity.Rollup)
+	var order []string
+	for _, r := range rollups {
+		sum, ok := byLink[r.URLID]
+		if !ok {
+			sum = newRollup(r.URLID, granularity, start)
+			byLink[r.URLID] = sum
+			order = append(order, r.URLID)
+		}
+		sum.Human += r.Human
+		sum.Bots += r.Bots
+		sum.Previews += r.Previews
+		for k, n := range r.Countries {
+			sum.Countries[k] += n
+		}
+		for k, n := range r.Devices {
+			sum.Devices[k] += n
+		}
+		for k, n := range r.Referrers {
+			sum.Referrers[k] += n
+		}
+	}
+
+	result := make([]entity.Rollup, 0, len(order))
+	for _, id := range order {
+		result = append(result, *byLink[id])
+	}
+	return result
+}
@@ -0,0 +1,293 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/repository"
+	"url-shortener/internal/services"
+	"url-shortener/pkg/botdetect"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRollupRepo struct {
+	mock.Mock
+}
+
+func (m *MockRollupRepo) Upsert(ctx context.Context, rollups []entity.Rollup) error {
+	args := m.Called(ctx, rollups)
+	return args.Error(0)
+}
+
+func (m *MockRollupRepo) FindHourly(ctx context.Context, urlIDs []string, from, to time.Time) ([]entity.Rollup, error) {
+	args := m.Called(ctx, urlIDs, from, to)
+	if args.Get(0) != nil {
+		return args.Get(0).([]entity.Rollup), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockRollupRepo) ActiveLinks(ctx context.Context, from, to time.Time) ([]string, error) {
+	args := m.Called(ctx, from, to)
+	if args.Get(0) != nil {
+		return args.Get(0).([]string), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockRollupRepo) Checkpoint(ctx context.Context) (time.Time, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+func (m *MockRollupRepo) SetCheckpoint(ctx context.Context, through time.Time) error {
+	args := m.Called(ctx, through)
+	return args.Error(0)
+}
+
+func (m *MockRollupRepo) AcquireLease(ctx context.Context, owner string, ttl time.Duration) (bool, error) {
+	args := m.Called(ctx, owner, ttl)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRollupRepo) ReleaseLease(ctx context.Context, owner string) error {
+	args := m.Called(ctx, owner)
+	return args.Error(0)
+}
+
+func (m *MockRollupRepo) TimeSeries(ctx context.Context, query repository.TimeSeriesQuery) ([]entity.ClickBucket, error) {
+	args := m.Called(ctx, query)
+	if args.Get(0) != nil {
+		return args.Get(0).([]entity.ClickBucket), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockRollupRepo) Breakdown(ctx context.Context, query repository.BreakdownQuery) ([]entity.BreakdownRow, int, error) {
+	args := m.Called(ctx, query)
+	if args.Get(0) != nil {
+		return args.Get(0).([]entity.BreakdownRow), args.Int(1), args.Error(2)
+	}
+	return nil, args.Int(1), args.Error(2)
+}
+
+func TestRollupService_FoldsFromOldestClick(t *testing.T) {
+	statsRepo := new(MockStatsRepo)
+	rollups := new(MockRollupRepo)
+	hour := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	now := hour.Add(time.Hour + 5*time.Minute)
+
+	rollups.On("AcquireLease", mock.Anything, "a", mock.Anything).Return(true, nil)
+	rollups.On("ReleaseLease", mock.Anything, "a").Return(nil)
+	rollups.On("Checkpoint", mock.Anything).Return(time.Time{}, nil)
+	statsRepo.On("OldestClick", mock.Anything).Return(hour.Add(20*time.Minute), nil)
+	statsRepo.On("Stream", mock.Anything, repository.ExportQuery{From: hour, To: hour.Add(time.Hour)}).Return([]entity.URLStat{
+		{URLID: "abc", Class: botdetect.ClassHuman, Country: "BR", Device: "mobile", RefererHost: "google.com"},
+		{URLID: "abc", Country: "BR", Device: "desktop"},
+		{URLID: "abc", Class: botdetect.ClassBot, Country: "US"},
+		{URLID: "abc", Class: botdetect.ClassPreview},
+	}, nil)
+
+	hourly := entity.Rollup{
+		URLID: "abc", Granularity: entity.RollupHour, Start: hour,
+		Human: 2, Bots: 1, Previews: 1,
+		Countries: map[string]int{"BR": 2},
+		Devices:   map[string]int{"mobile": 1, "desktop": 1},
+		Referrers: map[string]int{"google.com": 1, "": 1},
+	}
+	earlier := entity.Rollup{
+		URLID: "abc", Granularity: entity.RollupHour, Start: hour.Add(-time.Hour),
+		Human: 3, Countries: map[string]int{"BR": 3}, Devices: map[string]int{}, Referrers: map[string]int{},
+	}
+	day := hour.Truncate(24 * time.Hour)
+
+	rollups.On("Upsert", mock.Anything, []entity.Rollup{hourly}).Return(nil).Once()
+	rollups.On("FindHourly", mock.Anything, []string{"abc"}, day, day.Add(24*time.Hour)).Return([]entity.Rollup{earlier, hourly}, nil)
+	rollups.On("Upsert", mock.Anything, []entity.Rollup{{
+		URLID: "abc", Granularity: entity.RollupDay, Start: day,
+		Human: 5, Bots: 1, Previews: 1,
+		Countries: map[string]int{"BR": 5},
+		Devices:   map[string]int{"mobile": 1, "desktop": 1},
+		Referrers: map[string]int{"google.com": 1, "": 1},
+	}}).Return(nil).Once()
+	rollups.On("SetCheckpoint", mock.Anything, hour.Add(time.Hour)).Return(nil)
+
+	svc := services.NewRollupService(statsRepo, rollups, services.RollupConfig{Owner: "a"})
+	n, err := svc.Run(context.Background(), now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	rollups.AssertExpectations(t)
+}
+
+func TestRollupService_ResumesFromCheckpointUpToLag(t *testing.T) {
+	statsRepo := new(MockStatsRepo)
+	rollups := new(MockRollupRepo)
+	checkpoint := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	now := checkpoint.Add(3*time.Hour + time.Minute)
+
+	rollups.On("AcquireLease", mock.Anything, "a", mock.Anything).Return(true, nil)
+	rollups.On("ReleaseLease", mock.Anything, "a").Return(nil)
+	rollups.On("Checkpoint", mock.Anything).Return(checkpoint, nil)
+	statsRepo.On("Stream", mock.Anything, mock.Anything).Return(nil, nil)
+	rollups.On("SetCheckpoint", mock.Anything, mock.Anything).Return(nil)
+
+	svc := services.NewRollupService(statsRepo, rollups, services.RollupConfig{Owner: "a", Lag: 2 * time.Minute})
+	n, err := svc.Run(context.Background(), now)
+
+	assert.NoError(t, err)
+	// The hour starting at 12:00 is still within the lag of 13:01.
+	assert.Equal(t, 2, n)
+	rollups.AssertCalled(t, "SetCheckpoint", mock.Anything, checkpoint.Add(2*time.Hour))
+	rollups.AssertNotCalled(t, "SetCheckpoint", mock.Anything, checkpoint.Add(3*time.Hour))
+	statsRepo.AssertNotCalled(t, "OldestClick", mock.Anything)
+	rollups.AssertNotCalled(t, "Upsert", mock.Anything, mock.Anything)
+}
+
+func TestRollupService_SkipsWhenLeaseIsHeld(t *testing.T) {
+	statsRepo := new(MockStatsRepo)
+	rollups := new(MockRollupRepo)
+	rollups.On("AcquireLease", mock.Anything, "b", mock.Anything).Return(false, nil)
+
+	svc := services.NewRollupService(statsRepo, rollups, services.RollupConfig{Owner: "b"})
+	n, err := svc.Run(context.Background(), time.Now())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	rollups.AssertNotCalled(t, "Checkpoint", mock.Anything)
+	rollups.AssertNotCalled(t, "ReleaseLease", mock.Anything, mock.Anything)
+}
+
+func TestRolledUpStats_TimeSeriesSplitsAroundCheckpoint(t *testing.T) {
+	raw := new(MockStatsRepo)
+	rollups := new(MockRollupRepo)
+	from := time.Date(2025, 6, 1, 9, 30, 0, 0, time.UTC)
+	to := time.Date(2025, 6, 1, 14, 0, 0, 0, time.UTC)
+	checkpoint := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	query := repository.TimeSeriesQuery{URLIDs: []string{"abc"}, Interval: repository.IntervalDay, From: from, To: to, Location: time.UTC}
+
+	rollups.On("Checkpoint", mock.Anything).Return(checkpoint, nil)
+	rolled := query
+	rolled.From, rolled.To = from.Add(30*time.Minute), checkpoint
+	rollups.On("TimeSeries", mock.Anything, rolled).Return([]entity.ClickBucket{{Start: from.Truncate(24 * time.Hour), Clicks: 10}}, nil)
+	head := query
+	head.To = rolled.From
+	raw.On("TimeSeries", mock.Anything, head).Return([]entity.ClickBucket{{Start: from.Truncate(24 * time.Hour), Clicks: 1}}, nil)
+	tail := query
+	tail.From = checkpoint
+	raw.On("TimeSeries", mock.Anything, tail).Return([]entity.ClickBucket{{Start: from.Truncate(24 * time.Hour), Clicks: 2}}, nil)
+
+	buckets, err := services.NewRolledUpStats(raw, rollups).TimeSeries(context.Background(), query)
+
+	assert.NoError(t, err)
+	total := 0
+	for _, b := range buckets {
+		total += b.Clicks
+	}
+	assert.Equal(t, 13, total)
+	raw.AssertExpectations(t)
+}
+
+func TestRolledUpStats_FractionalOffsetsReadRawClicks(t *testing.T) {
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Skip("tzdata not available")
+	}
+	raw := new(MockStatsRepo)
+	rollups := new(MockRollupRepo)
+	rollups.On("Checkpoint", mock.Anything).Return(time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC), nil)
+
+	query := repository.TimeSeriesQuery{
+		URLIDs:   []string{"abc"},
+		Interval: repository.IntervalDay,
+		From:     time.Date(2025, 6, 1, 0, 0, 0, 0, kolkata),
+		To:       time.Date(2025, 6, 8, 0, 0, 0, 0, kolkata),
+		Location: kolkata,
+	}
+	raw.On("TimeSeries", mock.Anything, query).Return([]entity.ClickBucket{{Start: query.From, Clicks: 4}}, nil)
+
+	buckets, err := services.NewRolledUpStats(raw, rollups).TimeSeries(context.Background(), query)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []entity.ClickBucket{{Start: query.From, Clicks: 4}}, buckets)
+	rollups.AssertNotCalled(t, "TimeSeries", mock.Anything, mock.Anything)
+}
+
+func TestRolledUpStats_FractionalOffsetLaterInRange(t *testing.T) {
+	// Lord Howe Island is +10:30 in winter and +11 in summer.
+	lordHowe, err := time.LoadLocation("Australia/Lord_Howe")
+	if err != nil {
+		t.Skip("tzdata not available")
+	}
+	raw := new(MockStatsRepo)
+	rollups := new(MockRollupRepo)
+	rollups.On("Checkpoint", mock.Anything).Return(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), nil)
+
+	query := repository.TimeSeriesQuery{
+		URLIDs:   []string{"abc"},
+		Interval: repository.IntervalWeek,
+		From:     time.Date(2025, 3, 1, 0, 0, 0, 0, lordHowe),
+		To:       time.Date(2025, 5, 1, 0, 0, 0, 0, lordHowe),
+		Location: lordHowe,
+	}
+	raw.On("TimeSeries", mock.Anything, query).Return([]entity.ClickBucket{}, nil)
+
+	_, err = services.NewRolledUpStats(raw, rollups).TimeSeries(context.Background(), query)
+
+	assert.NoError(t, err)
+	rollups.AssertNotCalled(t, "TimeSeries", mock.Anything, mock.Anything)
+}
+
+func TestRolledUpStats_BreakdownMergesSegments(t *testing.T) {
+	raw := new(MockStatsRepo)
+	rollups := new(MockRollupRepo)
+	from := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	checkpoint := from.Add(5 * time.Hour)
+	query := repository.BreakdownQuery{URLIDs: []string{"abc"}, From: from, Dimension: repository.DimensionCountry, Limit: 2}
+
+	rollups.On("Checkpoint", mock.Anything).Return(checkpoint, nil)
+	rollups.On("Breakdown", mock.Anything, repository.BreakdownQuery{
+		URLIDs: []string{"abc"}, From: from, To: checkpoint, Dimension: repository.DimensionCountry,
+	}).Return([]entity.BreakdownRow{{Key: "BR", Clicks: 5}, {Key: "US", Clicks: 4}, {Key: "PT", Clicks: 1}}, 10, nil)
+	raw.On("Breakdown", mock.Anything, mock.MatchedBy(func(q repository.BreakdownQuery) bool {
+		return q.From.Equal(checkpoint) && q.To.IsZero()
+	})).Return([]entity.BreakdownRow{{Key: "US", Clicks: 3}}, 3, nil)
+
+	rows, total, err := services.NewRolledUpStats(raw, rollups).Breakdown(context.Background(), query)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 13, total)
+	assert.Equal(t, []entity.BreakdownRow{{Key: "US", Clicks: 7}, {Key: "BR", Clicks: 5}}, rows)
+}
+
+func TestRolledUpStats_FallsBackToRawClicks(t *testing.T) {
+	raw := new(MockStatsRepo)
+	rollups := new(MockRollupRepo)
+	from := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	rollups.On("Checkpoint", mock.Anything).Return(from.Add(5*time.Hour), nil)
+
+	stats := services.NewRolledUpStats(raw, rollups)
+
+	browsers := repository.BreakdownQuery{URLIDs: []string{"abc"}, From: from, Dimension: repository.DimensionBrowser, Limit: 10}
+	raw.On("Breakdown", mock.Anything, browsers).Return([]entity.BreakdownRow{{Key: "Chrome", Clicks: 1}}, 1, nil)
+	rows, _, err := stats.Breakdown(context.Background(), browsers)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+
+	withBots := repository.BreakdownQuery{URLIDs: []string{"abc"}, From: from, Dimension: repository.DimensionCountry, Limit: 10, IncludeBots: true}
+	raw.On("Breakdown", mock.Anything, withBots).Return([]entity.BreakdownRow{}, 0, nil)
+	_, _, err = stats.Breakdown(context.Background(), withBots)
+	assert.NoError(t, err)
+
+	// Nothing rolled up yet within the range.
+	recent := repository.TimeSeriesQuery{URLIDs: []string{"abc"}, Interval: repository.IntervalHour, From: from.Add(6 * time.Hour), Location: time.UTC}
+	raw.On("TimeSeries", mock.Anything, recent).Return([]entity.ClickBucket{}, nil)
+	_, err = stats.TimeSeries(context.Background(), recent)
+	assert.NoError(t, err)
+
+	rollups.AssertNotCalled(t, "Breakdown", mock.Anything, mock.Anything)
+	rollups.AssertNotCalled(t, "TimeSeries", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/repository"
+)
+
+// rawSegmentRows is the row limit of breakdowns over the raw clicks around a
+// rollup range. Those segments span at most a few hours, so the limit only
+// guards against pathological cardinality.
+const rawSegmentRows = 10000
+
+// rollupDimensions are the breakdowns the rollups can serve.
+var rollupDimensions = map[repository.Dimension]bool{
+	repository.DimensionCountry:     true,
+	repository.DimensionDevice:      true,
+	repository.DimensionRefererHost: true,
+	repository.DimensionLink:        true,
+}
+
+// rolledUpStats serves time series and breakdowns from the rollups for the
+// whole hours before the rollup checkpoint, and from raw clicks for the rest:
+// the partial hour a range starts in and everything after the checkpoint.
+// Queries the rollups cannot answer go to the raw clicks entirely.
+type rolledUpStats struct {
+	repository.URLStatsRepository
+	rollups repository.RollupRepository
+}
+
+// NewRolledUpStats wraps raw so that the stats reads it can serve come from
+// rollups. Writes and click log reads pass through to raw.
+func NewRolledUpStats(raw repository.URLStatsRepository, rollups repository.RollupRepository) repository.URLStatsRepository {
+	return &rolledUpStats{URLStatsRepository: raw, rollups: rollups}
+}
+
+// segments splits [from, to) around the whole hours [h0, h1) before the
+// checkpoint. ok is false when there are none.
+func (r *rolledUpStats) segments(ctx context.Context, from, to time.Time) (h0, h1 time.Time, ok bool, err error) {
+	checkpoint, err := r.rollups.Checkpoint(ctx)
+	if err != nil || checkpoint.IsZero() {
+		return h0, h1, false, err
+	}
+
+	h0 = from.UTC().Truncate(time.Hour)
+	if h0.Before(from) {
+		h0 = h0.Add(time.Hour)
+	}
+	h1 = checkpoint
+	if !to.IsZero() && to.Before(h1) {
+		h1 = to.UTC().Truncate(time.Hour)
+	}
+	return h0, h1, h0.Before(h1), nil
+}
+
+func (r *rolledUpStats) TimeSeries(ctx context.Context, query repository.TimeSeriesQuery) ([]entity.ClickBucket, error) {
+	// Hourly rollups start on UTC hours, so in a zone with a fractional
+	// offset they straddle local hours and, for every interval, midnights.
+	if fractionalOffset(query.Location, query.From, query.To) {
+		return r.URLStatsRepository.TimeSeries(ctx, query)
+	}
+
+	h0, h1, ok, err := r.segments(ctx, query.From, query.To)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return r.URLStatsRepository.TimeSeries(ctx, query)
+	}
+
+	rolled := query
+	rolled.From, rolled.To = h0, h1
+	buckets, err := r.rollups.TimeSeries(ctx, rolled)
+	if err != nil {
+		return nil, err
+	}
+
+	// Buckets of different segments may share a start; callers add them up.
+	for _, raw := range rawSegments(query.From, query.To, h0, h1) {
+		segment := query
+		segment.From, segment.To = raw[0], raw[1]
+		rows, err := r.URLStatsRepository.TimeSeries(ctx, segment)
+		if err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, rows...)
+	}
+
+	return buckets, nil
+}
+
+func (r *rolledUpStats) Breakdown(ctx context.Context, query repository.BreakdownQuery) ([]entity.BreakdownRow, int, error) {
+	if query.IncludeBots || !rollupDimensions[query.Dimension] {
+		return r.URLStatsRepository.Breakdown(ctx, query)
+	}
+
+	h0, h1, ok, err := r.segments(ctx, query.From, query.To)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !ok {
+		return r.URLStatsRepository.Breakdown(ctx, query)
+	}
+
+	rolled := query
+	rolled.From, rolled.To, rolled.Limit = h0, h1, 0
+	rows, total, err := r.rollups.Breakdown(ctx, rolled)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.Key] += row.Clicks
+	}
+	for _, raw := range rawSegments(query.From, query.To, h0, h1) {
+		segment := query
+		segment.From, segment.To, segment.Limit = raw[0], raw[1], rawSegmentRows
+		rows, n, err := r.URLStatsRepository.Breakdown(ctx, segment)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, row := range rows {
+			counts[row.Key] += row.Clicks
+		}
+		total += n
+	}
+
+	merged := make([]entity.BreakdownRow, 0, len(counts))
+	for key, clicks := range counts {
+		merged = append(merged, entity.BreakdownRow{Key: key, Clicks: clicks})
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		if merged[i].Clicks != merged[j].Clicks {
+			return merged[i].Clicks > merged[j].Clicks
+		}
+		return merged[i].Key < merged[j].Key
+	})
+	if len(merged) > query.Limit {
+		merged = merged[:query.Limit]
+	}
+
+	return merged, total, nil
+}
+
+// fractionalOffset reports whether loc is off UTC by a non-whole number of
+// hours at any time in [from, to), where a zero to means now.
+func fractionalOffset(loc *time.Location, from, to time.Time) bool {
+	if loc == nil {
+		return false
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+	for t := from; ; {
+		local := t.In(loc)
+		if _, offset := local.Zone(); offset%3600 != 0 {
+			return true
+		}
+		_, end := local.ZoneBounds()
+		if end.IsZero() || !end.Before(to) {
+			return false
+		}
+		t = end
+	}
+}
+
+// rawSegments returns the parts of [from, to) outside [h0, h1), where a zero
+// to is open ended.
+func rawSegments(from, to, h0, h1 time.Time) [][2]time.Time {
+	var segments [][2]time.Time
+	if from.Before(h0) {
+		segments = append(segments, [2]time.Time{from, h0})
+	}
+	if to.IsZero() || h1.Before(to) {
+		segments = append(segments, [2]time.Time{h1, to})
+	}
+	return segments
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/exceptions"
+	"url-shortener/internal/domain/repository"
+	"url-shortener/internal/services/dto"
+)
+
+// ShareService mints and serves read-only stats shares. A share token is the
+// share ID signed with key, so forged tokens are rejected without a database
+// read; revocation and expiry are checked against the stored share.
+type ShareService struct {
+	shares repository.StatsShareRepository
+	urls   *URLService
+	key    []byte
+}
+
+func NewShareService(shares repository.StatsShareRepository, urls *URLService, key []byte) *ShareService {
+	return &ShareService{shares: shares, urls: urls, key: key}
+}
+
+func (s *ShareService) Create(ctx context.Context, urlID, ownerID string, input dto.ShareInput) (*dto.Share, error) {
+	if _, err := s.urls.ownedURL(ctx, urlID, ownerID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	share := &entity.StatsShare{
+		ID:        newShareID(),
+		URLID:     urlID,
+		OwnerID:   ownerID,
+		CreatedAt: now,
+	}
+	if input.ExpiresIn != "" {
+		ttl, err := time.ParseDuration(input.ExpiresIn)
+		if err != nil || ttl <= 0 {
+			return nil, exceptions.ErrInvalidShare
+		}
+		share.ExpiresAt = now.Add(ttl)
+	}
+
+	if err := s.shares.Save(ctx, share); err != nil {
+		return nil, err
+	}
+	return s.toDTO(share), nil
+}
+
+func (s *ShareService) List(ctx context.Context, urlID, ownerID string) ([]dto.Share, error) {
+	if _, err := s.urls.ownedURL(ctx, urlID, ownerID); err != nil {
+		return nil, err
+	}
+
+	shares, err := s.shares.FindByURL(ctx, urlID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]dto.Share, 0, len(shares))
+	for i := range shares {
+		result = append(result, *s.toDTO(&shares[i]))
+	}
+	return result, nil
+}
+
+func (s *ShareService) Revoke(ctx context.Context, urlID, ownerID, shareID string) error {
+	if _, err := s.urls.ownedURL(ctx, urlID, ownerID); err != nil {
+		return err
+	}
+	return s.shares.Revoke(ctx, urlID, shareID, time.Now())
+}
+
+// Stats returns the stats of the link shared by token. Unknown, forged,
+// revoked and expired tokens all fail with exceptions.ErrShareNotFound.
+func (s *ShareService) Stats(ctx context.Context, token string, input dto.TimeSeriesInput) (*dto.SharedStats, error) {
+	share, err := s.resolve(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	id, owner := share.URLID, share.OwnerID
+
+	url, err := s.urls.ownedURL(ctx, id, owner)
+	if err != nil {
+		if errors.Is(err, exceptions.ErrURLNotFound) {
+			return nil, exceptions.ErrShareNotFound
+		}
+		return nil, err
+	}
+	stats, err := s.urls.Stats(ctx, id, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	input.IncludeBots = false
+	series, err := s.urls.TimeSeries(ctx, id, owner, input)
+	if err != nil {
+		return nil, err
+	}
+	rows := dto.BreakdownInput{From: input.From, To: input.To}
+	referrers, err := s.urls.Referrers(ctx, id, owner, rows)
+	if err != nil {
+		return nil, err
+	}
+	countries, err := s.urls.Geo(ctx, id, owner, rows)
+	if err != nil {
+		return nil, err
+	}
+	rows.By = string(repository.DimensionDevice)
+	devices, err := s.urls.Breakdown(ctx, id, owner, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &dto.SharedStats{
+		URLID:       id,
+		OriginalURL: url.OriginalURL,
+		Resume:      stats.StatsResume,
+		TimeSeries:  series,
+		Referrers:   referrers,
+		Countries:   countries,
+		Devices:     devices,
+	}
+	if !share.ExpiresAt.IsZero() {
+		expiresAt := share.ExpiresAt
+		result.ExpiresAt = &expiresAt
+	}
+	return result, nil
+}
+
+func (s *ShareService) resolve(ctx context.Context, token string) (*entity.StatsShare, error) {
+	id, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(s.sign(id))) {
+		return nil, exceptions.ErrShareNotFound
+	}
+
+	share, err := s.shares.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !share.Active(time.Now()) {
+		return nil, exceptions.ErrShareNotFound
+	}
+	return share, nil
+}
+
+func (s *ShareService) sign(id string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte("stats-share:" + id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *ShareService) toDTO(share *entity.StatsShare) *dto.Share {
+	result := &dto.Share{
+		ID:        share.ID,
+		URLID:     share.URLID,
+		Token:     share.ID + "." + s.sign(share.ID),
+		CreatedAt: share.CreatedAt,
+		Revoked:   !share.RevokedAt.IsZero(),
+	}
+	if !share.ExpiresAt.IsZero() {
+		expiresAt := share.ExpiresAt
+		result.ExpiresAt = &expiresAt
+	}
+	return result
+}
+
+func newShareID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,133 @@
+package services_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/exceptions"
+	"url-shortener/internal/services"
+	"url-shortener/internal/services/dto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockShareRepo struct {
+	mock.Mock
+}
+
+func (m *MockShareRepo) Save(ctx context.Context, share *entity.StatsShare) error {
+	args := m.Called(ctx, share)
+	return args.Error(0)
+}
+
+func (m *MockShareRepo) FindByID(ctx context.Context, id string) (*entity.StatsShare, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) != nil {
+		return args.Get(0).(*entity.StatsShare), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockShareRepo) FindByURL(ctx context.Context, urlID string) ([]entity.StatsShare, error) {
+	args := m.Called(ctx, urlID)
+	if args.Get(0) != nil {
+		return args.Get(0).([]entity.StatsShare), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockShareRepo) Revoke(ctx context.Context, urlID, id string, at time.Time) error {
+	args := m.Called(ctx, urlID, id, at)
+	return args.Error(0)
+}
+
+func newShareService() (*services.ShareService, *MockShareRepo, *MockStatsRepo) {
+	urlRepo := new(MockURLRepo)
+	statsRepo := new(MockStatsRepo)
+	shares := new(MockShareRepo)
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{
+		ID: "url1", OwnerID: "owner1", OriginalURL: "https://example.com", ClickCount: 42,
+	}, nil)
+
+	svc := services.NewURLService(urlRepo, new(MockIDGen), statsRepo)
+	return services.NewShareService(shares, svc, []byte("key")), shares, statsRepo
+}
+
+func TestShareService_CreateAndReadStats(t *testing.T) {
+	svc, shares, statsRepo := newShareService()
+
+	var saved *entity.StatsShare
+	shares.On("Save", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		saved = args.Get(1).(*entity.StatsShare)
+	}).Return(nil)
+
+	share, err := svc.Create(context.Background(), "url1", "owner1", dto.ShareInput{ExpiresIn: "24h"})
+	assert.NoError(t, err)
+	assert.Equal(t, "owner1", saved.OwnerID)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), *share.ExpiresAt, time.Minute)
+	assert.True(t, strings.HasPrefix(share.Token, saved.ID+"."))
+
+	shares.On("FindByID", mock.Anything, saved.ID).Return(saved, nil)
+	statsRepo.On("TimeSeries", mock.Anything, mock.Anything).Return([]entity.ClickBucket{}, nil)
+	statsRepo.On("Breakdown", mock.Anything, mock.Anything).Return([]entity.BreakdownRow{{Key: "", Clicks: 42}}, 42, nil)
+
+	stats, err := svc.Stats(context.Background(), share.Token, dto.TimeSeriesInput{})
+	assert.NoError(t, err)
+	assert.Equal(t, "url1", stats.URLID)
+	assert.Equal(t, 42, stats.Resume.Clicks)
+	assert.Equal(t, "direct", stats.Referrers.Rows[0].Key)
+	assert.Equal(t, "device", stats.Devices.By)
+}
+
+func TestShareService_RejectsForgedTokensWithoutLookup(t *testing.T) {
+	svc, shares, _ := newShareService()
+
+	for _, token := range []string{"", "abc", "abc.def", "abc."} {
+		_, err := svc.Stats(context.Background(), token, dto.TimeSeriesInput{})
+		assert.ErrorIs(t, err, exceptions.ErrShareNotFound, token)
+	}
+	shares.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+}
+
+func TestShareService_RejectsRevokedAndExpiredShares(t *testing.T) {
+	svc, shares, _ := newShareService()
+
+	var saved []*entity.StatsShare
+	shares.On("Save", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		saved = append(saved, args.Get(1).(*entity.StatsShare))
+	}).Return(nil)
+
+	revoked, _ := svc.Create(context.Background(), "url1", "owner1", dto.ShareInput{})
+	expired, _ := svc.Create(context.Background(), "url1", "owner1", dto.ShareInput{})
+
+	saved[0].RevokedAt = time.Now()
+	saved[1].ExpiresAt = time.Now().Add(-time.Minute)
+	shares.On("FindByID", mock.Anything, saved[0].ID).Return(saved[0], nil)
+	shares.On("FindByID", mock.Anything, saved[1].ID).Return(saved[1], nil)
+
+	for _, token := range []string{revoked.Token, expired.Token} {
+		_, err := svc.Stats(context.Background(), token, dto.TimeSeriesInput{})
+		assert.ErrorIs(t, err, exceptions.ErrShareNotFound)
+	}
+}
+
+func TestShareService_OnlyOwnerManagesShares(t *testing.T) {
+	svc, shares, _ := newShareService()
+
+	_, err := svc.Create(context.Background(), "url1", "intruder", dto.ShareInput{})
+	assert.ErrorIs(t, err, exceptions.ErrUnauthorizedURLStatistics)
+
+	_, err = svc.Create(context.Background(), "url1", "owner1", dto.ShareInput{ExpiresIn: "-1h"})
+	assert.ErrorIs(t, err, exceptions.ErrInvalidShare)
+
+	err = svc.Revoke(context.Background(), "url1", "intruder", "share1")
+	assert.ErrorIs(t, err, exceptions.ErrUnauthorizedURLStatistics)
+
+	shares.On("Revoke", mock.Anything, "url1", "share1", mock.Anything).Return(nil)
+	assert.NoError(t, svc.Revoke(context.Background(), "url1", "owner1", "share1"))
+	shares.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
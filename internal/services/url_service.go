@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"net"
 	"net/url"
 	"strings"
@@ -10,20 +11,137 @@ import (
 	"url-shortener/internal/domain/repository"
 	"url-shortener/internal/services/dto"
 	"url-shortener/pkg"
+	"url-shortener/pkg/botdetect"
+	"url-shortener/pkg/geoip"
+	"url-shortener/pkg/referrer"
+	"url-shortener/pkg/useragent"
 )
 
 type URLService struct {
 	repo        repository.URLRepository
+	redirects   repository.URLRepository
 	statsRepo   repository.URLStatsRepository
 	idGenerator pkg.IDGenerator
+	cache       *LinkCache
+	clicks      ClickRecorder
+	visitors    repository.UniqueVisitorRepository
+	visitorKey  []byte
+	referrers   *referrer.Classifier
+	geo         GeoLocator
+	live        *ClickBroker
+	privacy     PrivacyPolicy
+	ips         *ipAnonymizer
+	retention   RetentionPolicy
+
+	conversions      repository.ConversionRepository
+	conversionWindow time.Duration
+	campaigns        repository.CampaignRepository
+	metadata         *MetadataWorker
+}
+
+// GeoLocator resolves a client IP to a location. It is consulted on every
+// redirect, so implementations must not block on the network.
+type GeoLocator interface {
+	Lookup(ip string) (geoip.Location, bool)
 }
 
-func NewURLService(repo repository.URLRepository, idGen pkg.IDGenerator, statsRepo repository.URLStatsRepository) *URLService {
-	return &URLService{
+type URLServiceOption func(*URLService)
+
+// WithLinkCache serves Resolve from an in-process LRU in front of the redirect
+// repository. Only redirects read from it; link edits drop the local entry and
+// other replicas pick them up once it expires.
+func WithLinkCache(cache *LinkCache) URLServiceOption {
+	return func(s *URLService) {
+		s.cache = cache
+	}
+}
+
+// WithRedirectRepository resolves redirects through repo, typically a cache in
+// front of the repository passed to NewURLService. Link writes go through it
+// too so its entries are dropped, while Stats, ownership checks and listings
+// keep reading the uncached repository.
+func WithRedirectRepository(repo repository.URLRepository) URLServiceOption {
+	return func(s *URLService) {
+		s.redirects = repo
+	}
+}
+
+// WithClickRecorder replaces the default inline click writes, typically with
+// a ClickPipeline.
+func WithClickRecorder(recorder ClickRecorder) URLServiceOption {
+	return func(s *URLService) {
+		s.clicks = recorder
+	}
+}
+
+// WithUniqueVisitors fingerprints each click with key and reports approximate
+// unique visitors from repo in Stats. The recorder is responsible for feeding
+// the fingerprints into repo.
+func WithUniqueVisitors(repo repository.UniqueVisitorRepository, key []byte) URLServiceOption {
+	return func(s *URLService) {
+		s.visitors = repo
+		s.visitorKey = key
+	}
+}
+
+// WithInternalHosts reports referrers from hosts as internal traffic, typically
+// the hosts the shortener itself is served from.
+func WithInternalHosts(hosts ...string) URLServiceOption {
+	return func(s *URLService) {
+		s.referrers = referrer.NewClassifier(hosts...)
+	}
+}
+
+// WithGeoLocator enriches clicks with the location of the client IP.
+func WithGeoLocator(geo GeoLocator) URLServiceOption {
+	return func(s *URLService) {
+		s.geo = geo
+	}
+}
+
+// WithLiveClicks publishes every resolved click to broker for live
+// subscribers.
+func WithLiveClicks(broker *ClickBroker) URLServiceOption {
+	return func(s *URLService) {
+		s.live = broker
+	}
+}
+
+// WithPrivacy applies policy to every recorded click. Without it clicks keep
+// the full IP and user agent.
+func WithPrivacy(policy PrivacyPolicy) URLServiceOption {
+	return func(s *URLService) {
+		s.privacy = policy
+	}
+}
+
+// WithRetention stamps each click with the expiry of its owner's retention.
+func WithRetention(policy RetentionPolicy) URLServiceOption {
+	return func(s *URLService) {
+		s.retention = policy
+	}
+}
+
+func NewURLService(repo repository.URLRepository, idGen pkg.IDGenerator, statsRepo repository.URLStatsRepository, opts ...URLServiceOption) *URLService {
+	s := &URLService{
 		repo:        repo,
 		statsRepo:   statsRepo,
 		idGenerator: idGen,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.redirects == nil {
+		s.redirects = repo
+	}
+	if s.clicks == nil {
+		s.clicks = &syncClickRecorder{repo: repo, statsRepo: statsRepo}
+	}
+	if s.referrers == nil {
+		s.referrers = referrer.NewClassifier()
+	}
+	s.ips = newIPAnonymizer(s.privacy.IP)
+	return s
 }
 
 func (s *URLService) isPrivateIP(ip net.IP) bool {
@@ -58,10 +176,24 @@ func (s *URLService) validateDestination(raw string) error {
 	return nil
 }
 
-func (s *URLService) Shorten(originalURL, ownerID string) (*entity.URL, error) {
+// Shorten creates a link to input.URL, in input.CampaignID when set.
+func (s *URLService) Shorten(ctx context.Context, input dto.URL, ownerID string) (*entity.URL, error) {
+	originalURL := input.URL
 	if err := s.validateDestination(originalURL); err != nil {
 		return nil, exceptions.ErrInvalidURL
 	}
+	tags, folder, err := normalizeLabels(input.Tags, input.Folder)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.CampaignID != "" {
+		campaign, err := s.openCampaign(ctx, input.CampaignID, ownerID)
+		if err != nil {
+			return nil, err
+		}
+		originalURL = withUTM(originalURL, campaign.UTM)
+	}
 
 	id, err := s.idGenerator.Generate()
 	if err != nil {
@@ -73,75 +205,188 @@ func (s *URLService) Shorten(originalURL, ownerID string) (*entity.URL, error) {
 		OriginalURL: originalURL,
 		OwnerID:     ownerID,
 		CreatedAt:   time.Now(),
+		CampaignID:  input.CampaignID,
+		Tags:        tags,
+		Folder:      folder,
 	}
 
-	if err := s.repo.Save(&urlEntity); err != nil {
+	if err := s.redirects.Save(ctx, &urlEntity); err != nil {
 		return nil, err
 	}
+	if s.metadata != nil {
+		s.metadata.Enqueue(urlEntity.ID, urlEntity.OriginalURL)
+	}
 
 	return &urlEntity, nil
 }
 
-func (s *URLService) Resolve(id, ip, userAgent, referer string) (*entity.URL, error) {
-	url, err := s.repo.FindByID(id)
+// Resolve records a visit to the link and returns where to send the visitor.
+func (s *URLService) Resolve(ctx context.Context, id string, visit dto.Visit) (*dto.Redirect, error) {
+	url, err := s.findForRedirect(ctx, id)
 	if err != nil {
 		return nil, exceptions.ErrURLNotFound
 	}
 
-	if err := s.repo.IncrementClick(id); err != nil {
-		return nil, err
+	agent := useragent.ParseWithHints(visit.UserAgent, useragent.Hints{
+		UA:       visit.ClientHintUA,
+		Mobile:   visit.ClientHintMobile,
+		Platform: visit.ClientHintPlatform,
+	})
+
+	class := botdetect.Classify(botdetect.Request{
+		Method:    visit.Method,
+		UserAgent: visit.UserAgent,
+		Accept:    visit.Accept,
+	})
+
+	if s.privacy.HonorOptOut && optedOut(visit) {
+		s.record(ctx, url, &entity.URLStat{URLID: id, ClickedAt: time.Now(), Class: class, OptOut: true})
+		return &dto.Redirect{Location: url.OriginalURL}, nil
 	}
 
+	source := s.referrers.Parse(visit.Referer)
+
 	stat := &entity.URLStat{
 		URLID:     id,
 		ClickedAt: time.Now(),
-		IP:        ip,
-		UserAgent: userAgent,
-		Referer:   referer,
+		IP:        visit.IP,
+		UserAgent: visit.UserAgent,
+		Referer:   visit.Referer,
+		Class:     class,
+
+		RefererHost:   source.Host,
+		RefererSource: source.Category,
+
+		Browser:        agent.Browser,
+		BrowserVersion: agent.BrowserVersion,
+		OS:             agent.OS,
+		OSVersion:      agent.OSVersion,
+		Device:         agent.Device,
 	}
-	if err := s.statsRepo.Save(stat); err != nil {
-		return nil, err
+	if s.geo != nil {
+		if loc, ok := s.geo.Lookup(visit.IP); ok {
+			stat.Country = loc.Country
+			stat.Region = loc.Region
+			stat.City = loc.City
+			stat.Latitude = loc.Latitude
+			stat.Longitude = loc.Longitude
+		}
+	}
+	if s.visitors != nil && class == botdetect.ClassHuman {
+		stat.VisitorHash = visitorHash(s.visitorKey, visit.IP, visit.UserAgent)
 	}
 
-	return url, nil
+	stat.IP = s.ips.anonymize(visit.IP)
+	if s.privacy.DropUserAgent {
+		stat.UserAgent = ""
+	}
+
+	// Only human clicks get a click ID, matching the tracked clicks the
+	// conversion rate is computed against.
+	redirect := &dto.Redirect{Location: url.OriginalURL}
+	if s.conversions != nil && url.ClickIDParam != "" && class == botdetect.ClassHuman {
+		if clickID, ok := s.issueClickID(ctx, url, stat.ClickedAt); ok {
+			stat.ClickID = clickID
+			redirect.ClickID = clickID
+			redirect.Location = withClickID(url.OriginalURL, url.ClickIDParam, clickID)
+		}
+	}
+	s.record(ctx, url, stat)
+
+	return redirect, nil
 }
 
-func (s *URLService) Stats(id, ownerID string) (*dto.URLStats, error) {
-	url, err := s.repo.FindByID(id)
-	if err != nil {
-		return nil, exceptions.ErrURLNotFound
+func (s *URLService) record(ctx context.Context, url *entity.URL, stat *entity.URLStat) {
+	if retention := s.retention.For(url.OwnerID); retention > 0 {
+		stat.ExpireAt = stat.ClickedAt.Add(retention)
+	}
+	s.clicks.Record(ctx, stat)
+	if s.live != nil {
+		s.live.Publish(stat)
 	}
+}
 
-	if url.OwnerID != ownerID {
-		return nil, exceptions.ErrUnauthorizedURLStatistics
+// WarmCache preloads the link cache with the limit most clicked links.
+func (s *URLService) WarmCache(ctx context.Context, limit int) (int, error) {
+	if s.cache == nil || limit <= 0 {
+		return 0, nil
 	}
 
-	stats, err := s.statsRepo.FindByURLID(id)
+	urls, err := s.repo.FindTop(ctx, limit)
 	if err != nil {
-		return nil, exceptions.ErrURLNotFound
+		return 0, err
+	}
+	for i := range urls {
+		s.cache.Add(&urls[i])
 	}
+	return len(urls), nil
+}
+
+// appendQuery adds params to the query of destination, leaving the existing
+// parameters as they were.
+func appendQuery(destination string, params ...[2]string) string {
+	u, err := url.Parse(destination)
+	if err != nil {
+		return destination
+	}
+	for _, p := range params {
+		pair := url.QueryEscape(p[0]) + "=" + url.QueryEscape(p[1])
+		if u.RawQuery == "" {
+			u.RawQuery = pair
+		} else {
+			u.RawQuery += "&" + pair
+		}
+	}
+	return u.String()
+}
+
+func (s *URLService) findForRedirect(ctx context.Context, id string) (*entity.URL, error) {
+	if s.cache == nil {
+		return s.redirects.FindByID(ctx, id)
+	}
+	return s.cache.Get(ctx, id, s.redirects.FindByID)
+}
 
-	var statsData []dto.Data
+// uncache drops a link this replica has cached after a write through
+// s.redirects, which drops it from Redis. Other replicas are not told: they
+// keep redirecting to the old link until their entry expires after
+// LINK_CACHE_TTL.
+func (s *URLService) uncache(id string) {
+	if s.cache != nil {
+		s.cache.Remove(id)
+	}
+}
 
-	for _, sEnt := range stats {
-		statsData = append(statsData, dto.Data{
-			ID:        sEnt.ID,
-			URLID:     sEnt.URLID,
-			ClickedAt: sEnt.ClickedAt,
-			IP:        sEnt.IP,
-			UserAgent: sEnt.UserAgent,
-			Referer:   sEnt.Referer,
-		})
+func (s *URLService) Stats(ctx context.Context, id, ownerID string) (*dto.URLStats, error) {
+	url, err := s.ownedURL(ctx, id, ownerID)
+	if err != nil {
+		return nil, err
 	}
 
 	resume := dto.Resume{
 		Clicks:    url.ClickCount,
+		BotClicks: url.BotClickCount,
 		LastClick: url.LastClick,
 	}
 
+	if s.visitors != nil {
+		sketch, err := s.visitors.Sketch(ctx, []string{id}, time.Time{}, time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		resume.Uniques = sketch.Estimate()
+	}
+
+	if s.conversions != nil && (url.ClickIDParam != "" || url.TrackedClickCount > 0) {
+		conversions, err := s.conversionStats(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+		resume.Conversions = conversions
+	}
+
 	result := &dto.URLStats{
 		StatsResume: resume,
-		StatsData:   statsData,
 	}
 
 	return result, nil
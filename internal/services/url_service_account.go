@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/exceptions"
+	"url-shortener/internal/domain/repository"
+	"url-shortener/internal/services/dto"
+	"url-shortener/pkg/referrer"
+)
+
+// AccountAnalytics aggregates the clicks of every link owned by ownerID over
+// one period: totals, a time series and the top links, referrers and
+// countries. input.Tag and input.Folder narrow it to part of the account.
+func (s *URLService) AccountAnalytics(ctx context.Context, ownerID string, input dto.AccountAnalyticsInput) (*dto.AccountAnalytics, error) {
+	return s.aggregate(ctx, input, func() ([]entity.URL, error) {
+		if input.Tag == "" && input.Folder == "" {
+			return s.repo.FindByOwner(ctx, ownerID)
+		}
+		return s.repo.List(ctx, repository.URLFilter{OwnerID: ownerID, Tag: input.Tag, Folder: input.Folder})
+	})
+}
+
+// aggregate reports the clicks of the links returned by load as one. Input
+// is validated before the links are loaded.
+func (s *URLService) aggregate(ctx context.Context, input dto.AccountAnalyticsInput, load func() ([]entity.URL, error)) (*dto.AccountAnalytics, error) {
+	query, err := parseTimeSeriesInput(dto.TimeSeriesInput{
+		Interval: input.Interval,
+		From:     input.From,
+		To:       input.To,
+		TZ:       input.TZ,
+	}, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	query.IncludeBots = input.IncludeBots
+
+	limit := input.Limit
+	switch {
+	case limit == 0:
+		limit = defaultBreakdownRows
+	case limit < 0 || limit > maxBreakdownRows:
+		return nil, exceptions.ErrInvalidStatsQuery
+	}
+
+	urls, err := load()
+	if err != nil {
+		return nil, err
+	}
+	originals := make(map[string]string, len(urls))
+	for _, url := range urls {
+		query.URLIDs = append(query.URLIDs, url.ID)
+		originals[url.ID] = url.OriginalURL
+	}
+
+	result := &dto.AccountAnalytics{
+		Links:        len(urls),
+		Interval:     string(query.Interval),
+		TZ:           query.Location.String(),
+		From:         query.From,
+		To:           query.To,
+		TopLinks:     []dto.LinkClicks{},
+		TopReferrers: []dto.BreakdownRow{},
+		TopCountries: []dto.BreakdownRow{},
+	}
+	if len(urls) == 0 {
+		result.Buckets, result.Total = fillBuckets(nil, query)
+		return result, nil
+	}
+
+	buckets, err := s.statsRepo.TimeSeries(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	result.Buckets, result.Total = fillBuckets(buckets, query)
+
+	if s.visitors != nil {
+		sketch, err := s.visitors.Sketch(ctx, query.URLIDs, query.From, query.To)
+		if err != nil {
+			return nil, err
+		}
+		result.Uniques = sketch.Estimate()
+	}
+
+	top := func(dimension repository.Dimension, emptyKey string) ([]dto.BreakdownRow, error) {
+		rows, total, err := s.statsRepo.Breakdown(ctx, repository.BreakdownQuery{
+			URLIDs:      query.URLIDs,
+			From:        query.From,
+			To:          query.To,
+			Dimension:   dimension,
+			Limit:       limit,
+			IncludeBots: query.IncludeBots,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return breakdownRows(rows, total, emptyKey), nil
+	}
+
+	links, err := top(repository.DimensionLink, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range links {
+		result.TopLinks = append(result.TopLinks, dto.LinkClicks{
+			URLID:       row.Key,
+			OriginalURL: originals[row.Key],
+			Clicks:      row.Clicks,
+			Percent:     row.Percent,
+		})
+	}
+	if result.TopReferrers, err = top(repository.DimensionRefererHost, referrer.CategoryDirect); err != nil {
+		return nil, err
+	}
+	if result.TopCountries, err = top(repository.DimensionCountry, "unknown"); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,79 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/exceptions"
+	"url-shortener/internal/domain/repository"
+	"url-shortener/internal/services"
+	"url-shortener/internal/services/dto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestURLService_AccountAnalytics_AggregatesOwnedLinks(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	statsRepo := new(MockStatsRepo)
+	svc := services.NewURLService(urlRepo, new(MockIDGen), statsRepo)
+
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	ids := []string{"url1", "url2"}
+
+	urlRepo.On("FindByOwner", mock.Anything, "owner1").Return([]entity.URL{
+		{ID: "url1", OriginalURL: "https://a.example"},
+		{ID: "url2", OriginalURL: "https://b.example"},
+	}, nil)
+	statsRepo.On("TimeSeries", mock.Anything, mock.MatchedBy(func(q repository.TimeSeriesQuery) bool {
+		return assert.ObjectsAreEqual(ids, q.URLIDs) && q.From.Equal(from) && q.To.Equal(to)
+	})).Return([]entity.ClickBucket{{Start: from, Clicks: 3}, {Start: from.AddDate(0, 0, 1), Clicks: 1}}, nil)
+
+	breakdown := func(dimension repository.Dimension) repository.BreakdownQuery {
+		return repository.BreakdownQuery{URLIDs: ids, From: from, To: to, Dimension: dimension, Limit: 10}
+	}
+	statsRepo.On("Breakdown", mock.Anything, breakdown(repository.DimensionLink)).
+		Return([]entity.BreakdownRow{{Key: "url2", Clicks: 3}, {Key: "url1", Clicks: 1}}, 4, nil)
+	statsRepo.On("Breakdown", mock.Anything, breakdown(repository.DimensionRefererHost)).
+		Return([]entity.BreakdownRow{{Key: "", Clicks: 4}}, 4, nil)
+	statsRepo.On("Breakdown", mock.Anything, breakdown(repository.DimensionCountry)).
+		Return([]entity.BreakdownRow{{Key: "BR", Clicks: 4}}, 4, nil)
+
+	analytics, err := svc.AccountAnalytics(context.Background(), "owner1", dto.AccountAnalyticsInput{
+		From: "2025-03-01",
+		To:   "2025-03-03",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, analytics.Links)
+	assert.Equal(t, 4, analytics.Total)
+	assert.Len(t, analytics.Buckets, 2)
+	assert.Equal(t, dto.LinkClicks{URLID: "url2", OriginalURL: "https://b.example", Clicks: 3, Percent: 75}, analytics.TopLinks[0])
+	assert.Equal(t, "direct", analytics.TopReferrers[0].Key)
+	assert.Equal(t, "BR", analytics.TopCountries[0].Key)
+}
+
+func TestURLService_AccountAnalytics_NoLinks(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	statsRepo := new(MockStatsRepo)
+	svc := services.NewURLService(urlRepo, new(MockIDGen), statsRepo)
+
+	urlRepo.On("FindByOwner", mock.Anything, "owner1").Return(nil, nil)
+
+	analytics, err := svc.AccountAnalytics(context.Background(), "owner1", dto.AccountAnalyticsInput{Interval: "day"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, analytics.Total)
+	assert.NotEmpty(t, analytics.Buckets)
+	assert.Empty(t, analytics.TopLinks)
+	statsRepo.AssertNotCalled(t, "TimeSeries", mock.Anything, mock.Anything)
+}
+
+func TestURLService_AccountAnalytics_InvalidLimit(t *testing.T) {
+	svc := services.NewURLService(new(MockURLRepo), new(MockIDGen), new(MockStatsRepo))
+
+	_, err := svc.AccountAnalytics(context.Background(), "owner1", dto.AccountAnalyticsInput{Limit: 1000})
+	assert.ErrorIs(t, err, exceptions.ErrInvalidStatsQuery)
+}
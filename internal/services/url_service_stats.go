@@ -0,0 +1,462 @@
+package services
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/exceptions"
+	"url-shortener/internal/domain/repository"
+	"url-shortener/internal/services/dto"
+	"url-shortener/pkg/referrer"
+)
+
+const (
+	// maxBuckets bounds the size of a time series response.
+	maxBuckets = 2000
+
+	defaultClickPageSize = 100
+	maxClickPageSize     = 1000
+
+	defaultBreakdownRows = 10
+	maxBreakdownRows     = 100
+
+	// mapCountries covers every ISO 3166-1 code; mapPoints bounds the points
+	// layer of the map.
+	mapCountries = 300
+	mapPoints    = 1000
+)
+
+// clientDimensions are the breakdowns served by Breakdown.
+var clientDimensions = map[repository.Dimension]bool{
+	repository.DimensionBrowser:  true,
+	repository.DimensionOS:       true,
+	repository.DimensionDevice:   true,
+	repository.DimensionPlatform: true,
+}
+
+// referrerDimensions maps the "by" values accepted by Referrers.
+var referrerDimensions = map[string]repository.Dimension{
+	"host":   repository.DimensionRefererHost,
+	"source": repository.DimensionRefererSource,
+}
+
+// geoDimensions maps the "by" values accepted by Geo.
+var geoDimensions = map[string]repository.Dimension{
+	"country": repository.DimensionCountry,
+	"region":  repository.DimensionRegion,
+	"city":    repository.DimensionCity,
+}
+
+var defaultSpans = map[repository.Interval]time.Duration{
+	repository.IntervalHour: 48 * time.Hour,
+	repository.IntervalDay:  30 * 24 * time.Hour,
+	repository.IntervalWeek: 12 * 7 * 24 * time.Hour,
+}
+
+func (s *URLService) TimeSeries(ctx context.Context, id, ownerID string, input dto.TimeSeriesInput) (*dto.TimeSeries, error) {
+	if _, err := s.ownedURL(ctx, id, ownerID); err != nil {
+		return nil, err
+	}
+
+	query, err := parseTimeSeriesInput(input, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	query.URLIDs = []string{id}
+	query.IncludeBots = input.IncludeBots
+
+	buckets, err := s.statsRepo.TimeSeries(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &dto.TimeSeries{
+		URLID:    id,
+		Interval: string(query.Interval),
+		TZ:       query.Location.String(),
+		From:     query.From,
+		To:       query.To,
+	}
+	result.Buckets, result.Total = fillBuckets(buckets, query)
+
+	return result, nil
+}
+
+// Clicks returns one page of the raw click log of a link, newest first.
+func (s *URLService) Clicks(ctx context.Context, id, ownerID string, input dto.ClickLogInput) (*dto.ClickPage, error) {
+	if _, err := s.ownedURL(ctx, id, ownerID); err != nil {
+		return nil, err
+	}
+
+	query := repository.ClickQuery{
+		URLID:       id,
+		RefererHost: referrer.NormalizeHost(input.RefererHost),
+		UserAgent:   input.UserAgent,
+		Class:       input.Class,
+		Cursor:      input.Cursor,
+		Limit:       input.Limit,
+	}
+	switch {
+	case query.Limit == 0:
+		query.Limit = defaultClickPageSize
+	case query.Limit < 0 || query.Limit > maxClickPageSize:
+		return nil, exceptions.ErrInvalidStatsQuery
+	}
+
+	var err error
+	if query.From, query.To, err = parseStatsRange(input.From, input.To); err != nil {
+		return nil, err
+	}
+
+	stats, next, err := s.statsRepo.FindPage(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &dto.ClickPage{Data: make([]dto.Data, 0, len(stats)), NextCursor: next}
+	for _, sEnt := range stats {
+		page.Data = append(page.Data, dto.Data{
+			ID:        sEnt.ID,
+			URLID:     sEnt.URLID,
+			ClickedAt: sEnt.ClickedAt,
+			IP:        sEnt.IP,
+			UserAgent: sEnt.UserAgent,
+			Referer:   sEnt.Referer,
+
+			RefererHost:   sEnt.RefererHost,
+			RefererSource: sEnt.RefererSource,
+
+			Browser:        sEnt.Browser,
+			BrowserVersion: sEnt.BrowserVersion,
+			OS:             sEnt.OS,
+			OSVersion:      sEnt.OSVersion,
+			Device:         sEnt.Device,
+			Class:          sEnt.Class,
+			OptOut:         sEnt.OptOut,
+			ClickID:        sEnt.ClickID,
+
+			Country: sEnt.Country,
+			Region:  sEnt.Region,
+			City:    sEnt.City,
+		})
+	}
+
+	return page, nil
+}
+
+// Breakdown groups a link's clicks by a client dimension such as browser or
+// device, with each row's share of the total.
+func (s *URLService) Breakdown(ctx context.Context, id, ownerID string, input dto.BreakdownInput) (*dto.Breakdown, error) {
+	if _, err := s.ownedURL(ctx, id, ownerID); err != nil {
+		return nil, err
+	}
+
+	dimension := repository.Dimension(input.By)
+	if !clientDimensions[dimension] {
+		return nil, exceptions.ErrInvalidStatsQuery
+	}
+
+	return s.breakdown(ctx, id, dimension, input, "unknown")
+}
+
+// Referrers returns a link's top referrers, grouped by normalized host or by
+// source category. Direct traffic is reported under "direct".
+func (s *URLService) Referrers(ctx context.Context, id, ownerID string, input dto.BreakdownInput) (*dto.Breakdown, error) {
+	if _, err := s.ownedURL(ctx, id, ownerID); err != nil {
+		return nil, err
+	}
+
+	if input.By == "" {
+		input.By = "host"
+	}
+	dimension, ok := referrerDimensions[input.By]
+	if !ok {
+		return nil, exceptions.ErrInvalidStatsQuery
+	}
+
+	return s.breakdown(ctx, id, dimension, input, referrer.CategoryDirect)
+}
+
+// Geo breaks a link's clicks down by country, region or city. Clicks that
+// could not be located are reported under "unknown".
+func (s *URLService) Geo(ctx context.Context, id, ownerID string, input dto.BreakdownInput) (*dto.Breakdown, error) {
+	if _, err := s.ownedURL(ctx, id, ownerID); err != nil {
+		return nil, err
+	}
+
+	if input.By == "" {
+		input.By = "country"
+	}
+	dimension, ok := geoDimensions[input.By]
+	if !ok {
+		return nil, exceptions.ErrInvalidStatsQuery
+	}
+
+	return s.breakdown(ctx, id, dimension, input, "unknown")
+}
+
+// GeoMap returns clicks per country and per coarse coordinate.
+func (s *URLService) GeoMap(ctx context.Context, id, ownerID string, input dto.GeoMapInput) (*dto.GeoMap, error) {
+	if _, err := s.ownedURL(ctx, id, ownerID); err != nil {
+		return nil, err
+	}
+
+	from, to, err := parseStatsRange(input.From, input.To)
+	if err != nil {
+		return nil, err
+	}
+
+	countries, _, err := s.statsRepo.Breakdown(ctx, repository.BreakdownQuery{
+		URLIDs:      []string{id},
+		From:        from,
+		To:          to,
+		Dimension:   repository.DimensionCountry,
+		Limit:       mapCountries,
+		IncludeBots: input.IncludeBots,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	points, err := s.statsRepo.Locations(ctx, repository.GeoQuery{
+		URLIDs:      []string{id},
+		From:        from,
+		To:          to,
+		Limit:       mapPoints,
+		IncludeBots: input.IncludeBots,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &dto.GeoMap{
+		URLID:     id,
+		Countries: make(map[string]int, len(countries)),
+		Points:    make([]dto.GeoPoint, 0, len(points)),
+	}
+	for _, row := range countries {
+		if row.Key != "" {
+			result.Countries[row.Key] = row.Clicks
+		}
+	}
+	for _, p := range points {
+		result.Points = append(result.Points, dto.GeoPoint{Lat: p.Latitude, Lon: p.Longitude, Clicks: p.Clicks})
+	}
+
+	return result, nil
+}
+
+// breakdown runs a breakdown of one link, reporting clicks without a value
+// for the dimension under emptyKey.
+func (s *URLService) breakdown(ctx context.Context, id string, dimension repository.Dimension, input dto.BreakdownInput, emptyKey string) (*dto.Breakdown, error) {
+	query := repository.BreakdownQuery{
+		URLIDs:      []string{id},
+		Dimension:   dimension,
+		Limit:       input.Limit,
+		IncludeBots: input.IncludeBots,
+	}
+	switch {
+	case query.Limit == 0:
+		query.Limit = defaultBreakdownRows
+	case query.Limit < 0 || query.Limit > maxBreakdownRows:
+		return nil, exceptions.ErrInvalidStatsQuery
+	}
+
+	var err error
+	if query.From, query.To, err = parseStatsRange(input.From, input.To); err != nil {
+		return nil, err
+	}
+
+	rows, total, err := s.statsRepo.Breakdown(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.Breakdown{
+		URLID: id,
+		By:    input.By,
+		Total: total,
+		Rows:  breakdownRows(rows, total, emptyKey),
+	}, nil
+}
+
+// Live subscribes the owner to the clicks of a link as they happen.
+// lastEventID is the raw Last-Event-ID of a reconnecting client; events after
+// it that are still retained are returned as a backlog.
+func (s *URLService) Live(ctx context.Context, id, ownerID, lastEventID string) (*Subscription, []LiveEvent, error) {
+	if _, err := s.ownedURL(ctx, id, ownerID); err != nil {
+		return nil, nil, err
+	}
+	if s.live == nil {
+		return nil, nil, ErrClickBrokerClosed
+	}
+
+	// A malformed ID is treated as a fresh connection rather than an error,
+	// since browsers resend whatever the server last sent.
+	last, _ := strconv.ParseUint(lastEventID, 10, 64)
+	return s.live.Subscribe(id, last)
+}
+
+func (s *URLService) ownedURL(ctx context.Context, id, ownerID string) (*entity.URL, error) {
+	url, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, exceptions.ErrURLNotFound
+	}
+	if url.OwnerID != ownerID {
+		return nil, exceptions.ErrUnauthorizedURLStatistics
+	}
+	return url, nil
+}
+
+func parseTimeSeriesInput(input dto.TimeSeriesInput, now time.Time) (repository.TimeSeriesQuery, error) {
+	query := repository.TimeSeriesQuery{Interval: repository.IntervalDay, Location: time.UTC}
+
+	if input.Interval != "" {
+		query.Interval = repository.Interval(input.Interval)
+	}
+	span, ok := defaultSpans[query.Interval]
+	if !ok {
+		return query, exceptions.ErrInvalidStatsQuery
+	}
+
+	if input.TZ != "" {
+		loc, err := time.LoadLocation(input.TZ)
+		if err != nil {
+			return query, exceptions.ErrInvalidStatsQuery
+		}
+		query.Location = loc
+	}
+
+	var err error
+	query.To = now
+	if input.To != "" {
+		if query.To, err = parseStatsTime(input.To, query.Location); err != nil {
+			return query, err
+		}
+	}
+	query.From = query.To.Add(-span)
+	if input.From != "" {
+		if query.From, err = parseStatsTime(input.From, query.Location); err != nil {
+			return query, err
+		}
+	}
+
+	if !query.From.Before(query.To) {
+		return query, exceptions.ErrInvalidStatsQuery
+	}
+	if n := countBuckets(query); n > maxBuckets {
+		return query, exceptions.ErrInvalidStatsQuery
+	}
+
+	return query, nil
+}
+
+// parseStatsRange parses optional UTC bounds; a missing bound stays zero,
+// which repositories treat as open.
+func parseStatsRange(rawFrom, rawTo string) (from, to time.Time, err error) {
+	if rawFrom != "" {
+		if from, err = parseStatsTime(rawFrom, time.UTC); err != nil {
+			return
+		}
+	}
+	if rawTo != "" {
+		if to, err = parseStatsTime(rawTo, time.UTC); err != nil {
+			return
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		err = exceptions.ErrInvalidStatsQuery
+	}
+	return
+}
+
+func breakdownRows(rows []entity.BreakdownRow, total int, emptyKey string) []dto.BreakdownRow {
+	result := make([]dto.BreakdownRow, 0, len(rows))
+	for _, row := range rows {
+		key := row.Key
+		if key == "" {
+			key = emptyKey
+		}
+		percent := 0.0
+		if total > 0 {
+			percent = math.Round(float64(row.Clicks)*1000/float64(total)) / 10
+		}
+		result = append(result, dto.BreakdownRow{Key: key, Clicks: row.Clicks, Percent: percent})
+	}
+	return result
+}
+
+// parseStatsTime accepts an RFC 3339 timestamp or a bare date, which is taken
+// as midnight in loc.
+func parseStatsTime(raw string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", raw, loc); err == nil {
+		return t, nil
+	}
+	return time.Time{}, exceptions.ErrInvalidStatsQuery
+}
+
+// truncate aligns t to the start of its bucket in loc, matching Mongo's
+// $dateTrunc with weeks starting on Monday.
+func truncate(t time.Time, interval repository.Interval, loc *time.Location) time.Time {
+	t = t.In(loc)
+	switch interval {
+	case repository.IntervalHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+	case repository.IntervalWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	}
+}
+
+func nextBucket(t time.Time, interval repository.Interval) time.Time {
+	switch interval {
+	case repository.IntervalHour:
+		return t.Add(time.Hour)
+	case repository.IntervalWeek:
+		return t.AddDate(0, 0, 7)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+func countBuckets(query repository.TimeSeriesQuery) int {
+	n := 0
+	for t := truncate(query.From, query.Interval, query.Location); t.Before(query.To); t = nextBucket(t, query.Interval) {
+		n++
+		if n > maxBuckets {
+			break
+		}
+	}
+	return n
+}
+
+// fillBuckets lays the aggregated buckets over every bucket in the query
+// range, inserting zeros where no clicks were recorded. The first bucket
+// starts before query.From when it is not aligned, and is flagged partial.
+func fillBuckets(buckets []entity.ClickBucket, query repository.TimeSeriesQuery) ([]dto.Bucket, int) {
+	counts := make(map[int64]int, len(buckets))
+	for _, b := range buckets {
+		counts[b.Start.Unix()] += b.Clicks
+	}
+
+	var (
+		result []dto.Bucket
+		total  int
+	)
+	for t := truncate(query.From, query.Interval, query.Location); t.Before(query.To); t = nextBucket(t, query.Interval) {
+		clicks := counts[t.Unix()]
+		partial := t.Before(query.From) || nextBucket(t, query.Interval).After(query.To)
+		result = append(result, dto.Bucket{Start: t, Clicks: clicks, Partial: partial})
+		total += clicks
+	}
+
+	return result, total
+}
@@ -0,0 +1,462 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/internal/domain/exceptions"
+	"url-shortener/internal/domain/repository"
+	"url-shortener/internal/infra/cache"
+	"url-shortener/internal/services"
+	"url-shortener/internal/services/dto"
+	"url-shortener/pkg/geoip"
+	"url-shortener/pkg/hll"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// ----------------- TimeSeries -----------------
+
+func TestURLService_TimeSeries_FillsEmptyBuckets(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	statsRepo := new(MockStatsRepo)
+	svc := services.NewURLService(urlRepo, new(MockIDGen), statsRepo)
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{ID: "url1", OwnerID: "owner1"}, nil)
+
+	day2 := time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC)
+	statsRepo.On("TimeSeries", mock.Anything, mock.MatchedBy(func(q repository.TimeSeriesQuery) bool {
+		return q.Interval == repository.IntervalDay && q.URLIDs[0] == "url1"
+	})).Return([]entity.ClickBucket{{Start: day2, Clicks: 7}}, nil)
+
+	series, err := svc.TimeSeries(context.Background(), "url1", "owner1", dto.TimeSeriesInput{
+		Interval: "day",
+		From:     "2025-03-01",
+		To:       "2025-03-04",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 7, series.Total)
+	assert.Equal(t, []int{0, 7, 0}, bucketClicks(series.Buckets))
+	for _, b := range series.Buckets {
+		assert.False(t, b.Partial)
+	}
+}
+
+func TestURLService_TimeSeries_AlignsToTimezone(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	statsRepo := new(MockStatsRepo)
+	svc := services.NewURLService(urlRepo, new(MockIDGen), statsRepo)
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{ID: "url1", OwnerID: "owner1"}, nil)
+
+	loc, _ := time.LoadLocation("America/Sao_Paulo")
+	monday := time.Date(2025, 3, 3, 0, 0, 0, 0, loc)
+	statsRepo.On("TimeSeries", mock.Anything, mock.Anything).Return([]entity.ClickBucket{{Start: monday.UTC(), Clicks: 3}}, nil)
+
+	series, err := svc.TimeSeries(context.Background(), "url1", "owner1", dto.TimeSeriesInput{
+		Interval: "week",
+		From:     "2025-03-05",
+		To:       "2025-03-12",
+		TZ:       "America/Sao_Paulo",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "America/Sao_Paulo", series.TZ)
+	assert.Equal(t, []int{3, 0}, bucketClicks(series.Buckets))
+	assert.True(t, series.Buckets[0].Start.Equal(monday))
+	// The range starts and ends on a Wednesday, inside both weeks.
+	assert.True(t, series.Buckets[0].Partial)
+	assert.True(t, series.Buckets[1].Partial)
+}
+
+func TestURLService_TimeSeries_InvalidQuery(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	svc := services.NewURLService(urlRepo, new(MockIDGen), new(MockStatsRepo))
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{ID: "url1", OwnerID: "owner1"}, nil)
+
+	inputs := []dto.TimeSeriesInput{
+		{Interval: "minute"},
+		{TZ: "Mars/Olympus"},
+		{From: "yesterday"},
+		{From: "2025-03-04", To: "2025-03-01"},
+		{Interval: "hour", From: "2020-01-01", To: "2025-01-01"},
+	}
+	for _, input := range inputs {
+		_, err := svc.TimeSeries(context.Background(), "url1", "owner1", input)
+		assert.ErrorIs(t, err, exceptions.ErrInvalidStatsQuery, "%+v", input)
+	}
+}
+
+func TestURLService_TimeSeries_Unauthorized(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	svc := services.NewURLService(urlRepo, new(MockIDGen), new(MockStatsRepo))
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{ID: "url1", OwnerID: "owner1"}, nil)
+
+	_, err := svc.TimeSeries(context.Background(), "url1", "otherUser", dto.TimeSeriesInput{})
+	assert.ErrorIs(t, err, exceptions.ErrUnauthorizedURLStatistics)
+}
+
+type MockVisitorRepo struct {
+	mock.Mock
+}
+
+func (m *MockVisitorRepo) Observe(ctx context.Context, observations []entity.VisitorObservation) error {
+	args := m.Called(ctx, observations)
+	return args.Error(0)
+}
+
+func (m *MockVisitorRepo) Sketch(ctx context.Context, urlIDs []string, from, to time.Time) (*hll.Sketch, error) {
+	args := m.Called(ctx, urlIDs, from, to)
+	if args.Get(0) != nil {
+		return args.Get(0).(*hll.Sketch), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func TestURLService_Stats_IncludesUniques(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	visitorRepo := new(MockVisitorRepo)
+	svc := services.NewURLService(urlRepo, new(MockIDGen), new(MockStatsRepo),
+		services.WithUniqueVisitors(visitorRepo, []byte("key")))
+
+	sketch := hll.New()
+	for i := uint64(1); i <= 3; i++ {
+		sketch.Insert(i * 0x9E3779B97F4A7C15)
+	}
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{ID: "url1", OwnerID: "owner1", ClickCount: 10}, nil)
+	visitorRepo.On("Sketch", mock.Anything, []string{"url1"}, time.Time{}, time.Time{}).Return(sketch, nil)
+
+	result, err := svc.Stats(context.Background(), "url1", "owner1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 10, result.StatsResume.Clicks)
+	assert.Equal(t, uint64(3), result.StatsResume.Uniques)
+}
+
+func TestURLService_Resolve_FingerprintsVisitor(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	statsRepo := new(MockStatsRepo)
+	recorder := &captureRecorder{}
+	svc := services.NewURLService(urlRepo, new(MockIDGen), statsRepo,
+		services.WithClickRecorder(recorder),
+		services.WithUniqueVisitors(new(MockVisitorRepo), []byte("key")))
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{ID: "url1"}, nil)
+
+	_, _ = svc.Resolve(context.Background(), "url1", browserVisit("1.2.3.4"))
+	_, _ = svc.Resolve(context.Background(), "url1", browserVisit("1.2.3.4"))
+	_, _ = svc.Resolve(context.Background(), "url1", browserVisit("5.6.7.8"))
+	_, _ = svc.Resolve(context.Background(), "url1", dto.Visit{IP: "1.2.3.4", UserAgent: "curl/8.4.0", Accept: "*/*"})
+
+	assert.Len(t, recorder.stats, 4)
+	assert.NotZero(t, recorder.stats[0].VisitorHash)
+	assert.Equal(t, recorder.stats[0].VisitorHash, recorder.stats[1].VisitorHash)
+	assert.NotEqual(t, recorder.stats[0].VisitorHash, recorder.stats[2].VisitorHash)
+	assert.Zero(t, recorder.stats[3].VisitorHash)
+}
+
+func TestURLService_Resolve_ClassifiesClicks(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	recorder := &captureRecorder{}
+	svc := services.NewURLService(urlRepo, new(MockIDGen), new(MockStatsRepo), services.WithClickRecorder(recorder))
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{ID: "url1"}, nil)
+
+	_, _ = svc.Resolve(context.Background(), "url1", browserVisit("1.2.3.4"))
+	_, _ = svc.Resolve(context.Background(), "url1", dto.Visit{Method: "GET", UserAgent: "Slackbot-LinkExpanding 1.0", Accept: "*/*"})
+	_, _ = svc.Resolve(context.Background(), "url1", dto.Visit{Method: "GET", UserAgent: "python-requests/2.31", Accept: "*/*"})
+
+	assert.Equal(t, "human", recorder.stats[0].Class)
+	assert.Equal(t, "preview", recorder.stats[1].Class)
+	assert.Equal(t, "bot", recorder.stats[2].Class)
+}
+
+func browserVisit(ip string) dto.Visit {
+	return dto.Visit{
+		Method:    "GET",
+		IP:        ip,
+		UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+		Accept:    "text/html,application/xhtml+xml,*/*;q=0.8",
+	}
+}
+
+type captureRecorder struct {
+	stats []*entity.URLStat
+}
+
+func (r *captureRecorder) Record(_ context.Context, stat *entity.URLStat) {
+	r.stats = append(r.stats, stat)
+}
+
+func bucketClicks(buckets []dto.Bucket) []int {
+	clicks := make([]int, len(buckets))
+	for i, b := range buckets {
+		clicks[i] = b.Clicks
+	}
+	return clicks
+}
+
+// ----------------- Stats / Clicks -----------------
+
+func TestURLService_Stats_ReturnsSummaryOnly(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	statsRepo := new(MockStatsRepo)
+	svc := services.NewURLService(urlRepo, new(MockIDGen), statsRepo)
+
+	last := time.Now()
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{ID: "url1", OwnerID: "owner1", ClickCount: 5, LastClick: last}, nil)
+
+	result, err := svc.Stats(context.Background(), "url1", "owner1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, result.StatsResume.Clicks)
+	assert.Equal(t, last, result.StatsResume.LastClick)
+	statsRepo.AssertNotCalled(t, "FindPage", mock.Anything, mock.Anything)
+}
+
+func TestURLService_Stats_BypassesRedirectCache(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	urlRepo := new(MockURLRepo)
+	statsRepo := new(MockStatsRepo)
+	redirects := cache.NewRedisURLRepository(urlRepo, client, time.Hour, time.Minute)
+	svc := services.NewURLService(urlRepo, new(MockIDGen), statsRepo, services.WithRedirectRepository(redirects))
+
+	stored := &entity.URL{ID: "url1", OwnerID: "owner1", OriginalURL: "https://example.com"}
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(stored, nil)
+	urlRepo.On("IncrementClick", mock.Anything, "url1").Run(func(mock.Arguments) {
+		stored.ClickCount++
+		stored.LastClick = time.Now()
+	}).Return(nil)
+	statsRepo.On("Save", mock.Anything, mock.Anything).Return(nil)
+
+	_, err := svc.Resolve(context.Background(), "url1", browserVisit("1.2.3.4"))
+	assert.NoError(t, err)
+	_, err = svc.Resolve(context.Background(), "url1", browserVisit("1.2.3.4"))
+	assert.NoError(t, err)
+
+	result, err := svc.Stats(context.Background(), "url1", "owner1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.StatsResume.Clicks)
+	assert.False(t, result.StatsResume.LastClick.IsZero())
+	// The second redirect came from Redis; only the first one and Stats hit
+	// the repository.
+	urlRepo.AssertNumberOfCalls(t, "FindByID", 2)
+}
+
+func TestURLService_Clicks_Page(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	statsRepo := new(MockStatsRepo)
+	svc := services.NewURLService(urlRepo, new(MockIDGen), statsRepo)
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{ID: "url1", OwnerID: "owner1"}, nil)
+	statsRepo.On("FindPage", mock.Anything, repository.ClickQuery{
+		URLID:       "url1",
+		From:        time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+		RefererHost: "news.ycombinator.com",
+		UserAgent:   "iPhone",
+		Cursor:      "c1",
+		Limit:       100,
+	}).Return([]entity.URLStat{{ID: "s1", URLID: "url1"}}, "s1", nil)
+
+	page, err := svc.Clicks(context.Background(), "url1", "owner1", dto.ClickLogInput{
+		From:        "2025-03-01",
+		RefererHost: "WWW.News.YCombinator.com",
+		UserAgent:   "iPhone",
+		Cursor:      "c1",
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, page.Data, 1)
+	assert.Equal(t, "s1", page.NextCursor)
+}
+
+func TestURLService_Clicks_InvalidLimit(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	svc := services.NewURLService(urlRepo, new(MockIDGen), new(MockStatsRepo))
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{ID: "url1", OwnerID: "owner1"}, nil)
+
+	_, err := svc.Clicks(context.Background(), "url1", "owner1", dto.ClickLogInput{Limit: 5000})
+	assert.ErrorIs(t, err, exceptions.ErrInvalidStatsQuery)
+}
+
+// ----------------- Breakdown -----------------
+
+func TestURLService_Breakdown_Percentages(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	statsRepo := new(MockStatsRepo)
+	svc := services.NewURLService(urlRepo, new(MockIDGen), statsRepo)
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{ID: "url1", OwnerID: "owner1"}, nil)
+	statsRepo.On("Breakdown", mock.Anything, repository.BreakdownQuery{
+		URLIDs:    []string{"url1"},
+		Dimension: repository.DimensionPlatform,
+		Limit:     10,
+	}).Return([]entity.BreakdownRow{{Key: "iOS Safari", Clicks: 42}, {Key: "", Clicks: 8}}, 100, nil)
+
+	breakdown, err := svc.Breakdown(context.Background(), "url1", "owner1", dto.BreakdownInput{By: "platform"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 100, breakdown.Total)
+	assert.Equal(t, []dto.BreakdownRow{
+		{Key: "iOS Safari", Clicks: 42, Percent: 42},
+		{Key: "unknown", Clicks: 8, Percent: 8},
+	}, breakdown.Rows)
+}
+
+func TestURLService_Breakdown_InvalidDimension(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	svc := services.NewURLService(urlRepo, new(MockIDGen), new(MockStatsRepo))
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{ID: "url1", OwnerID: "owner1"}, nil)
+
+	_, err := svc.Breakdown(context.Background(), "url1", "owner1", dto.BreakdownInput{By: "shoe_size"})
+	assert.ErrorIs(t, err, exceptions.ErrInvalidStatsQuery)
+}
+
+// ----------------- Referrers -----------------
+
+func TestURLService_Referrers_DefaultsToHost(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	statsRepo := new(MockStatsRepo)
+	svc := services.NewURLService(urlRepo, new(MockIDGen), statsRepo)
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{ID: "url1", OwnerID: "owner1"}, nil)
+	statsRepo.On("Breakdown", mock.Anything, repository.BreakdownQuery{
+		URLIDs:    []string{"url1"},
+		Dimension: repository.DimensionRefererHost,
+		Limit:     5,
+	}).Return([]entity.BreakdownRow{{Key: "t.co", Clicks: 3}, {Key: "", Clicks: 1}}, 4, nil)
+
+	breakdown, err := svc.Referrers(context.Background(), "url1", "owner1", dto.BreakdownInput{Limit: 5})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "host", breakdown.By)
+	assert.Equal(t, []dto.BreakdownRow{
+		{Key: "t.co", Clicks: 3, Percent: 75},
+		{Key: "direct", Clicks: 1, Percent: 25},
+	}, breakdown.Rows)
+}
+
+func TestURLService_Referrers_InvalidGrouping(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	svc := services.NewURLService(urlRepo, new(MockIDGen), new(MockStatsRepo))
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{ID: "url1", OwnerID: "owner1"}, nil)
+
+	_, err := svc.Referrers(context.Background(), "url1", "owner1", dto.BreakdownInput{By: "browser"})
+	assert.ErrorIs(t, err, exceptions.ErrInvalidStatsQuery)
+}
+
+func TestURLService_Resolve_NormalizesReferrer(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	recorder := &captureRecorder{}
+	svc := services.NewURLService(urlRepo, new(MockIDGen), new(MockStatsRepo),
+		services.WithClickRecorder(recorder), services.WithInternalHosts("sho.rt"))
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{ID: "url1"}, nil)
+
+	for _, ref := range []string{"https://m.facebook.com/story", "https://sho.rt/other", ""} {
+		_, err := svc.Resolve(context.Background(), "url1", dto.Visit{Referer: ref})
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, "facebook.com", recorder.stats[0].RefererHost)
+	assert.Equal(t, "social", recorder.stats[0].RefererSource)
+	assert.Equal(t, "internal", recorder.stats[1].RefererSource)
+	assert.Equal(t, "", recorder.stats[2].RefererHost)
+	assert.Equal(t, "direct", recorder.stats[2].RefererSource)
+}
+
+// ----------------- Geo -----------------
+
+type stubGeo map[string]geoip.Location
+
+func (g stubGeo) Lookup(ip string) (geoip.Location, bool) {
+	loc, ok := g[ip]
+	return loc, ok
+}
+
+func TestURLService_Resolve_LocatesClient(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	recorder := &captureRecorder{}
+	geo := stubGeo{"200.1.2.3": {Country: "BR", Region: "BR-SP", City: "São Paulo", Latitude: -23.5, Longitude: -46.6}}
+	svc := services.NewURLService(urlRepo, new(MockIDGen), new(MockStatsRepo),
+		services.WithClickRecorder(recorder), services.WithGeoLocator(geo))
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{ID: "url1"}, nil)
+
+	_, err := svc.Resolve(context.Background(), "url1", dto.Visit{IP: "200.1.2.3"})
+	assert.NoError(t, err)
+	_, err = svc.Resolve(context.Background(), "url1", dto.Visit{IP: "10.0.0.1"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "BR-SP", recorder.stats[0].Region)
+	assert.Equal(t, -23.5, recorder.stats[0].Latitude)
+	assert.Equal(t, "", recorder.stats[1].Country)
+}
+
+func TestURLService_GeoMap(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	statsRepo := new(MockStatsRepo)
+	svc := services.NewURLService(urlRepo, new(MockIDGen), statsRepo)
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{ID: "url1", OwnerID: "owner1"}, nil)
+	statsRepo.On("Breakdown", mock.Anything, repository.BreakdownQuery{
+		URLIDs:    []string{"url1"},
+		Dimension: repository.DimensionCountry,
+		Limit:     300,
+	}).Return([]entity.BreakdownRow{{Key: "BR", Clicks: 7}, {Key: "", Clicks: 2}}, 9, nil)
+	statsRepo.On("Locations", mock.Anything, repository.GeoQuery{
+		URLIDs: []string{"url1"},
+		Limit:  1000,
+	}).Return([]entity.GeoPoint{{Latitude: -23.5, Longitude: -46.6, Clicks: 5}}, nil)
+
+	geoMap, err := svc.GeoMap(context.Background(), "url1", "owner1", dto.GeoMapInput{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"BR": 7}, geoMap.Countries)
+	assert.Equal(t, []dto.GeoPoint{{Lat: -23.5, Lon: -46.6, Clicks: 5}}, geoMap.Points)
+}
+
+func TestURLService_Geo_InvalidGrouping(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	svc := services.NewURLService(urlRepo, new(MockIDGen), new(MockStatsRepo))
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{ID: "url1", OwnerID: "owner1"}, nil)
+
+	_, err := svc.Geo(context.Background(), "url1", "owner1", dto.BreakdownInput{By: "planet"})
+	assert.ErrorIs(t, err, exceptions.ErrInvalidStatsQuery)
+}
+
+func TestURLService_Resolve_ParsesUserAgent(t *testing.T) {
+	urlRepo := new(MockURLRepo)
+	recorder := &captureRecorder{}
+	svc := services.NewURLService(urlRepo, new(MockIDGen), new(MockStatsRepo), services.WithClickRecorder(recorder))
+
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(&entity.URL{ID: "url1"}, nil)
+
+	_, err := svc.Resolve(context.Background(), "url1", dto.Visit{
+		UserAgent:          "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36",
+		ClientHintUA:       `"Google Chrome";v="124", "Chromium";v="124"`,
+		ClientHintPlatform: `"Android"`,
+	})
+
+	assert.NoError(t, err)
+	stat := recorder.stats[0]
+	assert.Equal(t, "Chrome", stat.Browser)
+	assert.Equal(t, "124", stat.BrowserVersion)
+	assert.Equal(t, "Android", stat.OS)
+	assert.Equal(t, "mobile", stat.Device)
+}
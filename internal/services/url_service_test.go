@@ -1,11 +1,15 @@
 package services_test
 
 import (
+	"context"
 	"errors"
 	"testing"
+	"time"
 	"url-shortener/internal/domain/entity"
 	"url-shortener/internal/domain/exceptions"
+	"url-shortener/internal/domain/repository"
 	"url-shortener/internal/services"
+	"url-shortener/internal/services/dto"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -17,21 +21,96 @@ type MockURLRepo struct {
 	mock.Mock
 }
 
-func (m *MockURLRepo) Save(u *entity.URL) error {
-	args := m.Called(u)
+func (m *MockURLRepo) Save(ctx context.Context, u *entity.URL) error {
+	args := m.Called(ctx, u)
 	return args.Error(0)
 }
 
-func (m *MockURLRepo) FindByID(id string) (*entity.URL, error) {
-	args := m.Called(id)
+func (m *MockURLRepo) FindByID(ctx context.Context, id string) (*entity.URL, error) {
+	args := m.Called(ctx, id)
 	if args.Get(0) != nil {
 		return args.Get(0).(*entity.URL), args.Error(1)
 	}
 	return nil, args.Error(1)
 }
 
-func (m *MockURLRepo) IncrementClick(id string) error {
-	args := m.Called(id)
+func (m *MockURLRepo) FindTop(ctx context.Context, limit int) ([]entity.URL, error) {
+	args := m.Called(ctx, limit)
+	if args.Get(0) != nil {
+		return args.Get(0).([]entity.URL), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockURLRepo) FindByOwner(ctx context.Context, ownerID string) ([]entity.URL, error) {
+	args := m.Called(ctx, ownerID)
+	if args.Get(0) != nil {
+		return args.Get(0).([]entity.URL), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockURLRepo) FindByCampaign(ctx context.Context, campaignID string) ([]entity.URL, error) {
+	args := m.Called(ctx, campaignID)
+	if args.Get(0) != nil {
+		return args.Get(0).([]entity.URL), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockURLRepo) List(ctx context.Context, filter repository.URLFilter) ([]entity.URL, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) != nil {
+		return args.Get(0).([]entity.URL), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockURLRepo) SetMetadata(ctx context.Context, id string, metadata entity.LinkMetadata) error {
+	args := m.Called(ctx, id, metadata)
+	return args.Error(0)
+}
+
+func (m *MockURLRepo) SetCampaign(ctx context.Context, id, campaignID, originalURL string) error {
+	args := m.Called(ctx, id, campaignID, originalURL)
+	return args.Error(0)
+}
+
+func (m *MockURLRepo) SetLabels(ctx context.Context, id string, tags []string, folder string) error {
+	args := m.Called(ctx, id, tags, folder)
+	return args.Error(0)
+}
+
+func (m *MockURLRepo) SetMetadataOverride(ctx context.Context, id string, override entity.LinkMetadata) error {
+	args := m.Called(ctx, id, override)
+	return args.Error(0)
+}
+
+func (m *MockURLRepo) SetConversions(ctx context.Context, id, clickIDParam string, window time.Duration, postbackSecret string) error {
+	args := m.Called(ctx, id, clickIDParam, window, postbackSecret)
+	return args.Error(0)
+}
+
+func (m *MockURLRepo) IncrementClick(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockURLRepo) IncrementClicks(ctx context.Context, increments []repository.ClickIncrement) error {
+	args := m.Called(ctx, increments)
+	return args.Error(0)
+}
+
+func (m *MockURLRepo) ClickCounts(ctx context.Context) (map[string]int, error) {
+	args := m.Called(ctx)
+	if args.Get(0) != nil {
+		return args.Get(0).(map[string]int), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockURLRepo) AdjustClickCount(ctx context.Context, id string, delta int) error {
+	args := m.Called(ctx, id, delta)
 	return args.Error(0)
 }
 
@@ -39,15 +118,83 @@ type MockStatsRepo struct {
 	mock.Mock
 }
 
-func (m *MockStatsRepo) Save(stat *entity.URLStat) error {
-	args := m.Called(stat)
+func (m *MockStatsRepo) Save(ctx context.Context, stat *entity.URLStat) error {
+	args := m.Called(ctx, stat)
+	return args.Error(0)
+}
+
+func (m *MockStatsRepo) SaveMany(ctx context.Context, stats []*entity.URLStat) error {
+	args := m.Called(ctx, stats)
 	return args.Error(0)
 }
 
-func (m *MockStatsRepo) FindByURLID(urlID string) ([]entity.URLStat, error) {
-	args := m.Called(urlID)
+func (m *MockStatsRepo) FindPage(ctx context.Context, query repository.ClickQuery) ([]entity.URLStat, string, error) {
+	args := m.Called(ctx, query)
+	if args.Get(0) != nil {
+		return args.Get(0).([]entity.URLStat), args.String(1), args.Error(2)
+	}
+	return nil, args.String(1), args.Error(2)
+}
+
+func (m *MockStatsRepo) CountByURL(ctx context.Context) (map[string]int, error) {
+	args := m.Called(ctx)
+	if args.Get(0) != nil {
+		return args.Get(0).(map[string]int), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockStatsRepo) TimeSeries(ctx context.Context, query repository.TimeSeriesQuery) ([]entity.ClickBucket, error) {
+	args := m.Called(ctx, query)
 	if args.Get(0) != nil {
-		return args.Get(0).([]entity.URLStat), args.Error(1)
+		return args.Get(0).([]entity.ClickBucket), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockStatsRepo) Breakdown(ctx context.Context, query repository.BreakdownQuery) ([]entity.BreakdownRow, int, error) {
+	args := m.Called(ctx, query)
+	if args.Get(0) != nil {
+		return args.Get(0).([]entity.BreakdownRow), args.Int(1), args.Error(2)
+	}
+	return nil, args.Int(1), args.Error(2)
+}
+
+func (m *MockStatsRepo) DeleteBefore(ctx context.Context, query repository.PurgeQuery) (int, error) {
+	args := m.Called(ctx, query)
+	return args.Int(0), args.Error(1)
+}
+
+// Stream feeds fn the clicks returned by the expectation.
+func (m *MockStatsRepo) Stream(ctx context.Context, query repository.ExportQuery, fn func(*entity.URLStat) error) error {
+	args := m.Called(ctx, query)
+	if stats, ok := args.Get(0).([]entity.URLStat); ok {
+		for i := range stats {
+			if err := fn(&stats[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockStatsRepo) FindByClickID(ctx context.Context, clickID string) (*entity.URLStat, error) {
+	args := m.Called(ctx, clickID)
+	if args.Get(0) != nil {
+		return args.Get(0).(*entity.URLStat), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockStatsRepo) OldestClick(ctx context.Context) (time.Time, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+func (m *MockStatsRepo) Locations(ctx context.Context, query repository.GeoQuery) ([]entity.GeoPoint, error) {
+	args := m.Called(ctx, query)
+	if args.Get(0) != nil {
+		return args.Get(0).([]entity.GeoPoint), args.Error(1)
 	}
 	return nil, args.Error(1)
 }
@@ -71,9 +218,9 @@ func TestURLService_Shorten_Success(t *testing.T) {
 	svc := services.NewURLService(urlRepo, idGen, statsRepo)
 
 	idGen.On("Generate").Return("abc123", nil)
-	urlRepo.On("Save", mock.AnythingOfType("*entity.URL")).Return(nil)
+	urlRepo.On("Save", mock.Anything, mock.AnythingOfType("*entity.URL")).Return(nil)
 
-	urlEntity, err := svc.Shorten("https://example.com", "owner1")
+	urlEntity, err := svc.Shorten(context.Background(), dto.URL{URL: "https://example.com"}, "owner1")
 
 	assert.NoError(t, err)
 	assert.NotNil(t, urlEntity)
@@ -89,7 +236,7 @@ func TestURLService_Shorten_InvalidURL(t *testing.T) {
 
 	svc := services.NewURLService(urlRepo, idGen, statsRepo)
 
-	_, err := svc.Shorten("invalid-url", "owner1")
+	_, err := svc.Shorten(context.Background(), dto.URL{URL: "invalid-url"}, "owner1")
 	assert.ErrorIs(t, err, exceptions.ErrInvalidURL)
 }
 
@@ -105,7 +252,7 @@ func TestURLService_Shorten_PrivateIP(t *testing.T) {
 
 	idGen.On("Generate").Return("some-id", nil)
 
-	result, err := svc.Shorten(privateURL, ownerID)
+	result, err := svc.Shorten(context.Background(), dto.URL{URL: privateURL}, ownerID)
 
 	assert.ErrorIs(t, err, exceptions.ErrInvalidURL)
 	assert.Nil(t, result)
@@ -120,7 +267,7 @@ func TestURLService_Shorten_GenerateError(t *testing.T) {
 
 	idGen.On("Generate").Return("", errors.New("generate error"))
 
-	_, err := svc.Shorten("https://example.com", "owner1")
+	_, err := svc.Shorten(context.Background(), dto.URL{URL: "https://example.com"}, "owner1")
 	assert.Error(t, err)
 	assert.EqualError(t, err, "generate error")
 }
@@ -133,9 +280,9 @@ func TestURLService_Shorten_SaveError(t *testing.T) {
 	svc := services.NewURLService(urlRepo, idGen, statsRepo)
 
 	idGen.On("Generate").Return("abc123", nil)
-	urlRepo.On("Save", mock.AnythingOfType("*entity.URL")).Return(errors.New("save error"))
+	urlRepo.On("Save", mock.Anything, mock.AnythingOfType("*entity.URL")).Return(errors.New("save error"))
 
-	_, err := svc.Shorten("https://example.com", "owner1")
+	_, err := svc.Shorten(context.Background(), dto.URL{URL: "https://example.com"}, "owner1")
 	assert.Error(t, err)
 	assert.EqualError(t, err, "save error")
 }
@@ -154,15 +301,15 @@ func TestURLService_Resolve_Success(t *testing.T) {
 		OriginalURL: "https://example.com",
 	}
 
-	urlRepo.On("FindByID", "abc123").Return(urlEntity, nil)
-	urlRepo.On("IncrementClick", "abc123").Return(nil)
-	statsRepo.On("Save", mock.AnythingOfType("*entity.URLStat")).Return(nil)
+	urlRepo.On("FindByID", mock.Anything, "abc123").Return(urlEntity, nil)
+	urlRepo.On("IncrementClick", mock.Anything, "abc123").Return(nil)
+	statsRepo.On("Save", mock.Anything, mock.AnythingOfType("*entity.URLStat")).Return(nil)
 
-	res, err := svc.Resolve("abc123", "1.2.3.4", "user-agent", "referer")
+	res, err := svc.Resolve(context.Background(), "abc123", dto.Visit{IP: "1.2.3.4", UserAgent: "user-agent", Referer: "referer"})
 
 	assert.NoError(t, err)
 	assert.NotNil(t, res)
-	assert.Equal(t, "https://example.com", res.OriginalURL)
+	assert.Equal(t, "https://example.com", res.Location)
 }
 
 func TestURLService_Resolve_NotFound(t *testing.T) {
@@ -172,13 +319,12 @@ func TestURLService_Resolve_NotFound(t *testing.T) {
 
 	svc := services.NewURLService(urlRepo, idGen, statsRepo)
 
-	urlRepo.On("FindByID", "abc123").Return(nil, errors.New("not found"))
+	urlRepo.On("FindByID", mock.Anything, "abc123").Return(nil, errors.New("not found"))
 
-	res, err := svc.Resolve("abc123", "1.2.3.4", "user-agent", "referer")
+	res, err := svc.Resolve(context.Background(), "abc123", dto.Visit{IP: "1.2.3.4", UserAgent: "user-agent", Referer: "referer"})
 
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, exceptions.ErrURLNotFound)
 	assert.Nil(t, res)
-	assert.EqualError(t, err, "not found")
 }
 
 func TestURLService_Resolve_IncrementClickError(t *testing.T) {
@@ -190,14 +336,14 @@ func TestURLService_Resolve_IncrementClickError(t *testing.T) {
 
 	urlEntity := &entity.URL{ID: "abc123", OriginalURL: "https://example.com"}
 
-	urlRepo.On("FindByID", "abc123").Return(urlEntity, nil)
-	urlRepo.On("IncrementClick", "abc123").Return(errors.New("increment error"))
+	urlRepo.On("FindByID", mock.Anything, "abc123").Return(urlEntity, nil)
+	urlRepo.On("IncrementClick", mock.Anything, "abc123").Return(errors.New("increment error"))
+	statsRepo.On("Save", mock.Anything, mock.AnythingOfType("*entity.URLStat")).Return(nil)
 
-	res, err := svc.Resolve("abc123", "1.2.3.4", "user-agent", "referer")
+	res, err := svc.Resolve(context.Background(), "abc123", dto.Visit{IP: "1.2.3.4", UserAgent: "user-agent", Referer: "referer"})
 
-	assert.Error(t, err)
-	assert.Nil(t, res)
-	assert.EqualError(t, err, "increment error")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", res.Location)
 }
 
 func TestURLService_Resolve_StatsSaveError(t *testing.T) {
@@ -209,15 +355,14 @@ func TestURLService_Resolve_StatsSaveError(t *testing.T) {
 
 	urlEntity := &entity.URL{ID: "abc123", OriginalURL: "https://example.com"}
 
-	urlRepo.On("FindByID", "abc123").Return(urlEntity, nil)
-	urlRepo.On("IncrementClick", "abc123").Return(nil)
-	statsRepo.On("Save", mock.AnythingOfType("*entity.URLStat")).Return(errors.New("stats save error"))
+	urlRepo.On("FindByID", mock.Anything, "abc123").Return(urlEntity, nil)
+	urlRepo.On("IncrementClick", mock.Anything, "abc123").Return(nil)
+	statsRepo.On("Save", mock.Anything, mock.AnythingOfType("*entity.URLStat")).Return(errors.New("stats save error"))
 
-	res, err := svc.Resolve("abc123", "1.2.3.4", "user-agent", "referer")
+	res, err := svc.Resolve(context.Background(), "abc123", dto.Visit{IP: "1.2.3.4", UserAgent: "user-agent", Referer: "referer"})
 
-	assert.Error(t, err)
-	assert.Nil(t, res)
-	assert.EqualError(t, err, "stats save error")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", res.Location)
 }
 
 // ----------------- Stats -----------------
@@ -247,10 +392,10 @@ func TestURLService_Resolve_StatsSaveError(t *testing.T) {
 // 		},
 // 	}
 
-// 	urlRepo.On("FindByID", "url1").Return(urlEntity, nil)
-// 	statsRepo.On("FindByURLID", "url1").Return(statsEntities, nil)
+// 	urlRepo.On("FindByID", mock.Anything, "url1").Return(urlEntity, nil)
+// 	statsRepo.On("FindByURLID", mock.Anything, "url1").Return(statsEntities, nil)
 
-// 	result, err := svc.Stats("url1", "owner1")
+// 	result, err := svc.Stats(context.Background(), "url1", "owner1")
 
 // 	assert.NoError(t, err)
 // 	assert.NotNil(t, result)
@@ -271,9 +416,9 @@ func TestURLService_Stats_Unauthorized(t *testing.T) {
 		OwnerID: "owner1",
 	}
 
-	urlRepo.On("FindByID", "url1").Return(urlEntity, nil)
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(urlEntity, nil)
 
-	result, err := svc.Stats("url1", "otherUser")
+	result, err := svc.Stats(context.Background(), "url1", "otherUser")
 
 	assert.ErrorIs(t, err, exceptions.ErrUnauthorizedURLStatistics)
 	assert.Nil(t, result)
@@ -286,16 +431,15 @@ func TestURLService_Stats_URLNotFound(t *testing.T) {
 
 	svc := services.NewURLService(urlRepo, idGen, statsRepo)
 
-	urlRepo.On("FindByID", "url1").Return(nil, errors.New("not found"))
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(nil, errors.New("not found"))
 
-	result, err := svc.Stats("url1", "owner1")
+	result, err := svc.Stats(context.Background(), "url1", "owner1")
 
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, exceptions.ErrURLNotFound)
 	assert.Nil(t, result)
-	assert.EqualError(t, err, "not found")
 }
 
-func TestURLService_Stats_StatsRepoError(t *testing.T) {
+func TestURLService_Clicks_StatsRepoError(t *testing.T) {
 	urlRepo := new(MockURLRepo)
 	statsRepo := new(MockStatsRepo)
 	idGen := new(MockIDGen)
@@ -307,10 +451,10 @@ func TestURLService_Stats_StatsRepoError(t *testing.T) {
 		OwnerID: "owner1",
 	}
 
-	urlRepo.On("FindByID", "url1").Return(urlEntity, nil)
-	statsRepo.On("FindByURLID", "url1").Return(nil, errors.New("stats error"))
+	urlRepo.On("FindByID", mock.Anything, "url1").Return(urlEntity, nil)
+	statsRepo.On("FindPage", mock.Anything, mock.Anything).Return(nil, "", errors.New("stats error"))
 
-	result, err := svc.Stats("url1", "owner1")
+	result, err := svc.Clicks(context.Background(), "url1", "owner1", dto.ClickLogInput{})
 
 	assert.Error(t, err)
 	assert.Nil(t, result)
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"url-shortener/internal/domain/entity"
 	"url-shortener/internal/domain/exceptions"
 	"url-shortener/internal/domain/repository"
@@ -23,8 +24,8 @@ func NewUserService(repo repository.UserRepository, hasher pkg.Hasher, token sec
 	}
 }
 
-func (s *UserService) Save(user *dto.UserInput) (*dto.UserOutput, error) {
-	userEmail, _ := s.repo.FindByEmail(user.Email)
+func (s *UserService) Save(ctx context.Context, user *dto.UserInput) (*dto.UserOutput, error) {
+	userEmail, _ := s.repo.FindByEmail(ctx, user.Email)
 	if userEmail != nil {
 		return nil, exceptions.ErrEmailAlreadyExists
 	}
@@ -40,7 +41,7 @@ func (s *UserService) Save(user *dto.UserInput) (*dto.UserOutput, error) {
 		HashedPassword: hashedPassword,
 	}
 
-	savedUser, err := s.repo.Save(&userEntity)
+	savedUser, err := s.repo.Save(ctx, &userEntity)
 	if err != nil {
 		return nil, err
 	}
@@ -55,8 +56,8 @@ func (s *UserService) Save(user *dto.UserInput) (*dto.UserOutput, error) {
 	return &result, nil
 }
 
-func (s *UserService) LoginUser(input *dto.LoginUserInput) (*dto.LoginUserOutput, error) {
-	user, err := s.repo.FindByEmail(input.Email)
+func (s *UserService) LoginUser(ctx context.Context, input *dto.LoginUserInput) (*dto.LoginUserOutput, error) {
+	user, err := s.repo.FindByEmail(ctx, input.Email)
 	if err != nil {
 		return nil, exceptions.ErrInvalidCredentials
 	}
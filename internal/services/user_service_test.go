@@ -1,6 +1,7 @@
 package services_test
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
@@ -20,30 +21,35 @@ type MockUserRepo struct {
 	mock.Mock
 }
 
-func (m *MockUserRepo) Save(u *entity.User) (*entity.User, error) {
-	args := m.Called(u)
+func (m *MockUserRepo) Save(ctx context.Context, u *entity.User) (*entity.User, error) {
+	args := m.Called(ctx, u)
 	if args.Get(0) != nil {
 		return args.Get(0).(*entity.User), args.Error(1)
 	}
 	return nil, args.Error(1)
 }
 
-func (m *MockUserRepo) FindByID(id string) (*entity.User, error) {
-	args := m.Called(id)
+func (m *MockUserRepo) FindByID(ctx context.Context, id string) (*entity.User, error) {
+	args := m.Called(ctx, id)
 	if args.Get(0) != nil {
 		return args.Get(0).(*entity.User), args.Error(1)
 	}
 	return nil, args.Error(1)
 }
 
-func (m *MockUserRepo) FindByEmail(email string) (*entity.User, error) {
-	args := m.Called(email)
+func (m *MockUserRepo) FindByEmail(ctx context.Context, email string) (*entity.User, error) {
+	args := m.Called(ctx, email)
 	if args.Get(0) != nil {
 		return args.Get(0).(*entity.User), args.Error(1)
 	}
 	return nil, args.Error(1)
 }
 
+func (m *MockUserRepo) SetWebhook(ctx context.Context, id, url, secret string) error {
+	args := m.Called(ctx, id, url, secret)
+	return args.Error(0)
+}
+
 type MockHasher struct {
 	mock.Mock
 }
@@ -91,11 +97,11 @@ func TestUserService_Save_Success(t *testing.T) {
 		CreatedAt:      time.Now(),
 	}
 
-	repo.On("FindByEmail", input.Email).Return(nil, nil)
+	repo.On("FindByEmail", mock.Anything, input.Email).Return(nil, nil)
 	hasher.On("HashPassword", input.Password).Return(hashed, nil)
-	repo.On("Save", mock.AnythingOfType("*entity.User")).Return(savedUser, nil)
+	repo.On("Save", mock.Anything, mock.AnythingOfType("*entity.User")).Return(savedUser, nil)
 
-	result, err := svc.Save(input)
+	result, err := svc.Save(context.Background(), input)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
@@ -113,9 +119,9 @@ func TestUserService_Save_EmailExists(t *testing.T) {
 	input := &dto.UserInput{Name: "John", Email: "john@example.com", Password: "123"}
 	existingUser := &entity.User{ID: "1", Email: input.Email}
 
-	repo.On("FindByEmail", input.Email).Return(existingUser, nil)
+	repo.On("FindByEmail", mock.Anything, input.Email).Return(existingUser, nil)
 
-	result, err := svc.Save(input)
+	result, err := svc.Save(context.Background(), input)
 
 	assert.ErrorIs(t, err, exceptions.ErrEmailAlreadyExists)
 	assert.Nil(t, result)
@@ -129,10 +135,10 @@ func TestUserService_Save_HashError(t *testing.T) {
 	svc := services.NewUserService(repo, hasher, token)
 
 	input := &dto.UserInput{Name: "John", Email: "john@example.com", Password: "123"}
-	repo.On("FindByEmail", input.Email).Return(nil, nil)
+	repo.On("FindByEmail", mock.Anything, input.Email).Return(nil, nil)
 	hasher.On("HashPassword", input.Password).Return("", errors.New("hash error"))
 
-	result, err := svc.Save(input)
+	result, err := svc.Save(context.Background(), input)
 
 	assert.Error(t, err)
 	assert.Nil(t, result)
@@ -149,11 +155,11 @@ func TestUserService_Save_SaveError(t *testing.T) {
 	input := &dto.UserInput{Name: "John", Email: "john@example.com", Password: "123"}
 	hashed := "hashed123"
 
-	repo.On("FindByEmail", input.Email).Return(nil, nil)
+	repo.On("FindByEmail", mock.Anything, input.Email).Return(nil, nil)
 	hasher.On("HashPassword", input.Password).Return(hashed, nil)
-	repo.On("Save", mock.AnythingOfType("*entity.User")).Return(nil, errors.New("save error"))
+	repo.On("Save", mock.Anything, mock.AnythingOfType("*entity.User")).Return(nil, errors.New("save error"))
 
-	result, err := svc.Save(input)
+	result, err := svc.Save(context.Background(), input)
 
 	assert.Error(t, err)
 	assert.Nil(t, result)
@@ -170,11 +176,11 @@ func TestUserService_LoginUser_Success(t *testing.T) {
 	input := &dto.LoginUserInput{Email: "john@example.com", Password: "123"}
 	user := &entity.User{ID: "1", Email: input.Email, HashedPassword: "hashed123"}
 
-	repo.On("FindByEmail", input.Email).Return(user, nil)
+	repo.On("FindByEmail", mock.Anything, input.Email).Return(user, nil)
 	hasher.On("CheckPasswordHash", input.Password, user.HashedPassword).Return(true)
 	token.On("GenerateToken", user.ID).Return("jwt-token", nil)
 
-	result, err := svc.LoginUser(input)
+	result, err := svc.LoginUser(context.Background(), input)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
@@ -191,10 +197,10 @@ func TestUserService_LoginUser_InvalidPassword(t *testing.T) {
 	input := &dto.LoginUserInput{Email: "john@example.com", Password: "wrong"}
 	user := &entity.User{ID: "1", Email: input.Email, HashedPassword: "hashed123"}
 
-	repo.On("FindByEmail", input.Email).Return(user, nil)
+	repo.On("FindByEmail", mock.Anything, input.Email).Return(user, nil)
 	hasher.On("CheckPasswordHash", input.Password, user.HashedPassword).Return(false)
 
-	result, err := svc.LoginUser(input)
+	result, err := svc.LoginUser(context.Background(), input)
 
 	assert.ErrorIs(t, err, exceptions.ErrInvalidCredentials)
 	assert.Nil(t, result)
@@ -208,9 +214,9 @@ func TestUserService_LoginUser_UserNotFound(t *testing.T) {
 	svc := services.NewUserService(repo, hasher, token)
 
 	input := &dto.LoginUserInput{Email: "notfound@example.com", Password: "123"}
-	repo.On("FindByEmail", input.Email).Return(nil, errors.New("not found"))
+	repo.On("FindByEmail", mock.Anything, input.Email).Return(nil, errors.New("not found"))
 
-	result, err := svc.LoginUser(input)
+	result, err := svc.LoginUser(context.Background(), input)
 
 	assert.ErrorIs(t, err, exceptions.ErrInvalidCredentials)
 	assert.Nil(t, result)
@@ -226,11 +232,11 @@ func TestUserService_LoginUser_GenerateTokenError(t *testing.T) {
 	input := &dto.LoginUserInput{Email: "john@example.com", Password: "123"}
 	user := &entity.User{ID: "1", Email: input.Email, HashedPassword: "hashed123"}
 
-	repo.On("FindByEmail", input.Email).Return(user, nil)
+	repo.On("FindByEmail", mock.Anything, input.Email).Return(user, nil)
 	hasher.On("CheckPasswordHash", input.Password, user.HashedPassword).Return(true)
 	token.On("GenerateToken", user.ID).Return("", errors.New("token error"))
 
-	result, err := svc.LoginUser(input)
+	result, err := svc.LoginUser(context.Background(), input)
 
 	assert.ErrorIs(t, err, exceptions.ErrInvalidCredentials)
 	assert.Nil(t, result)
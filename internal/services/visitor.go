@@ -0,0 +1,40 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"time"
+	"url-shortener/internal/domain/entity"
+	"url-shortener/pkg/hll"
+)
+
+// visitorHash fingerprints a visitor as a keyed hash of IP and user agent.
+// The key keeps fingerprints from being recomputed from a known IP, and only
+// the HyperLogLog register derived from it is ever stored.
+func visitorHash(key []byte, ip, userAgent string) uint64 {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(ip))
+	mac.Write([]byte{0})
+	mac.Write([]byte(userAgent))
+	return binary.BigEndian.Uint64(mac.Sum(nil))
+}
+
+// visitorObservations maps the fingerprinted clicks of a batch to register
+// updates of their link's daily sketch.
+func visitorObservations(batch []*entity.URLStat) []entity.VisitorObservation {
+	var observations []entity.VisitorObservation
+	for _, stat := range batch {
+		if stat.VisitorHash == 0 {
+			continue
+		}
+		idx, rank := hll.Position(stat.VisitorHash)
+		observations = append(observations, entity.VisitorObservation{
+			URLID:    stat.URLID,
+			Day:      stat.ClickedAt.UTC().Truncate(24 * time.Hour),
+			Register: idx,
+			Rank:     rank,
+		})
+	}
+	return observations
+}
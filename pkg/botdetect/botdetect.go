@@ -0,0 +1,82 @@
+// Package botdetect classifies requests as human traffic, automated bots or
+// link preview fetchers, from a maintained list of User-Agent signatures and
+// a few request heuristics.
+package botdetect
+
+import (
+	_ "embed"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+const (
+	ClassHuman   = "human"
+	ClassBot     = "bot"
+	ClassPreview = "preview"
+)
+
+type Request struct {
+	Method    string
+	UserAgent string
+	Accept    string
+}
+
+type signature struct {
+	class string
+	token string
+	re    *regexp.Regexp
+}
+
+func (s signature) matches(ua string) bool {
+	if s.re != nil {
+		return s.re.MatchString(ua)
+	}
+	return strings.Contains(ua, s.token)
+}
+
+//go:embed signatures.txt
+var signatureList string
+
+var signatures = parseSignatures(signatureList)
+
+// Classify returns ClassHuman, ClassBot or ClassPreview. Known signatures win;
+// otherwise HEAD requests are treated as link previews, and requests with no
+// User-Agent or no Accept header, which every browser sends on navigation,
+// as bots.
+func Classify(r Request) string {
+	ua := strings.ToLower(r.UserAgent)
+	for _, sig := range signatures {
+		if sig.matches(ua) {
+			return sig.class
+		}
+	}
+
+	switch {
+	case r.Method == http.MethodHead:
+		return ClassPreview
+	case strings.TrimSpace(r.UserAgent) == "", strings.TrimSpace(r.Accept) == "":
+		return ClassBot
+	}
+	return ClassHuman
+}
+
+func parseSignatures(list string) []signature {
+	var sigs []signature
+	for _, line := range strings.Split(list, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		class, token, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		sig := signature{class: class, token: strings.ToLower(strings.TrimSpace(token))}
+		if len(sig.token) > 2 && strings.HasPrefix(sig.token, "/") && strings.HasSuffix(sig.token, "/") {
+			sig.re = regexp.MustCompile(sig.token[1 : len(sig.token)-1])
+		}
+		sigs = append(sigs, sig)
+	}
+	return sigs
+}
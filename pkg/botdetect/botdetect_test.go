@@ -0,0 +1,59 @@
+package botdetect_test
+
+import (
+	"testing"
+	"url-shortener/pkg/botdetect"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const browserAccept = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		name string
+		req  botdetect.Request
+		want string
+	}{
+		{"safari", botdetect.Request{Method: "GET", Accept: browserAccept, UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1"}, botdetect.ClassHuman},
+		{"slack unfurl", botdetect.Request{Method: "GET", Accept: "*/*", UserAgent: "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)"}, botdetect.ClassPreview},
+		{"facebook", botdetect.Request{Method: "GET", Accept: "*/*", UserAgent: "facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)"}, botdetect.ClassPreview},
+		{"twitter", botdetect.Request{Method: "GET", UserAgent: "Twitterbot/1.0"}, botdetect.ClassPreview},
+		{"curl", botdetect.Request{Method: "GET", Accept: "*/*", UserAgent: "curl/8.4.0"}, botdetect.ClassBot},
+		{"googlebot", botdetect.Request{Method: "GET", Accept: browserAccept, UserAgent: "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"}, botdetect.ClassBot},
+		{"generic crawler", botdetect.Request{Method: "GET", Accept: browserAccept, UserAgent: "SomeCrawler/3.0"}, botdetect.ClassBot},
+		{"generic bot", botdetect.Request{Method: "GET", Accept: browserAccept, UserAgent: "Mozilla/5.0 (compatible; Bot/1.0)"}, botdetect.ClassBot},
+		{"cubot phone", botdetect.Request{Method: "GET", Accept: browserAccept, UserAgent: "Mozilla/5.0 (Linux; Android 11; CUBOT X30) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.6367.82 Mobile Safari/537.36"}, botdetect.ClassHuman},
+		{"head request", botdetect.Request{Method: "HEAD", Accept: browserAccept, UserAgent: "Mozilla/5.0 (Windows NT 10.0) Chrome/124.0"}, botdetect.ClassPreview},
+		{"no accept", botdetect.Request{Method: "GET", UserAgent: "Mozilla/5.0 (Windows NT 10.0) Chrome/124.0"}, botdetect.ClassBot},
+		{"no user agent", botdetect.Request{Method: "GET", Accept: browserAccept}, botdetect.ClassBot},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.want, botdetect.Classify(c.req), c.name)
+	}
+}
+
+func TestClassify_InAppBrowsers(t *testing.T) {
+	cases := []struct {
+		name string
+		ua   string
+		want string
+	}{
+		{"snapchat in-app", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 Snapchat/12.80.0.37 (like Safari/8617.2.4.10.8, panda)", botdetect.ClassHuman},
+		{"tumblr in-app", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 Tumblr/iPhone/33.3/333010/17.4/tumblr", botdetect.ClassHuman},
+		{"outlook in-app", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 Outlook-iOS/718.2400.0", botdetect.ClassHuman},
+		{"teams desktop", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 Edg/124.0.0.0 Teams/24102.2223.2870.9480/49", botdetect.ClassHuman},
+		{"viber in-app", "Mozilla/5.0 (Linux; Android 14; Pixel 7) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/124.0.6367.82 Mobile Safari/537.36 Viber/22.6.0.0", botdetect.ClassHuman},
+		{"snapchat preview", "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:109.0) Gecko/20100101 Firefox/114.0 (Snap URL Preview Service; bot; snapchat_preview@snap.com)", botdetect.ClassPreview},
+		{"tumblr preview", "Tumblr/14.0.835.186", botdetect.ClassPreview},
+		{"newer tumblr preview", "Tumblr/15.2.0.1", botdetect.ClassPreview},
+		{"teams preview", "Mozilla/5.0 (Windows NT 6.1; WOW64) AppleWebKit/537.36 (KHTML, like Gecko) SkypeUriPreview Preview/0.5 skype-url-preview@microsoft.com", botdetect.ClassPreview},
+		{"outlook preview", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 BingPreview/1.0b", botdetect.ClassPreview},
+	}
+
+	for _, c := range cases {
+		req := botdetect.Request{Method: "GET", Accept: browserAccept, UserAgent: c.ua}
+		assert.Equal(t, c.want, botdetect.Classify(req), c.name)
+	}
+}
@@ -0,0 +1,86 @@
+// Package geoip resolves IP addresses to a location using a local MaxMind
+// (.mmdb) City or Country database. Lookups never leave the process.
+package geoip
+
+import (
+	"math"
+	"net"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+type Location struct {
+	// Country is the ISO 3166-1 alpha-2 code, such as "BR".
+	Country string
+	// Region is the ISO 3166-2 code of the first subdivision, such as "BR-SP".
+	Region string
+	City   string
+
+	// Latitude and Longitude are rounded to one decimal place (about 11 km),
+	// enough to place a click on a map without pinpointing the visitor.
+	Latitude  float64
+	Longitude float64
+}
+
+type Reader struct {
+	db *geoip2.Reader
+}
+
+// Open memory maps the database at path.
+func Open(path string) (*Reader, error) {
+	db, err := geoip2.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{db: db}, nil
+}
+
+// Lookup reports false for unparsable, private or unknown addresses. A nil
+// Reader knows no addresses, so callers can run without a database.
+func (r *Reader) Lookup(ip string) (Location, bool) {
+	if r == nil {
+		return Location{}, false
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil || addr.IsPrivate() || addr.IsLoopback() {
+		return Location{}, false
+	}
+
+	// City databases are a superset of country databases; fall back for
+	// deployments that only ship GeoLite2-Country.
+	city, err := r.db.City(addr)
+	if err != nil {
+		country, err := r.db.Country(addr)
+		if err != nil || country.Country.IsoCode == "" {
+			return Location{}, false
+		}
+		return Location{Country: country.Country.IsoCode}, true
+	}
+	if city.Country.IsoCode == "" {
+		return Location{}, false
+	}
+
+	loc := Location{
+		Country: city.Country.IsoCode,
+		City:    city.City.Names["en"],
+	}
+	if len(city.Subdivisions) > 0 && city.Subdivisions[0].IsoCode != "" {
+		loc.Region = city.Country.IsoCode + "-" + city.Subdivisions[0].IsoCode
+	}
+	if city.Location.Latitude != 0 || city.Location.Longitude != 0 {
+		loc.Latitude = round1(city.Location.Latitude)
+		loc.Longitude = round1(city.Location.Longitude)
+	}
+	return loc, true
+}
+
+func (r *Reader) Close() error {
+	if r == nil {
+		return nil
+	}
+	return r.db.Close()
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
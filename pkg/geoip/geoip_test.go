@@ -0,0 +1,21 @@
+package geoip_test
+
+import (
+	"testing"
+	"url-shortener/pkg/geoip"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpen_MissingFile(t *testing.T) {
+	_, err := geoip.Open("testdata/missing.mmdb")
+	assert.Error(t, err)
+}
+
+func TestReader_NilKnowsNothing(t *testing.T) {
+	var r *geoip.Reader
+
+	_, ok := r.Lookup("8.8.8.8")
+	assert.False(t, ok)
+	assert.NoError(t, r.Close())
+}
@@ -0,0 +1,54 @@
+package hll_test
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"testing"
+	"url-shortener/pkg/hll"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func hash(i int) uint64 {
+	h := fnv.New64a()
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], uint64(i))
+	h.Write(b[:])
+	x := h.Sum64()
+	// fnv is weak in the high bits for sequential input; mix it.
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	return x
+}
+
+func TestSketch_Estimate(t *testing.T) {
+	for _, n := range []int{0, 10, 1000, 100000} {
+		s := hll.New()
+		for i := 0; i < n; i++ {
+			s.Insert(hash(i))
+			s.Insert(hash(i))
+		}
+
+		got := float64(s.Estimate())
+		assert.InDelta(t, float64(n), got, math.Max(2, float64(n)*0.05), "n=%d", n)
+	}
+}
+
+func TestSketch_MergeEqualsUnion(t *testing.T) {
+	a, b, union := hll.New(), hll.New(), hll.New()
+	for i := 0; i < 5000; i++ {
+		a.Insert(hash(i))
+		union.Insert(hash(i))
+	}
+	for i := 2500; i < 7500; i++ {
+		b.Insert(hash(i))
+		union.Insert(hash(i))
+	}
+
+	a.Merge(b)
+
+	assert.Equal(t, union.Estimate(), a.Estimate())
+	assert.Equal(t, union.Registers(), hll.FromRegisters(a.Registers()).Registers())
+}
@@ -0,0 +1,305 @@
+// Package linkmeta fetches a web page and extracts what is needed to preview
+// a link to it: the title, the OpenGraph title, description and image, and
+// the favicon.
+package linkmeta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+var (
+	ErrBlockedAddress = errors.New("linkmeta: address not allowed")
+	ErrNotHTML        = errors.New("linkmeta: response is not html")
+)
+
+const (
+	DefaultTimeout      = 5 * time.Second
+	DefaultMaxBytes     = 512 << 10
+	DefaultMaxRedirects = 3
+
+	// maxFieldLength bounds every extracted value, so a hostile page cannot
+	// fill the link document.
+	maxFieldLength = 1024
+)
+
+type Metadata struct {
+	Title         string
+	OGTitle       string
+	OGDescription string
+	OGImage       string
+	Favicon       string
+}
+
+// Fetcher downloads pages over a client that refuses to connect to loopback,
+// private, link-local and other non-public addresses, checked on the address
+// actually dialed so DNS answers and redirects cannot bypass it.
+type Fetcher struct {
+	client   *http.Client
+	maxBytes int64
+}
+
+type Option func(*Fetcher)
+
+// WithMaxBytes caps how much of a response body is read.
+func WithMaxBytes(n int64) Option {
+	return func(f *Fetcher) {
+		f.maxBytes = n
+	}
+}
+
+// WithClient replaces the SSRF-safe client, for tests against local servers.
+func WithClient(client *http.Client) Option {
+	return func(f *Fetcher) {
+		f.client = client
+	}
+}
+
+// NewFetcher returns a fetcher whose requests, redirects included, take at
+// most timeout.
+func NewFetcher(timeout time.Duration, opts ...Option) *Fetcher {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	f := &Fetcher{
+		client: &http.Client{
+			Timeout:       timeout,
+			Transport:     SafeTransport(timeout),
+			CheckRedirect: checkRedirect,
+		},
+		maxBytes: DefaultMaxBytes,
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// SafeTransport returns a transport that refuses to connect to non-public
+// addresses, for any client requesting user-supplied URLs.
+func SafeTransport(timeout time.Duration) *http.Transport {
+	dialer := &net.Dialer{Timeout: timeout, Control: denyPrivate}
+	return &http.Transport{
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+}
+
+// Fetch downloads rawURL and parses it. Only http and https URLs are fetched
+// and only HTML responses are parsed; at most the configured number of bytes
+// is read.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (Metadata, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return Metadata{}, fmt.Errorf("linkmeta: unsupported url %q", rawURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return Metadata{}, err
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	req.Header.Set("User-Agent", "url-shortener-linkmeta/1.0")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return Metadata{}, fmt.Errorf("linkmeta: %s returned %d", rawURL, resp.StatusCode)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if media, _, _ := mime.ParseMediaType(contentType); media != "text/html" && media != "application/xhtml+xml" {
+		return Metadata{}, ErrNotHTML
+	}
+
+	body, err := charset.NewReader(io.LimitReader(resp.Body, f.maxBytes), contentType)
+	if err != nil {
+		return Metadata{}, err
+	}
+	return Parse(body, resp.Request.URL), nil
+}
+
+// Parse extracts metadata from an HTML document, resolving image and favicon
+// URLs against base. Parsing stops at the end of <head>. A page without a
+// favicon link gets /favicon.ico.
+func Parse(r io.Reader, base *url.URL) Metadata {
+	var m Metadata
+	var inTitle bool
+	var title strings.Builder
+	var description string
+
+	z := html.NewTokenizer(r)
+loop:
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			break loop
+		case html.TextToken:
+			if inTitle {
+				title.Write(z.Text())
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = false
+			case "head":
+				break loop
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = tt == html.StartTagToken && m.Title == "" && title.Len() == 0
+			case "body":
+				break loop
+			case "meta":
+				attrs := attributes(z, hasAttr)
+				key := attrs["property"]
+				if key == "" {
+					key = attrs["name"]
+				}
+				switch strings.ToLower(key) {
+				case "og:title":
+					setOnce(&m.OGTitle, attrs["content"])
+				case "og:description":
+					setOnce(&m.OGDescription, attrs["content"])
+				case "description":
+					setOnce(&description, attrs["content"])
+				case "og:image", "og:image:url", "og:image:secure_url":
+					setOnce(&m.OGImage, resolve(base, attrs["content"]))
+				}
+			case "link":
+				attrs := attributes(z, hasAttr)
+				for _, rel := range strings.Fields(strings.ToLower(attrs["rel"])) {
+					if rel == "icon" {
+						setOnce(&m.Favicon, resolve(base, attrs["href"]))
+					}
+				}
+			}
+		}
+	}
+
+	if title.Len() > 0 {
+		m.Title = clean(title.String())
+	}
+	// The plain description is a fallback for og:description.
+	if m.OGDescription == "" {
+		m.OGDescription = description
+	}
+	if m.Favicon == "" && base != nil {
+		m.Favicon = resolve(base, "/favicon.ico")
+	}
+	return m
+}
+
+func attributes(z *html.Tokenizer, more bool) map[string]string {
+	attrs := make(map[string]string)
+	for more {
+		var key, val []byte
+		key, val, more = z.TagAttr()
+		attrs[string(key)] = string(val)
+	}
+	return attrs
+}
+
+func setOnce(dst *string, value string) {
+	if *dst == "" {
+		*dst = clean(value)
+	}
+}
+
+// clean collapses whitespace and truncates value to maxFieldLength bytes,
+// on a rune boundary.
+func clean(value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	if len(value) <= maxFieldLength {
+		return value
+	}
+	cut := maxFieldLength
+	for cut > 0 && !isRuneStart(value[cut]) {
+		cut--
+	}
+	return value[:cut]
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// resolve returns ref as an absolute http(s) URL, or "" when it is not one,
+// which drops javascript: and data: URLs.
+func resolve(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	return u.String()
+}
+
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= DefaultMaxRedirects {
+		return fmt.Errorf("linkmeta: stopped after %d redirects", len(via))
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return fmt.Errorf("linkmeta: redirect to unsupported url %q", req.URL)
+	}
+	return nil
+}
+
+// denyPrivate runs after DNS resolution, on the address about to be dialed.
+func denyPrivate(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !IsPublic(ip) {
+		return ErrBlockedAddress
+	}
+	return nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, 100.64.0.0/10.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// IsPublic reports whether ip is a globally routable unicast address.
+func IsPublic(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		if ip4[0] == 0 || sharedAddressSpace.Contains(ip4) {
+			return false
+		}
+	}
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !ip.IsLoopback()
+}
@@ -0,0 +1,143 @@
+package linkmeta_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"url-shortener/pkg/linkmeta"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const page = `<!doctype html>
+<html><head>
+<meta charset="utf-8">
+<title>
+  Promoção   de Verão
+</title>
+<meta property="og:title" content="Summer sale">
+<meta name="description" content="Plain description">
+<meta property="og:description" content="Everything 50% off">
+<meta property="og:image" content="/img/cover.png">
+<link rel="shortcut icon" href="//cdn.example/fav.png">
+</head><body><title>not this one</title><meta property="og:image" content="/late.png"></body></html>`
+
+func TestParse(t *testing.T) {
+	base, _ := url.Parse("https://shop.example/sale/")
+
+	m := linkmeta.Parse(strings.NewReader(page), base)
+
+	assert.Equal(t, linkmeta.Metadata{
+		Title:         "Promoção de Verão",
+		OGTitle:       "Summer sale",
+		OGDescription: "Everything 50% off",
+		OGImage:       "https://shop.example/img/cover.png",
+		Favicon:       "https://cdn.example/fav.png",
+	}, m)
+}
+
+func TestParse_FallbacksAndUnsafeURLs(t *testing.T) {
+	base, _ := url.Parse("http://blog.example/post")
+
+	m := linkmeta.Parse(strings.NewReader(`<head>
+		<meta name="description" content="Only plain">
+		<meta property="og:image" content="javascript:alert(1)">
+		<title>`+strings.Repeat("a", 2000)+`</title>`), base)
+
+	assert.Equal(t, "Only plain", m.OGDescription)
+	assert.Empty(t, m.OGImage)
+	assert.Equal(t, "http://blog.example/favicon.ico", m.Favicon)
+	assert.Len(t, m.Title, 1024)
+}
+
+func TestFetcher_Fetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/moved":
+			http.Redirect(w, r, "/page", http.StatusFound)
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=iso-8859-1")
+			_, _ = w.Write([]byte("<title>Promo\xe7\xe3o</title>"))
+		case "/huge":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte("<title>" + strings.Repeat("x", 4096) + "</title><meta property=og:title content=late>"))
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	f := linkmeta.NewFetcher(time.Second, linkmeta.WithClient(srv.Client()), linkmeta.WithMaxBytes(1024))
+
+	m, err := f.Fetch(context.Background(), srv.URL+"/moved")
+	assert.NoError(t, err)
+	assert.Equal(t, "Promoção", m.Title)
+	assert.Equal(t, srv.URL+"/favicon.ico", m.Favicon)
+
+	m, err = f.Fetch(context.Background(), srv.URL+"/huge")
+	assert.NoError(t, err)
+	assert.Empty(t, m.OGTitle)
+
+	_, err = f.Fetch(context.Background(), srv.URL+"/image")
+	assert.ErrorIs(t, err, linkmeta.ErrNotHTML)
+
+	_, err = f.Fetch(context.Background(), srv.URL+"/missing")
+	assert.Error(t, err)
+
+	_, err = f.Fetch(context.Background(), "ftp://example.com/")
+	assert.Error(t, err)
+}
+
+func TestFetcher_RefusesPrivateAddresses(t *testing.T) {
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Content-Type", "text/html")
+	}))
+	defer srv.Close()
+
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	f := linkmeta.NewFetcher(time.Second)
+
+	for _, target := range []string{srv.URL, "http://localhost:" + port} {
+		_, err := f.Fetch(context.Background(), target)
+		assert.ErrorIs(t, err, linkmeta.ErrBlockedAddress, target)
+	}
+	assert.Zero(t, hits)
+}
+
+func TestFetcher_Timeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	client := srv.Client()
+	client.Timeout = 50 * time.Millisecond
+	f := linkmeta.NewFetcher(50*time.Millisecond, linkmeta.WithClient(client))
+
+	start := time.Now()
+	_, err := f.Fetch(context.Background(), srv.URL)
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestIsPublic(t *testing.T) {
+	for _, raw := range []string{"127.0.0.1", "10.1.2.3", "192.168.0.1", "172.16.0.1", "169.254.169.254",
+		"100.64.0.1", "0.0.0.0", "::1", "fe80::1", "fc00::1", "::ffff:127.0.0.1", "224.0.0.1"} {
+		assert.False(t, linkmeta.IsPublic(net.ParseIP(raw)), raw)
+	}
+	for _, raw := range []string{"8.8.8.8", "2606:4700:4700::1111"} {
+		assert.True(t, linkmeta.IsPublic(net.ParseIP(raw)), raw)
+	}
+}
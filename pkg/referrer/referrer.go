@@ -0,0 +1,179 @@
+// Package referrer normalizes Referer headers into a host and a traffic
+// source category.
+package referrer
+
+import (
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	CategoryDirect   = "direct"
+	CategoryInternal = "internal"
+	CategorySearch   = "search"
+	CategorySocial   = "social"
+	CategoryEmail    = "email"
+	CategoryReferral = "referral"
+)
+
+type Source struct {
+	Host     string
+	Category string
+}
+
+// Classifier knows which hosts are our own, so navigation between our pages
+// is reported as internal traffic.
+type Classifier struct {
+	internal map[string]bool
+}
+
+func NewClassifier(internalHosts ...string) *Classifier {
+	c := &Classifier{internal: make(map[string]bool)}
+	for _, h := range internalHosts {
+		if h = NormalizeHost(h); h != "" {
+			c.internal[h] = true
+		}
+	}
+	return c
+}
+
+// Parse normalizes a raw Referer. Empty or unparsable referrers are direct
+// traffic. Android app referrers (android-app://<package>/) and iOS app
+// referrers (ios-app://<id>/<scheme>/...) are mapped to the site of the app
+// when it is known.
+func (c *Classifier) Parse(raw string) Source {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return Source{Category: CategoryDirect}
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return Source{Category: CategoryDirect}
+	}
+
+	if u.Scheme == "android-app" || u.Scheme == "ios-app" {
+		app := strings.ToLower(u.Host)
+		if u.Scheme == "ios-app" {
+			app = strings.ToLower(strings.Trim(u.Path, "/"))
+			if i := strings.Index(app, "/"); i >= 0 {
+				app = app[:i]
+			}
+		}
+		if known, ok := apps[app]; ok {
+			return known
+		}
+		return Source{Host: app, Category: CategoryReferral}
+	}
+
+	host := NormalizeHost(u.Hostname())
+	if c.internal[host] {
+		return Source{Host: host, Category: CategoryInternal}
+	}
+	return Source{Host: host, Category: categorize(host)}
+}
+
+var apps = map[string]Source{
+	"com.google.android.gm":                   {Host: "mail.google.com", Category: CategoryEmail},
+	"com.google.android.googlequicksearchbox": {Host: "google.com", Category: CategorySearch},
+	"com.microsoft.office.outlook":            {Host: "outlook.com", Category: CategoryEmail},
+	"com.facebook.katana":                     {Host: "facebook.com", Category: CategorySocial},
+	"com.facebook.orca":                       {Host: "messenger.com", Category: CategorySocial},
+	"com.instagram.android":                   {Host: "instagram.com", Category: CategorySocial},
+	"com.twitter.android":                     {Host: "twitter.com", Category: CategorySocial},
+	"com.linkedin.android":                    {Host: "linkedin.com", Category: CategorySocial},
+	"com.reddit.frontpage":                    {Host: "reddit.com", Category: CategorySocial},
+	"com.whatsapp":                            {Host: "whatsapp.com", Category: CategorySocial},
+	"org.telegram.messenger":                  {Host: "telegram.org", Category: CategorySocial},
+	"com.zhiliaoapp.musically":                {Host: "tiktok.com", Category: CategorySocial},
+	"com.pinterest":                           {Host: "pinterest.com", Category: CategorySocial},
+	"com.slack":                               {Host: "slack.com", Category: CategorySocial},
+	"com.discord":                             {Host: "discord.com", Category: CategorySocial},
+}
+
+var domains = []struct {
+	domain   string
+	category string
+}{
+	// Email clients are checked before search and social because webmail
+	// lives on subdomains of search and social sites.
+	{"mail.google.com", CategoryEmail},
+	{"mail.yahoo.com", CategoryEmail},
+	{"outlook.live.com", CategoryEmail},
+	{"outlook.office.com", CategoryEmail},
+	{"outlook.office365.com", CategoryEmail},
+	{"mail.proton.me", CategoryEmail},
+	{"mail.zoho.com", CategoryEmail},
+	{"fastmail.com", CategoryEmail},
+
+	{"bing.com", CategorySearch},
+	{"duckduckgo.com", CategorySearch},
+	{"search.yahoo.com", CategorySearch},
+	{"baidu.com", CategorySearch},
+	{"ecosia.org", CategorySearch},
+	{"search.brave.com", CategorySearch},
+	{"startpage.com", CategorySearch},
+
+	{"facebook.com", CategorySocial},
+	{"fb.com", CategorySocial},
+	{"messenger.com", CategorySocial},
+	{"instagram.com", CategorySocial},
+	{"twitter.com", CategorySocial},
+	{"x.com", CategorySocial},
+	{"t.co", CategorySocial},
+	{"linkedin.com", CategorySocial},
+	{"lnkd.in", CategorySocial},
+	{"reddit.com", CategorySocial},
+	{"news.ycombinator.com", CategorySocial},
+	{"youtube.com", CategorySocial},
+	{"tiktok.com", CategorySocial},
+	{"pinterest.com", CategorySocial},
+	{"whatsapp.com", CategorySocial},
+	{"t.me", CategorySocial},
+	{"telegram.org", CategorySocial},
+	{"discord.com", CategorySocial},
+	{"slack.com", CategorySocial},
+	{"threads.net", CategorySocial},
+	{"bsky.app", CategorySocial},
+	{"mastodon.social", CategorySocial},
+}
+
+// countrySearch matches search engines with a site per country, such as
+// google.com.br or yandex.ru, on the bare domain only: their other
+// subdomains (docs.google.com, disk.yandex.ru) are not search.
+var countrySearch = regexp.MustCompile(`^(google|yandex)\.(com?\.)?[a-z]{2,3}$`)
+
+func categorize(host string) string {
+	for _, d := range domains {
+		if host == d.domain || strings.HasSuffix(host, "."+d.domain) {
+			return d.category
+		}
+	}
+	if countrySearch.MatchString(host) {
+		return CategorySearch
+	}
+	if strings.HasPrefix(host, "mail.") || strings.HasPrefix(host, "webmail.") {
+		return CategoryEmail
+	}
+	return CategoryReferral
+}
+
+// NormalizeHost lower-cases a host, drops the port and strips the prefixes
+// sites use for mobile and redirect variants (www., m., l., lm.).
+func NormalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	host = strings.TrimSuffix(host, ".")
+	for _, prefix := range []string{"www.", "m.", "l.", "lm.", "mobile."} {
+		if strings.HasPrefix(host, prefix) && strings.Count(host, ".") > 1 {
+			host = strings.TrimPrefix(host, prefix)
+			break
+		}
+	}
+	return host
+}
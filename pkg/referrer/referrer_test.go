@@ -0,0 +1,41 @@
+package referrer_test
+
+import (
+	"testing"
+	"url-shortener/pkg/referrer"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifier_Parse(t *testing.T) {
+	c := referrer.NewClassifier("sho.rt:8080")
+
+	cases := []struct {
+		raw  string
+		want referrer.Source
+	}{
+		{"", referrer.Source{Category: referrer.CategoryDirect}},
+		{"not a url", referrer.Source{Category: referrer.CategoryDirect}},
+		{"https://www.google.com.br/", referrer.Source{Host: "google.com.br", Category: referrer.CategorySearch}},
+		{"https://google.de/search?q=x", referrer.Source{Host: "google.de", Category: referrer.CategorySearch}},
+		{"https://yandex.ru/search/", referrer.Source{Host: "yandex.ru", Category: referrer.CategorySearch}},
+		{"https://docs.google.com/document/d/x", referrer.Source{Host: "docs.google.com", Category: referrer.CategoryReferral}},
+		{"https://drive.google.com/", referrer.Source{Host: "drive.google.com", Category: referrer.CategoryReferral}},
+		{"https://duckduckgo.com/?q=x", referrer.Source{Host: "duckduckgo.com", Category: referrer.CategorySearch}},
+		{"https://l.facebook.com/l.php?u=x", referrer.Source{Host: "facebook.com", Category: referrer.CategorySocial}},
+		{"https://t.co/abc", referrer.Source{Host: "t.co", Category: referrer.CategorySocial}},
+		{"https://mail.google.com/mail/u/0/", referrer.Source{Host: "mail.google.com", Category: referrer.CategoryEmail}},
+		{"https://webmail.example.org/", referrer.Source{Host: "webmail.example.org", Category: referrer.CategoryEmail}},
+		{"android-app://com.google.android.gm/", referrer.Source{Host: "mail.google.com", Category: referrer.CategoryEmail}},
+		{"android-app://com.linkedin.android", referrer.Source{Host: "linkedin.com", Category: referrer.CategorySocial}},
+		{"android-app://com.example.reader/", referrer.Source{Host: "com.example.reader", Category: referrer.CategoryReferral}},
+		{"https://SHO.RT/dashboard", referrer.Source{Host: "sho.rt", Category: referrer.CategoryInternal}},
+		{"https://blog.example.com:443/post", referrer.Source{Host: "blog.example.com", Category: referrer.CategoryReferral}},
+		{"http://[::1]:8080/", referrer.Source{Host: "::1", Category: referrer.CategoryReferral}},
+		{"http://[2001:db8::1]/page", referrer.Source{Host: "2001:db8::1", Category: referrer.CategoryReferral}},
+	}
+
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, c.Parse(tc.raw), tc.raw)
+	}
+}
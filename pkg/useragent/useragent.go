@@ -0,0 +1,226 @@
+// Package useragent extracts browser, operating system and device class from
+// a User-Agent header, refined by User-Agent Client Hints when the browser
+// sends them.
+package useragent
+
+import (
+	"strings"
+)
+
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+)
+
+type Agent struct {
+	Browser        string
+	BrowserVersion string
+	OS             string
+	OSVersion      string
+	Device         string
+}
+
+// Hints holds the raw low-entropy client hint headers: Sec-CH-UA,
+// Sec-CH-UA-Mobile and Sec-CH-UA-Platform.
+type Hints struct {
+	UA       string
+	Mobile   string
+	Platform string
+}
+
+// Parse parses a User-Agent string. Browser versions are reduced to their
+// major component so they group well. Unknown fields are left empty.
+func Parse(ua string) Agent {
+	var a Agent
+	a.Browser, a.BrowserVersion = parseBrowser(ua)
+	a.OS, a.OSVersion = parseOS(ua)
+	a.Device = parseDevice(ua, a.OS)
+	return a
+}
+
+// ParseWithHints parses ua and lets client hints override what they cover.
+// Chromium freezes parts of the User-Agent string, so the hints are the more
+// accurate source when present.
+func ParseWithHints(ua string, hints Hints) Agent {
+	a := Parse(ua)
+
+	if browser, version := parseBrands(hints.UA); browser != "" {
+		a.Browser, a.BrowserVersion = browser, version
+	}
+	if platform := unquote(hints.Platform); platform != "" {
+		if os := platformNames[strings.ToLower(platform)]; os != "" {
+			if os != a.OS {
+				a.OSVersion = ""
+			}
+			a.OS = os
+		}
+	}
+	switch strings.TrimSpace(hints.Mobile) {
+	case "?1":
+		a.Device = DeviceMobile
+	case "?0":
+		if a.Device != DeviceTablet {
+			a.Device = DeviceDesktop
+		}
+	}
+
+	return a
+}
+
+var platformNames = map[string]string{
+	"android":   "Android",
+	"chrome os": "Chrome OS",
+	"chromeos":  "Chrome OS",
+	"ios":       "iOS",
+	"linux":     "Linux",
+	"macos":     "macOS",
+	"windows":   "Windows",
+}
+
+// browserTokens is checked in order; more specific tokens come first because
+// most browsers also claim to be Chrome and Safari.
+var browserTokens = []struct {
+	token string
+	name  string
+}{
+	{"EdgA/", "Edge"},
+	{"EdgiOS/", "Edge"},
+	{"Edg/", "Edge"},
+	{"Edge/", "Edge"},
+	{"OPR/", "Opera"},
+	{"SamsungBrowser/", "Samsung Internet"},
+	{"YaBrowser/", "Yandex"},
+	{"FxiOS/", "Firefox"},
+	{"Firefox/", "Firefox"},
+	{"CriOS/", "Chrome"},
+	{"Chromium/", "Chromium"},
+	{"Chrome/", "Chrome"},
+}
+
+func parseBrowser(ua string) (string, string) {
+	for _, b := range browserTokens {
+		if i := strings.Index(ua, b.token); i >= 0 {
+			return b.name, major(versionAt(ua, i+len(b.token)))
+		}
+	}
+	if strings.Contains(ua, "Safari/") && strings.Contains(ua, "Version/") {
+		return "Safari", major(versionAfter(ua, "Version/"))
+	}
+	if strings.Contains(ua, "Trident/") || strings.Contains(ua, "MSIE ") {
+		v := versionAfter(ua, "MSIE ")
+		if v == "" {
+			v = versionAfter(ua, "rv:")
+		}
+		return "Internet Explorer", major(v)
+	}
+	return "", ""
+}
+
+var windowsVersions = map[string]string{
+	"10.0": "10",
+	"6.3":  "8.1",
+	"6.2":  "8",
+	"6.1":  "7",
+	"6.0":  "Vista",
+	"5.1":  "XP",
+}
+
+func parseOS(ua string) (string, string) {
+	switch {
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPad"), strings.Contains(ua, "iPod"):
+		v := versionAfter(ua, "OS ")
+		return "iOS", strings.ReplaceAll(v, "_", ".")
+	case strings.Contains(ua, "Android"):
+		return "Android", versionAfter(ua, "Android ")
+	case strings.Contains(ua, "Windows"):
+		return "Windows", windowsVersions[versionAfter(ua, "Windows NT ")]
+	case strings.Contains(ua, "CrOS"):
+		return "Chrome OS", ""
+	case strings.Contains(ua, "Mac OS X"):
+		return "macOS", strings.ReplaceAll(versionAfter(ua, "Mac OS X "), "_", ".")
+	case strings.Contains(ua, "Linux"):
+		return "Linux", ""
+	}
+	return "", ""
+}
+
+func parseDevice(ua, os string) string {
+	switch {
+	case strings.Contains(ua, "iPad"), strings.Contains(ua, "Tablet"):
+		return DeviceTablet
+	case os == "Android" && !strings.Contains(ua, "Mobile"):
+		return DeviceTablet
+	case strings.Contains(ua, "Mobi"), strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPod"):
+		return DeviceMobile
+	case os != "":
+		return DeviceDesktop
+	}
+	return ""
+}
+
+var brandNames = map[string]string{
+	"google chrome":    "Chrome",
+	"microsoft edge":   "Edge",
+	"opera":            "Opera",
+	"brave":            "Brave",
+	"yandex":           "Yandex",
+	"samsung internet": "Samsung Internet",
+}
+
+// parseBrands reads a Sec-CH-UA brand list such as
+// `"Chromium";v="124", "Google Chrome";v="124", "Not-A.Brand";v="99"`,
+// preferring a named browser over the generic Chromium brand and skipping
+// the GREASE entries.
+func parseBrands(header string) (string, string) {
+	var fallback, fallbackVersion string
+	for _, item := range strings.Split(header, ",") {
+		parts := strings.Split(item, ";")
+		brand := unquote(parts[0])
+		version := ""
+		for _, p := range parts[1:] {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(p), "v="); ok {
+				version = major(unquote(v))
+			}
+		}
+
+		lower := strings.ToLower(brand)
+		if brand == "" || strings.Contains(lower, "not") && strings.Contains(lower, "brand") {
+			continue
+		}
+		if name, ok := brandNames[lower]; ok {
+			return name, version
+		}
+		if lower == "chromium" {
+			fallback, fallbackVersion = "Chromium", version
+		}
+	}
+	return fallback, fallbackVersion
+}
+
+func versionAfter(ua, token string) string {
+	i := strings.Index(ua, token)
+	if i < 0 {
+		return ""
+	}
+	return versionAt(ua, i+len(token))
+}
+
+func versionAt(ua string, start int) string {
+	end := start
+	for end < len(ua) && (ua[end] >= '0' && ua[end] <= '9' || ua[end] == '.' || ua[end] == '_') {
+		end++
+	}
+	return strings.Trim(ua[start:end], "._")
+}
+
+func major(version string) string {
+	if i := strings.IndexAny(version, "._"); i >= 0 {
+		return version[:i]
+	}
+	return version
+}
+
+func unquote(s string) string {
+	return strings.Trim(strings.TrimSpace(s), `"`)
+}
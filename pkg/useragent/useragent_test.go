@@ -0,0 +1,71 @@
+package useragent_test
+
+import (
+	"testing"
+	"url-shortener/pkg/useragent"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		ua   string
+		want useragent.Agent
+	}{
+		{
+			ua:   "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+			want: useragent.Agent{Browser: "Safari", BrowserVersion: "17", OS: "iOS", OSVersion: "17.4", Device: useragent.DeviceMobile},
+		},
+		{
+			ua:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 Edg/124.0.2478.51",
+			want: useragent.Agent{Browser: "Edge", BrowserVersion: "124", OS: "Windows", OSVersion: "10", Device: useragent.DeviceDesktop},
+		},
+		{
+			ua:   "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.6367.82 Mobile Safari/537.36",
+			want: useragent.Agent{Browser: "Chrome", BrowserVersion: "124", OS: "Android", OSVersion: "14", Device: useragent.DeviceMobile},
+		},
+		{
+			ua:   "Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/24.0 Chrome/117.0.0.0 Safari/537.36",
+			want: useragent.Agent{Browser: "Samsung Internet", BrowserVersion: "24", OS: "Android", OSVersion: "13", Device: useragent.DeviceTablet},
+		},
+		{
+			ua:   "Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:125.0) Gecko/20100101 Firefox/125.0",
+			want: useragent.Agent{Browser: "Firefox", BrowserVersion: "125", OS: "macOS", OSVersion: "10.15", Device: useragent.DeviceDesktop},
+		},
+		{
+			ua:   "Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/120.0.6099.119 Mobile/15E148 Safari/604.1",
+			want: useragent.Agent{Browser: "Chrome", BrowserVersion: "120", OS: "iOS", OSVersion: "16.6", Device: useragent.DeviceTablet},
+		},
+		{
+			ua:   "curl/8.4.0",
+			want: useragent.Agent{},
+		},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.want, useragent.Parse(c.ua), c.ua)
+	}
+}
+
+func TestParseWithHints(t *testing.T) {
+	ua := "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"
+
+	got := useragent.ParseWithHints(ua, useragent.Hints{
+		UA:       `"Chromium";v="124", "Brave";v="124", "Not-A.Brand";v="99"`,
+		Mobile:   "?0",
+		Platform: `"Linux"`,
+	})
+
+	assert.Equal(t, useragent.Agent{Browser: "Brave", BrowserVersion: "124", OS: "Linux", Device: useragent.DeviceDesktop}, got)
+}
+
+func TestParseWithHints_ChromiumFallback(t *testing.T) {
+	got := useragent.ParseWithHints("", useragent.Hints{
+		UA:     `"Not_A Brand";v="8", "Chromium";v="120"`,
+		Mobile: "?1",
+	})
+
+	assert.Equal(t, "Chromium", got.Browser)
+	assert.Equal(t, "120", got.BrowserVersion)
+	assert.Equal(t, useragent.DeviceMobile, got.Device)
+}